@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// -a11y writes the text output for screen readers: labeled sentences such
+// as "Temperature is 21 degrees Celsius." instead of icons, rules and
+// columns, with units written out. With -a11y=auto, e.g. a11y = "auto" in
+// the config, it is on when stdout is not a terminal.
+
+// a11yFlag is the boolean -a11y flag, which also accepts auto.
+type a11yFlag struct {
+	mode *string
+}
+
+func (f a11yFlag) IsBoolFlag() bool { return true }
+
+func (f a11yFlag) String() string {
+	if f.mode == nil || *f.mode == "" {
+		return "false"
+	}
+	return *f.mode
+}
+
+func (f a11yFlag) Set(value string) error {
+	if value == "auto" {
+		*f.mode = value
+		return nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("a11y must be true, false or auto")
+	}
+	*f.mode = strconv.FormatBool(on)
+	return nil
+}
+
+func a11ySetting(args []string) (any, error) {
+	value := strings.Join(args, " ")
+	if value == "auto" {
+		return value, nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.New("a11y must be true, false or auto")
+	}
+	return on, nil
+}
+
+// screenReaderOutput reports whether the -a11y mode is on for stdout.
+func screenReaderOutput(mode string) bool {
+	if mode == "auto" {
+		return !term.IsTerminal(int(os.Stdout.Fd()))
+	}
+	return mode == "true"
+}
+
+// spokenUnits returns the units of temperature and wind speed written out.
+func spokenUnits(units string) (temperature, windSpeed string) {
+	if units == "imperial" {
+		return "degrees Fahrenheit", "miles per hour"
+	}
+	return "degrees Celsius", "meters per second"
+}
+
+// spokenNumber formats v, reading a minus sign as "minus".
+func spokenNumber(format string, v float64) string {
+	s := localSprintf(format, v)
+	for _, minus := range []string{"-", "−"} {
+		if rest, ok := strings.CutPrefix(s, minus); ok {
+			if strings.Trim(rest, "0.,") == "" {
+				return rest
+			}
+			return "minus " + rest
+		}
+	}
+	return s
+}
+
+var directionNames = []string{"north", "northeast", "east", "southeast", "south", "southwest", "west", "northwest"}
+
+// directionName names the direction of degrees, e.g. "northwest".
+func directionName(degrees float64) string {
+	i := int(math.Mod(degrees/45+0.5, 8))
+	return directionNames[(i+8)%8]
+}
+
+// a11yChange adds the previous value to a sentence describing a changed
+// value in watch mode, e.g. "Temperature is 3 degrees Celsius, was 2".
+func a11yChange(sentence, value, previous string) string {
+	if previous == "" || previous == value {
+		return sentence
+	}
+	return sentence + ", was " + previous
+}
+
+// displayA11y writes the current weather as sentences.
+func displayA11y(w io.Writer, wt, prev *Weather, recent []float64, opt *options) {
+	temperatureUnit, windSpeedUnit := spokenUnits(opt.units)
+
+	// before returns the field of the previous reading formatted as the
+	// current one, or "" outside watch mode.
+	before := func(field func(*Weather) string) string {
+		if prev == nil {
+			return ""
+		}
+		return field(prev)
+	}
+
+	temperature := func(w *Weather) string { return spokenNumber("%.0f", w.Temperature) + " " + temperatureUnit }
+	conditions := func(w *Weather) string { return w.Conditions }
+
+	localFprintf(w, "%s.\n", wt.CityName)
+	if wt.CachedAt != nil {
+		localFprintf(w, "Cached %s ago.\n", formatAge(time.Since(*wt.CachedAt)))
+	}
+	if opt.verbose > 0 {
+		localFprintf(w, "Local time is %s.\n", localDate(localTime(time.Now(), wt.TimeZone), "15:04 on Monday 2 January"))
+	}
+	localFprintf(w, "%s.\n", a11yChange("Conditions are "+conditions(wt), conditions(wt), before(conditions)))
+	localFprintf(w, "%s.\n", a11yChange("Temperature is "+temperature(wt), temperature(wt), before(temperature)))
+	if opt.verbose == 0 {
+		return
+	}
+
+	pressure := func(w *Weather) string { return localSprintf("%.0f hectopascals", w.Pressure) }
+	humidity := func(w *Weather) string { return localSprintf("%.0f percent", w.Humidity) }
+	wind := func(w *Weather) string {
+		return localSprintf("%.1f %s from the %s", w.WindSpeed, windSpeedUnit, directionName(w.WindDegrees))
+	}
+	localFprintf(w, "%s.\n", a11yChange("Pressure is "+pressure(wt), pressure(wt), before(pressure)))
+	localFprintf(w, "%s.\n", a11yChange("Humidity is "+humidity(wt), humidity(wt), before(humidity)))
+	localFprintf(w, "%s.\n", a11yChange("Wind is "+wind(wt), wind(wt), before(wind)))
+	if len(recent) >= 2 {
+		low, high := recent[0], recent[0]
+		for _, v := range recent {
+			low, high = min(low, v), max(high, v)
+		}
+		localFprintf(w, "In the last 24 hours temperature was between %s and %s %s.\n", spokenNumber("%.0f", low), spokenNumber("%.0f", high), temperatureUnit)
+	}
+}
+
+// displayForecastA11y writes the forecast as a sentence for each period,
+// under a sentence naming the day.
+func displayForecastA11y(w io.Writer, f *Forecast, opt *options) {
+	temperatureUnit, windSpeedUnit := spokenUnits(opt.units)
+
+	localFprintf(w, "Forecast for %s.\n", f.CityName)
+	if f.CachedAt != nil {
+		localFprintf(w, "Cached %s ago.\n", formatAge(time.Since(*f.CachedAt)))
+	}
+	day := ""
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		if d := localDate(t, "Monday 2 January"); d != day {
+			localFprintf(w, "%s.\n", d)
+			day = d
+		}
+		localFprintf(w, "At %s, %s %s, %s", t.Format("15:04"), spokenNumber("%.0f", e.Temperature), temperatureUnit, e.Conditions)
+		if opt.verbose > 0 {
+			localFprintf(w, ", wind %.1f %s from the %s, precipitation %.1f millimeters, probability %.0f percent",
+				e.WindSpeed, windSpeedUnit, directionName(e.WindDegrees), e.Precipitation, e.Probability*100)
+		}
+		fmt.Fprintln(w, ".")
+	}
+}
+
+// displayAirA11y writes the air quality as sentences.
+func displayAirA11y(w io.Writer, a *AirQuality, names []string, opt *options) {
+	localFprintf(w, "Air quality in %s is %d of 5, %s.\n", a.CityName, a.Index, airQualityName(a.Index))
+	if a.CachedAt != nil {
+		localFprintf(w, "Cached %s ago.\n", formatAge(time.Since(*a.CachedAt)))
+	}
+	displayAirHealth(w, a.Index, opt.lang)
+	if opt.verbose == 0 {
+		return
+	}
+	for _, name := range names {
+		localFprintf(w, "%s is %.1f micrograms per cubic meter.\n", strings.ToUpper(strings.ReplaceAll(name, "_", ".")), a.Components[name])
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// weather score rates how suitable the current weather is for an outdoor
+// activity from 0 to 100: the weighted mean of a 0-100 rating of each of
+// the temperature, wind, precipitation, humidity and daylight.
+
+var activityValues = []string{"run", "bike", "walk"}
+
+func activityFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("activity", "the activity to score ("+strings.Join(activityValues, "|")+") (default run)", enumFlag(&opt.activity, "activity", activityValues))
+	fs.StringVar(&opt.scoreWeights, "weights", "", "weights of the ratings, e.g. \"temperature=3,wind=1\" (default the activity's)")
+}
+
+// activity describes the weather an activity is comfortable in, in metric
+// units. The temperature rating is 100 within the ideal range and loses
+// 100/tempSpread points per degree outside it; the wind rating is 100 up
+// to calmWind and 0 from maxWind.
+type activity struct {
+	minTemp, maxTemp float64
+	tempSpread       float64
+	calmWind         float64
+	maxWind          float64
+	weights          activityFactors
+}
+
+// activityFactors holds a value for each factor of the score: its rating
+// or its weight.
+type activityFactors struct {
+	Temperature   float64 `json:"temperature"`
+	Wind          float64 `json:"wind"`
+	Precipitation float64 `json:"precipitation"`
+	Humidity      float64 `json:"humidity"`
+	Daylight      float64 `json:"daylight"`
+}
+
+var activities = map[string]activity{
+	"run":  {minTemp: 8, maxTemp: 16, tempSpread: 15, calmWind: 4, maxWind: 15, weights: activityFactors{3, 1, 3, 2, 1}},
+	"bike": {minTemp: 15, maxTemp: 24, tempSpread: 15, calmWind: 3, maxWind: 12, weights: activityFactors{2, 3, 3, 1, 2}},
+	"walk": {minTemp: 15, maxTemp: 25, tempSpread: 20, calmWind: 5, maxWind: 18, weights: activityFactors{3, 1, 3, 1, 1}},
+}
+
+// precipitationRatings rates the weather of an icon, without the day or
+// night suffix. Icons not listed rate 100.
+// https://openweathermap.org/weather-conditions
+var precipitationRatings = map[string]float64{
+	"09": 40, // drizzle and showers
+	"10": 20, // rain
+	"11": 0,  // thunderstorm
+	"13": 20, // snow
+	"50": 80, // mist and fog
+}
+
+// parseWeights parses a list such as "temperature=3,wind=1" over the
+// weights w.
+func parseWeights(value string, w activityFactors) (activityFactors, error) {
+	fields := map[string]*float64{
+		"temperature":   &w.Temperature,
+		"temp":          &w.Temperature,
+		"wind":          &w.Wind,
+		"precipitation": &w.Precipitation,
+		"rain":          &w.Precipitation,
+		"humidity":      &w.Humidity,
+		"daylight":      &w.Daylight,
+	}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, number, _ := strings.Cut(item, "=")
+		field, ok := fields[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return w, fmt.Errorf("invalid weights %q, unknown rating %q", value, strings.TrimSpace(name))
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || v < 0 {
+			return w, fmt.Errorf("invalid weights %q, expected e.g. \"temperature=3,wind=1\"", value)
+		}
+		*field = v
+	}
+	if w.Temperature+w.Wind+w.Precipitation+w.Humidity+w.Daylight == 0 {
+		return w, fmt.Errorf("invalid weights %q, at least one must be positive", value)
+	}
+	return w, nil
+}
+
+// activityScore is the score of the weather at a location together with
+// the ratings it is made of.
+type activityScore struct {
+	City     string          `json:"city"`
+	Activity string          `json:"activity"`
+	Score    int             `json:"score"`
+	Ratings  activityFactors `json:"ratings"`
+	Weights  activityFactors `json:"weights"`
+}
+
+// scoreActivity rates m, the weather in metric units, for a.
+func scoreActivity(m *Weather, a activity, weights activityFactors) activityScore {
+	clamp := func(v float64) float64 { return max(0, min(100, v)) }
+
+	var r activityFactors
+	switch {
+	case m.Temperature < a.minTemp:
+		r.Temperature = clamp(100 - (a.minTemp-m.Temperature)*100/a.tempSpread)
+	case m.Temperature > a.maxTemp:
+		r.Temperature = clamp(100 - (m.Temperature-a.maxTemp)*100/a.tempSpread)
+	default:
+		r.Temperature = 100
+	}
+	r.Wind = clamp(100 - (m.WindSpeed-a.calmWind)*100/(a.maxWind-a.calmWind))
+	r.Precipitation = 100
+	if len(m.Icon) >= 2 {
+		if v, ok := precipitationRatings[m.Icon[:2]]; ok {
+			r.Precipitation = v
+		}
+	}
+	// Humid air is only uncomfortable from about 60%.
+	r.Humidity = clamp(100 - (m.Humidity-60)*100/40)
+	if strings.HasSuffix(m.Icon, "d") {
+		r.Daylight = 100
+	}
+
+	total := weights.Temperature*r.Temperature + weights.Wind*r.Wind + weights.Precipitation*r.Precipitation +
+		weights.Humidity*r.Humidity + weights.Daylight*r.Daylight
+	sum := weights.Temperature + weights.Wind + weights.Precipitation + weights.Humidity + weights.Daylight
+	return activityScore{Score: int(total/sum + 0.5), Ratings: r, Weights: weights}
+}
+
+// runScore scores the current weather of each location for -activity.
+func runScore(s *session) {
+	a := activities[s.opt.activity]
+	weights, err := parseWeights(s.opt.scoreWeights, a.weights)
+	if err != nil {
+		exitWithUsageError(err.Error())
+	}
+
+	for _, city := range s.cities() {
+		w, err := s.provider().current(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		s.recordHistory(w, s.opt.units)
+		sc := scoreActivity(toMetric(w, s.opt.units), a, weights)
+		sc.City, sc.Activity = w.CityName, s.opt.activity
+		displayScore(os.Stdout, sc, s.opt)
+	}
+}
+
+// displayScore writes e.g. "Helsinki: 72/100 for a run (temperature 90,
+// wind 80, precipitation 100, humidity 60, daylight 0)".
+func displayScore(w io.Writer, sc activityScore, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, sc, opt.format)
+		return
+	}
+
+	article := map[string]string{"run": "a run", "bike": "a bike ride", "walk": "a walk"}[sc.Activity]
+	r := sc.Ratings
+	localFprintf(w, "%s: %d/100 for %s (temperature %.0f, wind %.0f, precipitation %.0f, humidity %.0f, daylight %.0f)\n",
+		sc.City, sc.Score, article, r.Temperature, r.Wind, r.Precipitation, r.Humidity, r.Daylight)
+}
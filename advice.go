@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"strings"
+)
+
+// -advice follows the current weather with what to wear, e.g. "light
+// jacket, take an umbrella", from the wind chill and the precipitation.
+
+var coldToleranceValues = []string{"low", "normal", "high"}
+
+// coldToleranceShift is how many °C warmer the weather feels to people of
+// each cold tolerance.
+var coldToleranceShift = map[string]float64{"low": -3, "normal": 0, "high": 3}
+
+func adviceFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.advice, "advice", false, "follow the weather with what to wear")
+	fs.Func("cold-tolerance", "how well you tolerate cold for -advice ("+strings.Join(coldToleranceValues, "|")+") (default normal)", enumFlag(&opt.coldTolerance, "cold tolerance", coldToleranceValues))
+}
+
+// windChill returns the temperature the wind makes a temperature in °C
+// feel like at a wind speed in m/s, by the formula of Environment Canada
+// and the US National Weather Service. It is only defined in cold wind.
+func windChill(temperature, windSpeed float64) float64 {
+	kmh := windSpeed * 3.6
+	if temperature > 10 || kmh <= 4.8 {
+		return temperature
+	}
+	v := math.Pow(kmh, 0.16)
+	return 13.12 + 0.6215*temperature - 11.37*v + 0.3965*temperature*v
+}
+
+// clothingAdvice returns what to wear in m, the weather in metric units,
+// for a person of the cold tolerance.
+func clothingAdvice(m *Weather, tolerance string) string {
+	feels := windChill(m.Temperature, m.WindSpeed) + coldToleranceShift[tolerance]
+
+	var advice []string
+	switch {
+	case feels < -10:
+		advice = append(advice, "winter coat, hat and gloves")
+	case feels < 0:
+		advice = append(advice, "warm coat and gloves")
+	case feels < 10:
+		advice = append(advice, "jacket")
+	case feels < 16:
+		advice = append(advice, "light jacket")
+	case feels < 22:
+		advice = append(advice, "long sleeves")
+	default:
+		advice = append(advice, "t-shirt")
+	}
+
+	windy := m.WindSpeed >= 10
+	icon := ""
+	if len(m.Icon) >= 2 {
+		icon = m.Icon[:2]
+	}
+	switch {
+	case icon == "11":
+		advice = append(advice, "thunderstorms, better stay in")
+	case icon == "13":
+		advice = append(advice, "waterproof boots")
+	case (icon == "09" || icon == "10") && windy:
+		advice = append(advice, "rain jacket, too windy for an umbrella")
+	case icon == "09" || icon == "10":
+		advice = append(advice, "take an umbrella")
+	case windy:
+		advice = append(advice, "windproof layer")
+	}
+	return strings.Join(advice, ", ")
+}
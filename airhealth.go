@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// The health advice of each air quality index follows the European Air
+// Quality Index, whose five levels OpenWeather uses:
+// https://airindex.eea.europa.eu/AQI/index.html
+
+// airHealth is what an air quality index means for health and what the
+// general population and sensitive groups, such as children, the elderly
+// and people with asthma or heart or lung disease, should do about it.
+type airHealth struct {
+	description string
+	general     string
+	sensitive   string
+}
+
+// airHealthText holds the advice of a language with the labels it is
+// shown with. advice is indexed by the air quality index minus one.
+type airHealthText struct {
+	health, sensitiveGroups string
+	advice                  [5]airHealth
+}
+
+// airHealthTexts are keyed by -lang. Other languages fall back to English.
+var airHealthTexts = map[string]*airHealthText{
+	"en": {
+		health:          "health",
+		sensitiveGroups: "sensitive groups",
+		advice: [5]airHealth{
+			{"air quality is good, air pollution poses little or no risk", "enjoy your usual outdoor activities", "enjoy your usual outdoor activities"},
+			{"air quality is acceptable", "enjoy your usual outdoor activities", "enjoy your usual outdoor activities"},
+			{"air quality is moderate, sensitive people may experience symptoms", "enjoy your usual outdoor activities", "consider reducing intense outdoor activities if you experience symptoms"},
+			{"air quality is poor, anyone may begin to experience symptoms", "consider reducing intense outdoor activities if you experience symptoms such as sore eyes, a cough or a sore throat", "consider reducing physical activities, particularly outdoors, especially if you experience symptoms"},
+			{"air quality is very poor, a health risk for everyone", "consider reducing physical activities outdoors", "reduce physical activities, particularly outdoors, especially if you experience symptoms"},
+		},
+	},
+	"fi": {
+		health:          "terveys",
+		sensitiveGroups: "herkät ryhmät",
+		advice: [5]airHealth{
+			{"ilmanlaatu on hyvä, ilmansaasteista ei ole juuri haittaa", "nauti tavallisista ulkoiluharrastuksista", "nauti tavallisista ulkoiluharrastuksista"},
+			{"ilmanlaatu on tyydyttävä", "nauti tavallisista ulkoiluharrastuksista", "nauti tavallisista ulkoiluharrastuksista"},
+			{"ilmanlaatu on välttävä, herkät ihmiset voivat saada oireita", "nauti tavallisista ulkoiluharrastuksista", "harkitse rasittavan ulkoliikunnan vähentämistä, jos sinulla on oireita"},
+			{"ilmanlaatu on huono, kuka tahansa voi saada oireita", "harkitse rasittavan ulkoliikunnan vähentämistä, jos sinulla on oireita kuten silmien kirvelyä, yskää tai kurkkukipua", "harkitse liikunnan vähentämistä etenkin ulkona, varsinkin jos sinulla on oireita"},
+			{"ilmanlaatu on erittäin huono ja terveysriski kaikille", "harkitse ulkoliikunnan vähentämistä", "vähennä liikuntaa etenkin ulkona, varsinkin jos sinulla on oireita"},
+		},
+	},
+	"sv": {
+		health:          "hälsa",
+		sensitiveGroups: "känsliga grupper",
+		advice: [5]airHealth{
+			{"luftkvaliteten är god, luftföroreningarna medför liten eller ingen risk", "njut av dina vanliga utomhusaktiviteter", "njut av dina vanliga utomhusaktiviteter"},
+			{"luftkvaliteten är acceptabel", "njut av dina vanliga utomhusaktiviteter", "njut av dina vanliga utomhusaktiviteter"},
+			{"luftkvaliteten är måttlig, känsliga personer kan få symtom", "njut av dina vanliga utomhusaktiviteter", "överväg att minska ansträngande utomhusaktiviteter om du får symtom"},
+			{"luftkvaliteten är dålig, alla kan börja få symtom", "överväg att minska ansträngande utomhusaktiviteter om du får symtom som sveda i ögonen, hosta eller halsont", "överväg att minska fysisk aktivitet, särskilt utomhus, i synnerhet om du får symtom"},
+			{"luftkvaliteten är mycket dålig, en hälsorisk för alla", "överväg att minska fysisk aktivitet utomhus", "minska fysisk aktivitet, särskilt utomhus, i synnerhet om du får symtom"},
+		},
+	},
+	"de": {
+		health:          "Gesundheit",
+		sensitiveGroups: "empfindliche Gruppen",
+		advice: [5]airHealth{
+			{"die Luftqualität ist gut, die Luftverschmutzung stellt kaum ein Risiko dar", "genießen Sie Ihre üblichen Aktivitäten im Freien", "genießen Sie Ihre üblichen Aktivitäten im Freien"},
+			{"die Luftqualität ist akzeptabel", "genießen Sie Ihre üblichen Aktivitäten im Freien", "genießen Sie Ihre üblichen Aktivitäten im Freien"},
+			{"die Luftqualität ist mäßig, empfindliche Personen können Beschwerden bekommen", "genießen Sie Ihre üblichen Aktivitäten im Freien", "erwägen Sie, anstrengende Aktivitäten im Freien zu reduzieren, wenn Sie Beschwerden haben"},
+			{"die Luftqualität ist schlecht, jeder kann Beschwerden bekommen", "erwägen Sie, anstrengende Aktivitäten im Freien zu reduzieren, wenn Sie Beschwerden wie brennende Augen, Husten oder Halsschmerzen haben", "erwägen Sie, körperliche Aktivitäten zu reduzieren, besonders im Freien und vor allem, wenn Sie Beschwerden haben"},
+			{"die Luftqualität ist sehr schlecht, ein Gesundheitsrisiko für alle", "erwägen Sie, körperliche Aktivitäten im Freien zu reduzieren", "reduzieren Sie körperliche Aktivitäten, besonders im Freien und vor allem, wenn Sie Beschwerden haben"},
+		},
+	},
+}
+
+// displayAirHealth writes the health advice of an air quality index in
+// lang, e.g. "health: air quality is acceptable, enjoy your usual outdoor
+// activities" followed by the advice for sensitive groups.
+func displayAirHealth(w io.Writer, index int, lang string) {
+	if index < 1 || index > 5 {
+		return
+	}
+	t, ok := airHealthTexts[lang]
+	if !ok {
+		t = airHealthTexts["en"]
+	}
+	a := t.advice[index-1]
+	fmt.Fprintf(w, "%s: %s, %s\n", t.health, a.description, a.general)
+	fmt.Fprintf(w, "%s: %s\n", t.sensitiveGroups, a.sensitive)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const PRO_FORECAST_URL = "https://pro.openweathermap.org/data/2.5/forecast/hourly"
+
+// Coordinates used for probe requests; any valid location works.
+const probeLat, probeLon = "60.17", "24.94"
+
+func runAuth(s *session) {
+	args := s.args()
+	if len(args) > 0 && args[0] == "set-key" {
+		runAuthSetKey(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "check" {
+		exitWithUsageError("usage: weather auth check|set-key [key]")
+	}
+
+	keys := s.keys()
+	ok := true
+	for i, apiKey := range keys.keys {
+		if keys.size() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("key %d of %d\n", i+1, keys.size())
+		}
+
+		if s.opt.dryRun {
+			for _, u := range authProbeURLs(apiKey) {
+				fmt.Println(redact(u))
+			}
+			continue
+		}
+
+		if !checkAPIKey(os.Stdout, apiKey) {
+			ok = false
+		}
+	}
+
+	if !ok {
+		exit(exitAuth)
+	}
+}
+
+func authProbeURLs(apiKey string) []string {
+	q := url.Values{}
+	q.Set("lat", probeLat)
+	q.Set("lon", probeLon)
+	q.Set("appid", apiKey)
+	current := BASE_URL + "?" + q.Encode()
+	pro := PRO_FORECAST_URL + "?" + q.Encode()
+	q.Set("exclude", "minutely,hourly,daily,alerts")
+	onecall := ONECALL_URL + "?" + q.Encode()
+	return []string{current, pro, onecall}
+}
+
+// probeStatus issues a GET request and returns only the response status code.
+func probeStatus(u string) (int, error) {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// checkAPIKey reports whether apiKey is accepted by OpenWeather, which plan
+// it appears to be on and whether One Call 3.0 is enabled for it.
+func checkAPIKey(w io.Writer, apiKey string) bool {
+	urls := authProbeURLs(apiKey)
+
+	status, err := probeStatus(urls[0])
+	if err != nil {
+		fmt.Fprintf(w, "key: unknown (%s)\n", redact(err.Error()))
+		return false
+	}
+
+	switch status {
+	case http.StatusOK:
+		fmt.Fprintf(w, "key: valid\n")
+	case http.StatusUnauthorized:
+		fmt.Fprintf(w, "key: invalid\n")
+		fmt.Fprintf(w, "hint: new keys can take a couple of hours to activate, see https://home.openweathermap.org/api_keys\n")
+		return false
+	case http.StatusTooManyRequests:
+		fmt.Fprintf(w, "key: valid, but rate limited or blocked for exceeding the plan's quota\n")
+		return false
+	default:
+		fmt.Fprintf(w, "key: unknown (request status %d %s)\n", status, http.StatusText(status))
+		return false
+	}
+
+	// Paid plans get access to the pro endpoints, free keys are rejected.
+	if status, err := probeStatus(urls[1]); err == nil && status == http.StatusOK {
+		fmt.Fprintf(w, "plan: paid\n")
+	} else {
+		fmt.Fprintf(w, "plan: free\n")
+	}
+
+	status, err = probeStatus(urls[2])
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "one call 3.0: unknown (%s)\n", redact(err.Error()))
+	case status == http.StatusOK:
+		fmt.Fprintf(w, "one call 3.0: enabled\n")
+	default:
+		fmt.Fprintf(w, "one call 3.0: not enabled, subscribe at https://openweathermap.org/api/one-call-3\n")
+	}
+
+	return true
+}
+
+// runAuthSetKey stores the API key in the OS credential store. The key is
+// read from stdin when not given as an argument to keep it out of the shell
+// history.
+func runAuthSetKey(args []string) {
+	var key string
+	if len(args) > 0 {
+		key = args[0]
+	} else {
+		fmt.Fprint(os.Stderr, "OpenWeather API key: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			exitWithError("failed to read API key: " + err.Error())
+		}
+		key = line
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		exitWithError("API key must not be empty")
+	}
+
+	if err := keyringSet(key); err != nil {
+		exitWithError("failed to store API key: " + err.Error())
+	}
+	fmt.Println("API key stored in the system credential store")
+}
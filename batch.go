@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Batch mode fetches the current weather of many locations at once, e.g. a
+// fleet of sites listed in a file, and writes it as one table, CSV file or
+// JSON document.
+
+func batchFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.batchFile, "f", "", "read the locations from a file, one per line, - for stdin")
+	fs.IntVar(&opt.parallel, "parallel", 4, "how many locations of -f are fetched at once")
+}
+
+// batchResult is the outcome for a location of a batch.
+type batchResult struct {
+	Query string `json:"query"`
+	*Weather
+	Units string `json:"units,omitempty"`
+	unitValues
+	Error string `json:"error,omitempty"`
+
+	err error
+}
+
+// readLocations reads the locations of a batch file. Blank lines and lines
+// starting with # are skipped, and aliases are expanded.
+func (s *session) readLocations(path string) []string {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			exitWithError(err.Error())
+		}
+		defer f.Close()
+	}
+
+	var cities []string
+	in := bufio.NewScanner(f)
+	for in.Scan() {
+		city := strings.TrimSpace(in.Text())
+		if city == "" || strings.HasPrefix(city, "#") {
+			continue
+		}
+		if alias, ok := s.cfg.string("aliases." + city); ok {
+			city = alias
+		}
+		cities = append(cities, city)
+	}
+	if err := in.Err(); err != nil {
+		exitWithError(err.Error())
+	}
+	if len(cities) == 0 {
+		exitWithError("no locations in " + path)
+	}
+	return cities
+}
+
+// runBatch fetches the current weather of cities, -parallel at a time, and
+// displays the results in the order of cities. Failed locations do not stop
+// the others; outside watch mode the exit status is 1 when any failed.
+func runBatch(s *session, cities []string) {
+	results := s.fetchBatch(cities)
+	failed := false
+	for _, r := range results {
+		if errors.Is(r.err, errDryRun) || errors.Is(r.err, errRaw) {
+			return
+		}
+		if r.err != nil {
+			failed = true
+			continue
+		}
+		s.recordHistory(r.Weather, s.opt.units)
+		s.pushInflux(influxWeather(r.Weather, s.opt))
+		s.emitMetrics(r.CityName, weatherSamples(r.Weather, s.opt.units), r.Time)
+	}
+
+	displayBatch(os.Stdout, results, s.opt)
+	if failed && !s.opt.watch {
+		// The results of the other locations are still written to -output.
+		if err := commitOutput(s.opt.output); err != nil {
+			exitWithError(fmt.Sprintf("output: %s", err))
+		}
+		exit(1)
+	}
+}
+
+// fetchBatch fetches the current weather of cities, -parallel at a time,
+// and returns the results in the order of cities.
+func (s *session) fetchBatch(cities []string) []batchResult {
+	if s.opt.parallel < 1 {
+		exitWithUsageError("parallel must be at least 1")
+	}
+
+	results := make([]batchResult, len(cities))
+	s.fetchAll(context.Background(), len(cities), s.opt.parallel, func(i int) {
+		w, err := s.provider().current(s.query(cities[i]))
+		results[i] = batchResult{Query: cities[i], Weather: w, err: err}
+		if err == nil {
+			results[i].Units = s.opt.units
+			results[i].unitValues = newUnitValues(w, s.opt.units)
+		} else {
+			results[i].Error = redact(err.Error())
+		}
+	})
+	return results
+}
+
+// displayBatch writes the results. CSV and JSON carry the errors of failed
+// locations in an error field; the other formats report them on stderr.
+func displayBatch(w io.Writer, results []batchResult, opt *options) {
+	switch opt.format {
+	case "json":
+		writeJSON(w, results, opt.format)
+		return
+	case "jsonl":
+		for _, r := range results {
+			writeJSON(w, r, opt.format)
+		}
+		return
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"query", "time", "location", "units", "temperature", "pressure", "humidity", "wind_speed", "wind_degrees", "visibility", "conditions", "icon", "error"}, unitColumns...))
+		for _, r := range results {
+			if r.Weather == nil {
+				cw.Write(append([]string{r.Query, "", "", "", "", "", "", "", "", "", "", "", r.Error}, r.csv()...))
+				continue
+			}
+			cw.Write(append([]string{
+				r.Query, r.Time.Format(time.RFC3339), r.CityName, r.Units,
+				formatValue("temperature", r.Temperature), formatValue("pressure", r.Pressure), formatValue("humidity", r.Humidity),
+				formatValue("wind_speed", r.WindSpeed), formatValue("wind_degrees", r.WindDegrees), formatValue("visibility", r.Visibility),
+				r.Conditions, r.Icon, "",
+			}, r.csv()...))
+		}
+		cw.Flush()
+		return
+	}
+
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", r.Query, r.Error)
+		}
+	}
+	if opt.format != "table" {
+		for _, r := range results {
+			if r.Weather != nil {
+				display(w, r.Weather, nil, nil, opt)
+			}
+		}
+		return
+	}
+	switch displayLayout(opt) {
+	case "wide":
+		displayBatchWide(w, results, opt)
+		return
+	case "narrow":
+		displayBatchNarrow(w, results, opt)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "LOCATION\tTEMP\tCONDITIONS\tHUMIDITY\tWIND\tPRESSURE\tTIME")
+	for _, r := range results {
+		if r.Weather == nil {
+			continue
+		}
+		localFprintf(tw, "%s\t%.0f°%s\t%s %s\t%.0f%%\t%.1f %s\t%.0f hPa\t%s%s\n",
+			r.CityName, r.Temperature, temperatureSymbol, weatherIconIdToEmoji(r.Icon), r.Conditions,
+			r.Humidity, r.WindSpeed, windSpeedSymbol, r.Pressure,
+			localTime(r.Time, r.TimeZone).Format("15:04"), staleNote(r.CachedAt))
+	}
+	tw.Flush()
+}
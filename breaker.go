@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The circuit breaker of a provider stops long-running modes from hammering
+// an upstream that keeps failing. After -breaker-failures consecutive
+// failures the circuit opens and requests fail fast, or are served from the
+// cache, for -breaker-cooldown. Then a single probe request is let through:
+// when it succeeds the circuit closes again, otherwise it stays open for
+// another cooldown.
+
+type circuit int
+
+const (
+	circuitClosed circuit = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+var circuitNames = []string{"closed", "open", "half-open"}
+
+func breakerFlags(fs *flag.FlagSet, opt *options) {
+	fs.IntVar(&opt.breakerFailures, "breaker-failures", 5, "consecutive provider failures that open the circuit breaker, 0 disables it")
+	fs.DurationVar(&opt.breakerCooldown, "breaker-cooldown", 30*time.Second, "how long the circuit breaker stays open before probing the provider")
+}
+
+// circuitOpenError is returned for requests rejected by an open circuit.
+type circuitOpenError struct {
+	provider string
+	retry    time.Time
+	now      func() time.Time // the clock of the breaker
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("%s is failing, retrying in %s", e.provider, e.retry.Sub(e.now()).Round(time.Second))
+}
+
+type breaker struct {
+	provider string
+	failures int           // consecutive failures that open the circuit
+	cooldown time.Duration // how long the circuit stays open
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    circuit
+	failed   int
+	openedAt time.Time
+}
+
+func newBreaker(provider string, failures int, cooldown time.Duration) *breaker {
+	circuitState.set(float64(circuitClosed), provider)
+	return &breaker{provider: provider, failures: failures, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns nil when a request may be made, and a *circuitOpenError
+// otherwise. Once the cooldown has passed the first caller gets to probe.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if retry := b.openedAt.Add(b.cooldown); b.now().Before(retry) {
+			return &circuitOpenError{b.provider, retry, b.now}
+		}
+		b.transition(circuitHalfOpen)
+		return nil
+	case circuitHalfOpen:
+		// A probe is in flight.
+		return &circuitOpenError{b.provider, b.now().Add(b.cooldown), b.now}
+	}
+	return nil
+}
+
+// record updates the circuit with the outcome of an allowed request. Only
+// failures of the provider itself count; a rejected request, like an
+// unknown location, shows the provider is up.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && isUnavailable(err) {
+		// The requests allowed before the circuit opened may still fail
+		// after it; they do not open it again.
+		if b.state == circuitOpen {
+			return
+		}
+		b.failed++
+		if b.state == circuitHalfOpen || b.failed >= b.failures {
+			b.openedAt = b.now()
+			b.transition(circuitOpen)
+		}
+		return
+	}
+	b.failed = 0
+	if b.state != circuitClosed {
+		b.transition(circuitClosed)
+	}
+}
+
+// transition changes the state. Callers hold mu.
+func (b *breaker) transition(state circuit) {
+	b.state = state
+	circuitState.set(float64(state), b.provider)
+	circuitTransitions.inc(b.provider, circuitNames[state])
+}
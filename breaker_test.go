@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is the clock of a breaker under test, advanced by hand.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// testBreaker returns a breaker of provider, a name of its own so that the
+// metrics of the tests are apart, and its clock.
+func testBreaker(provider string, failures int, cooldown time.Duration) (*breaker, *testClock) {
+	clock := &testClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	b := newBreaker(provider, failures, cooldown)
+	b.now = clock.now
+	return b, clock
+}
+
+var (
+	errUnavailable = &statusError{code: http.StatusServiceUnavailable}
+	errNotFound    = &statusError{code: http.StatusNotFound}
+	errNetwork     = &url.Error{Op: "Get", URL: "https://api.openweathermap.org", Err: errors.New("connection refused")}
+)
+
+// metricValue returns the value of c for the label values, or -1 when it
+// has none.
+func metricValue(c *counterVec, values ...string) float64 {
+	value := -1.0
+	c.each(func(v []string, n float64) {
+		if slices.Equal(v, values) {
+			value = n
+		}
+	})
+	return value
+}
+
+func TestBreakerTrips(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []error
+		open     bool
+	}{
+		{"below the threshold", []error{errUnavailable, errUnavailable}, false},
+		{"at the threshold", []error{errUnavailable, errUnavailable, errUnavailable}, true},
+		{"reset by a success", []error{errUnavailable, errUnavailable, nil, errUnavailable, errUnavailable}, false},
+		{"rejected requests do not count", []error{errUnavailable, errNotFound, errUnavailable, errNotFound}, false},
+		{"network errors count", []error{errUnavailable, errNetwork, errUnavailable}, true},
+	}
+	for _, tt := range tests {
+		provider := "trips " + tt.name
+		b, clock := testBreaker(provider, 3, 30*time.Second)
+		for _, err := range tt.outcomes {
+			if err := b.allow(); err != nil {
+				t.Fatalf("%s: request rejected before the circuit opened: %s", tt.name, err)
+			}
+			b.record(err)
+			clock.advance(time.Second)
+		}
+
+		err := b.allow()
+		var open *circuitOpenError
+		if got := errors.As(err, &open); got != tt.open {
+			t.Errorf("%s: circuit open %t, want %t", tt.name, got, tt.open)
+			continue
+		}
+		want, transitions := circuitClosed, -1.0
+		if tt.open {
+			want, transitions = circuitOpen, 1
+			if retry := clock.t.Add(-time.Second).Add(30 * time.Second); !open.retry.Equal(retry) {
+				t.Errorf("%s: retry at %s, want %s", tt.name, open.retry, retry)
+			}
+		}
+		if got := metricValue(circuitState, provider); got != float64(want) {
+			t.Errorf("%s: circuit state metric %v, want %v", tt.name, got, float64(want))
+		}
+		if got := metricValue(circuitTransitions, provider, "open"); got != transitions {
+			t.Errorf("%s: %v transitions to open, want %v", tt.name, got, transitions)
+		}
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe error
+		want  circuit
+	}{
+		{"success", nil, circuitClosed},
+		{"rejected request", errNotFound, circuitClosed},
+		{"failure", errUnavailable, circuitOpen},
+	}
+	for _, tt := range tests {
+		provider := "probe " + tt.name
+		b, clock := testBreaker(provider, 2, time.Minute)
+		b.record(errUnavailable)
+		b.record(errUnavailable)
+
+		clock.advance(time.Minute - time.Second)
+		if err := b.allow(); err == nil {
+			t.Fatalf("%s: probe allowed before the cooldown", tt.name)
+		}
+		clock.advance(time.Second)
+		if err := b.allow(); err != nil {
+			t.Fatalf("%s: probe rejected after the cooldown: %s", tt.name, err)
+		}
+		if got := metricValue(circuitState, provider); got != float64(circuitHalfOpen) {
+			t.Errorf("%s: circuit state metric %v while probing, want %v", tt.name, got, float64(circuitHalfOpen))
+		}
+
+		b.record(tt.probe)
+		if got := metricValue(circuitState, provider); got != float64(tt.want) {
+			t.Errorf("%s: circuit state metric %v after the probe, want %v", tt.name, got, float64(tt.want))
+		}
+		err := b.allow()
+		switch tt.want {
+		case circuitClosed:
+			if err != nil {
+				t.Errorf("%s: request rejected after the circuit closed: %s", tt.name, err)
+			}
+			if got := metricValue(circuitTransitions, provider, "closed"); got != 1 {
+				t.Errorf("%s: %v transitions to closed, want 1", tt.name, got)
+			}
+		case circuitOpen:
+			// A failed probe opens the circuit for another cooldown.
+			if err == nil {
+				t.Errorf("%s: request allowed after a failed probe", tt.name)
+			}
+			clock.advance(time.Minute)
+			if err := b.allow(); err != nil {
+				t.Errorf("%s: second probe rejected after another cooldown: %s", tt.name, err)
+			}
+			if got := metricValue(circuitTransitions, provider, "open"); got != 2 {
+				t.Errorf("%s: %v transitions to open, want 2", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	const callers = 20
+	b, clock := testBreaker("single probe", 1, time.Minute)
+	b.record(errUnavailable)
+	clock.advance(time.Minute)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	probes := 0
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.allow() == nil {
+				mu.Lock()
+				probes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if probes != 1 {
+		t.Errorf("%d of %d concurrent callers probed, want 1", probes, callers)
+	}
+	if got := metricValue(circuitTransitions, "single probe", "half-open"); got != 1 {
+		t.Errorf("%v transitions to half-open, want 1", got)
+	}
+
+	var metrics strings.Builder
+	circuitState.write(&metrics)
+	if want := `weather_provider_circuit_state{provider="single probe"} 2` + "\n"; !strings.Contains(metrics.String(), want) {
+		t.Errorf("exported metrics lack %q:\n%s", want, metrics.String())
+	}
+}
+
+func TestBreakerLateFailures(t *testing.T) {
+	// Requests allowed while the circuit was closed fail after it opened.
+	b, clock := testBreaker("late failures", 2, time.Minute)
+	for i := 0; i < 5; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("request %d rejected before any failed: %s", i, err)
+		}
+	}
+	b.record(errUnavailable)
+	b.record(errUnavailable)
+	clock.advance(30 * time.Second)
+	b.record(errUnavailable)
+	b.record(errUnavailable)
+	b.record(errUnavailable)
+
+	if got := metricValue(circuitTransitions, "late failures", "open"); got != 1 {
+		t.Errorf("%v transitions to open, want 1", got)
+	}
+	clock.advance(30 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Errorf("probe rejected a cooldown after the circuit opened: %s", err)
+	}
+}
+
+func TestCircuitOpenErrorMessage(t *testing.T) {
+	b, clock := testBreaker("message", 1, time.Minute)
+	b.record(errUnavailable)
+	clock.advance(15 * time.Second)
+
+	err := b.allow()
+	if err == nil {
+		t.Fatal("request allowed while the circuit is open")
+	}
+	clock.advance(5 * time.Second)
+	if want := "message is failing, retrying in 40s"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
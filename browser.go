@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// -open opens a web page with the weather of the location in the default
+// browser, for when the summary is not enough: the weather map of
+// OpenWeather or the forecast of windy.com at the coordinates.
+
+var openSiteValues = []string{"openweather", "windy"}
+
+var errBrowserUnsupported = errors.New("opening a browser is not supported on this platform")
+
+func openFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.open, "open", false, "open the weather of the location in the default browser")
+	fs.Func("open-site", "the web site -open opens ("+strings.Join(openSiteValues, "|")+") (default openweather)", enumFlag(&opt.openSite, "site", openSiteValues))
+}
+
+// locationPage returns the URL of the page of site at lat, lon.
+func locationPage(site string, lat, lon float64) string {
+	if site == "windy" {
+		return fmt.Sprintf("https://www.windy.com/?%.4f,%.4f,10", lat, lon)
+	}
+	v := url.Values{}
+	v.Set("basemap", "map")
+	v.Set("cities", "true")
+	v.Set("layer", "temperature")
+	v.Set("lat", fmt.Sprintf("%.4f", lat))
+	v.Set("lon", fmt.Sprintf("%.4f", lon))
+	v.Set("zoom", "10")
+	return "https://openweathermap.org/weathermap?" + v.Encode()
+}
+
+// openLocation opens the page of city in the browser with -open, once per
+// location even in watch mode. Failures are reported but do not stop the
+// command.
+func (s *session) openLocation(city string) {
+	if !s.opt.open || s.opened[city] {
+		return
+	}
+	if s.opened == nil {
+		s.opened = map[string]bool{}
+	}
+	s.opened[city] = true
+
+	l, err := s.provider().locate(city)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: open: %s\n", redact(err.Error()))
+		return
+	}
+	if err := openBrowser(locationPage(s.opt.openSite, l.Lat, l.Lon)); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: open: %s\n", err)
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "os/exec"
+
+func openBrowser(u string) error {
+	return exec.Command("open", u).Start()
+}
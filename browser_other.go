@@ -0,0 +1,7 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package main
+
+func openBrowser(u string) error {
+	return errBrowserUnsupported
+}
@@ -0,0 +1,18 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Pages are opened with xdg-open from xdg-utils.
+
+func openBrowser(u string) error {
+	path, err := exec.LookPath("xdg-open")
+	if err != nil {
+		return errors.New("xdg-open not found, install xdg-utils")
+	}
+	return exec.Command(path, u).Start()
+}
@@ -0,0 +1,10 @@
+package main
+
+import "os/exec"
+
+// The URL protocol handler opens the page without the quoting rules of
+// cmd /c start, which would split it at every &.
+
+func openBrowser(u string) error {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+}
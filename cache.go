@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cache stores raw provider responses on disk, one file per request.
+type cache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	Key  string          `json:"key"`
+	Time time.Time       `json:"time"`
+	Body json.RawMessage `json:"body"`
+}
+
+type cacheStats struct {
+	Entries int
+	Fresh   int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "weather")
+}
+
+func newCache(dir string) *cache {
+	return &cache{dir: dir}
+}
+
+// path returns the file of key. Keys contain the full request so they are
+// hashed into file names.
+func (c *cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// get returns the cached response for key regardless of its age.
+func (c *cache) get(key string) (*cacheEntry, bool) {
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer bodyBuffers.Put(buf)
+	buf.Reset()
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, false
+	}
+	// The body is copied out of the buffer.
+	var e cacheEntry
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil || e.Key != key {
+		return nil, false
+	}
+	return &e, true
+}
+
+// put stores body for key. The file is written to a temporary file first so
+// that concurrent readers never see a partial entry.
+func (c *cache) put(key string, body []byte) error {
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer bodyBuffers.Put(buf)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(cacheEntry{Key: key, Time: time.Now(), Body: body}); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *cache) files() ([]string, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, filepath.Join(c.dir, e.Name()))
+		}
+	}
+	return files, nil
+}
+
+// clear removes every entry and returns the number of entries removed.
+func (c *cache) clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+func (c *cache) stats(ttl time.Duration) (cacheStats, error) {
+	var st cacheStats
+	files, err := c.files()
+	if err != nil {
+		return st, err
+	}
+
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var e cacheEntry
+		if json.Unmarshal(b, &e) != nil {
+			continue
+		}
+
+		st.Entries++
+		st.Size += int64(len(b))
+		if time.Since(e.Time) < ttl {
+			st.Fresh++
+		}
+		if st.Oldest.IsZero() || e.Time.Before(st.Oldest) {
+			st.Oldest = e.Time
+		}
+		if e.Time.After(st.Newest) {
+			st.Newest = e.Time
+		}
+	}
+	return st, nil
+}
+
+func runCache(s *session) {
+	args := s.args()
+	if len(args) == 0 || len(args) > 1 && args[0] != "forget" {
+		exitWithUsageError("usage: weather cache clear|stats|forget <location>")
+	}
+	if s.opt.cacheDir == "" {
+		exitWithError("caching is disabled")
+	}
+	c := newCache(s.opt.cacheDir)
+	geocoding := newGeocodingCache(s.opt.cacheDir)
+
+	switch args[0] {
+	case "clear":
+		n, err := c.clear()
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		g, err := geocoding.clear()
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		fmt.Printf("removed %d cached responses and %d geocoding results\n", n, g)
+
+	case "forget":
+		if len(args) < 2 {
+			exitWithUsageError("usage: weather cache forget <location>")
+		}
+		name := strings.Join(args[1:], " ")
+		if alias, ok := s.cfg.string("aliases." + name); ok {
+			name = alias
+		}
+		if lat, lon, ok := parseCoordinates(name); ok {
+			name = formatCoordinates(lat, lon)
+		}
+		n, err := geocoding.forget(name)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		fmt.Printf("removed %d geocoding results for %s\n", n, name)
+
+	case "stats":
+		st, err := c.stats(s.opt.cacheTTL)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		fmt.Printf("directory: %s\n", c.dir)
+		fmt.Printf("entries: %d (%d fresh, ttl %s)\n", st.Entries, st.Fresh, s.opt.cacheTTL)
+		fmt.Printf("size: %.1f KiB\n", float64(st.Size)/1024)
+		if st.Entries > 0 {
+			fmt.Printf("oldest: %s\n", st.Oldest.Format(time.Stamp))
+			fmt.Printf("newest: %s\n", st.Newest.Format(time.Stamp))
+		}
+		if gst, err := geocoding.stats(0); err == nil {
+			fmt.Printf("geocoding: %d locations\n", gst.Entries)
+		}
+
+	default:
+		exitWithUsageError(fmt.Sprintf("unknown cache command %q", args[0]))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	temperatureChartHeight   = 8
+	precipitationChartHeight = 3
+)
+
+// terminalWidth returns the width of the terminal on stdout, falling back
+// to $COLUMNS and then to 80 columns when stdout is not a terminal.
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(terminalStdout().Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// displayForecastChart plots the forecast temperature and precipitation as
+// block-character bar charts sharing a time axis. The entries are stretched
+// or sampled to fill width columns.
+func displayForecastChart(w io.Writer, f *Forecast, opt *options, width int) {
+	temperatureSymbol, _ := unitSymbols(opt.units)
+
+	localFprintf(w, "%s forecast%s\n", f.CityName, staleNote(f.CachedAt))
+	if len(f.Entries) == 0 {
+		return
+	}
+
+	low, high := f.Entries[0].Temperature, f.Entries[0].Temperature
+	wettest := 0.0
+	for _, e := range f.Entries {
+		low = min(low, e.Temperature)
+		high = max(high, e.Temperature)
+		wettest = max(wettest, e.Precipitation)
+	}
+
+	labels := []string{
+		localSprintf("%.0f°%s", high, temperatureSymbol),
+		localSprintf("%.0f°%s", low, temperatureSymbol),
+		localSprintf("%.1fmm", wettest),
+		"0mm",
+	}
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, displayWidth(label))
+	}
+
+	columns := max(width-labelWidth-2, 1)
+	sampled := make([]ForecastEntry, columns)
+	for c := range sampled {
+		sampled[c] = f.Entries[c*len(f.Entries)/columns]
+	}
+
+	// Bars are measured in eighths of a row. The lowest temperature still
+	// gets the smallest block so the chart never has gaps.
+	temperatures := make([]int, columns)
+	precipitation := make([]int, columns)
+	for c, e := range sampled {
+		temperatures[c] = temperatureChartHeight * 8
+		if high > low {
+			temperatures[c] = 1 + int(math.Round((e.Temperature-low)/(high-low)*float64(temperatureChartHeight*8-1)))
+		}
+		if wettest > 0 {
+			precipitation[c] = int(math.Ceil(e.Precipitation / wettest * precipitationChartHeight * 8))
+		}
+	}
+
+	writeBars(w, temperatures, temperatureChartHeight, labels[0], labels[1], labelWidth)
+	writeBars(w, precipitation, precipitationChartHeight, labels[2], labels[3], labelWidth)
+
+	// Label the axis with the weekday where each day starts, skipping
+	// labels that would overlap the previous one.
+	axis := []rune(strings.Repeat(" ", columns))
+	day, next := -1, 0
+	for c, e := range sampled {
+		t := localTime(e.Time, f.TimeZone)
+		if t.YearDay() == day {
+			continue
+		}
+		day = t.YearDay()
+		name := t.Format("Mon")
+		if c >= next && c+len(name) <= columns {
+			copy(axis[c:], []rune(name))
+			next = c + len(name) + 1
+		}
+	}
+	localFprintf(w, "%s  %s\n", strings.Repeat(" ", labelWidth), strings.TrimRight(string(axis), " "))
+}
+
+// writeBars writes height rows of vertical bars, top row first. Each bar
+// is given in eighths of a row.
+func writeBars(w io.Writer, bars []int, height int, top, bottom string, labelWidth int) {
+	for row := height - 1; row >= 0; row-- {
+		label, axis := "", "│"
+		switch row {
+		case height - 1:
+			label, axis = top, "┤"
+		case 0:
+			label, axis = bottom, "┤"
+		}
+
+		var sb strings.Builder
+		for _, bar := range bars {
+			fill := min(max(bar-row*8, 0), 8)
+			if fill == 0 {
+				sb.WriteByte(' ')
+			} else {
+				sb.WriteRune(sparkBlocks[fill-1])
+			}
+		}
+		padding := strings.Repeat(" ", labelWidth-displayWidth(label))
+		localFprintf(w, "%s%s %s%s\n", padding, label, axis, strings.TrimRight(sb.String(), " "))
+	}
+}
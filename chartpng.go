@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	pngChartWidth  = 800
+	pngChartHeight = 400
+	pngChartMargin = 50
+)
+
+var (
+	pngBackground    = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	pngAxis          = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	pngText          = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	pngTemperature   = color.RGBA{0xd6, 0x45, 0x2b, 0xff}
+	pngPrecipitation = color.RGBA{0x4a, 0x90, 0xd9, 0xff}
+)
+
+func isPNGPath(format string) bool {
+	return strings.HasSuffix(strings.ToLower(format), ".png")
+}
+
+// writeForecastPNG renders the forecast temperature as a line and the
+// precipitation as bars with their own scale into a PNG file at path.
+func writeForecastPNG(path string, f *Forecast, opt *options) error {
+	if len(f.Entries) == 0 {
+		return fmt.Errorf("no forecast entries")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, pngChartWidth, pngChartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(pngBackground), image.Point{}, draw.Src)
+
+	plot := image.Rect(pngChartMargin, pngChartMargin, pngChartWidth-pngChartMargin, pngChartHeight-pngChartMargin)
+	temperatureSymbol, _ := unitSymbols(opt.units)
+
+	low, high := f.Entries[0].Temperature, f.Entries[0].Temperature
+	wettest := 0.0
+	for _, e := range f.Entries {
+		low = min(low, e.Temperature)
+		high = max(high, e.Temperature)
+		wettest = max(wettest, e.Precipitation)
+	}
+	if high == low {
+		low, high = low-1, high+1
+	}
+
+	x := func(i int) int {
+		if len(f.Entries) == 1 {
+			return plot.Min.X
+		}
+		return plot.Min.X + i*plot.Dx()/(len(f.Entries)-1)
+	}
+	y := func(temperature float64) int {
+		return plot.Max.Y - int((temperature-low)/(high-low)*float64(plot.Dy()))
+	}
+
+	// Precipitation bars fill the lower third of the plot.
+	if wettest > 0 {
+		barWidth := max(plot.Dx()/len(f.Entries)-2, 1)
+		for i, e := range f.Entries {
+			height := int(e.Precipitation / wettest * float64(plot.Dy()) / 3)
+			bar := image.Rect(x(i)-barWidth/2, plot.Max.Y-height, x(i)+barWidth/2+1, plot.Max.Y)
+			draw.Draw(img, bar.Intersect(plot), image.NewUniform(pngPrecipitation), image.Point{}, draw.Src)
+		}
+	}
+
+	for i := 1; i < len(f.Entries); i++ {
+		drawLine(img, x(i-1), y(f.Entries[i-1].Temperature), x(i), y(f.Entries[i].Temperature), pngTemperature)
+	}
+
+	drawLine(img, plot.Min.X, plot.Min.Y, plot.Min.X, plot.Max.Y, pngAxis)
+	drawLine(img, plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y, pngAxis)
+
+	drawText(img, pngChartMargin, pngChartMargin/2, f.CityName+" forecast", pngText)
+	// The basic font only covers ASCII, so there is no degree sign.
+	drawText(img, 4, plot.Min.Y+4, fmt.Sprintf("%.0f %s", high, temperatureSymbol), pngTemperature)
+	drawText(img, 4, plot.Max.Y+4, fmt.Sprintf("%.0f %s", low, temperatureSymbol), pngTemperature)
+	if wettest > 0 {
+		drawText(img, plot.Max.X+4, plot.Max.Y-plot.Dy()/3+4, fmt.Sprintf("%.1fmm", wettest), pngPrecipitation)
+	}
+
+	day := -1
+	for i, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		if t.YearDay() != day {
+			day = t.YearDay()
+			drawLine(img, x(i), plot.Max.Y, x(i), plot.Max.Y+4, pngAxis)
+			drawText(img, x(i)+2, plot.Max.Y+18, t.Format("Mon 2"), pngText)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// drawLine draws a two pixel wide line from (x0, y0) to (x1, y1).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		img.Set(x0+1, y0, c)
+		img.Set(x0, y0+1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if 2*e >= dy {
+			e += dy
+			x0 += sx
+		}
+		if 2*e <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// drawText draws s with its baseline starting at (x, y).
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
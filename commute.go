@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// weather commute shows only what matters for the commute: the weather at
+// home when leaving in the morning and at work when heading home in the
+// evening, each within a window of local time.
+
+func commuteFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.commuteHome, "home", "", "home location")
+	fs.StringVar(&opt.commuteWork, "work", "", "work location")
+	fs.StringVar(&opt.commuteMorning, "morning", "07:30-08:30", "local time window of leaving for work")
+	fs.StringVar(&opt.commuteEvening, "evening", "16:30-17:30", "local time window of heading home")
+}
+
+// commuteLeg is the weather of a leg of the commute during its window:
+// the range of temperatures, the conditions at the start of the window and
+// the strongest wind and the precipitation of the periods it overlaps.
+type commuteLeg struct {
+	Leg           string    `json:"leg"`
+	City          string    `json:"city"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Low           float64   `json:"low"`
+	High          float64   `json:"high"`
+	Conditions    string    `json:"conditions"`
+	Icon          string    `json:"icon"`
+	WindSpeed     float64   `json:"wind_speed"`
+	Precipitation float64   `json:"precipitation"`
+	Probability   float64   `json:"precipitation_probability"`
+	Advice        []string  `json:"advice"`
+}
+
+// parseWindow parses a window such as "07:30-08:30" into the times of day
+// it starts and ends at.
+func parseWindow(value string) (start, end time.Time, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if ok {
+		start, err = time.Parse("15:04", strings.TrimSpace(from))
+	}
+	if ok && err == nil {
+		end, err = time.Parse("15:04", strings.TrimSpace(to))
+	}
+	if !ok || err != nil || !end.After(start) {
+		return start, end, fmt.Errorf("invalid window %q, expected e.g. 07:30-08:30", value)
+	}
+	return start, end, nil
+}
+
+// nextWindow returns the next occurrence of the window that has not ended
+// by now, today or tomorrow.
+func nextWindow(now, start, end time.Time) (time.Time, time.Time) {
+	at := func(t time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	}
+	s, e := at(start), at(end)
+	if !e.After(now) {
+		s, e = s.AddDate(0, 0, 1), e.AddDate(0, 0, 1)
+	}
+	return s, e
+}
+
+// commuteWeather summarizes the entries of f overlapping start to end, or
+// returns nil when the window is beyond the forecast.
+func commuteWeather(f *Forecast, leg string, start, end time.Time, units string) *commuteLeg {
+	var l *commuteLeg
+	for _, e := range f.Entries {
+		if !e.Time.Add(forecastPeriod).After(start) || !e.Time.Before(end) {
+			continue
+		}
+		if l == nil {
+			l = &commuteLeg{Leg: leg, City: f.CityName, Start: start, End: end, Low: e.Temperature, High: e.Temperature,
+				Conditions: e.Conditions, Icon: e.Icon}
+		}
+		l.Low, l.High = min(l.Low, e.Temperature), max(l.High, e.Temperature)
+		l.WindSpeed = max(l.WindSpeed, e.WindSpeed)
+		l.Precipitation += e.Precipitation
+		l.Probability = max(l.Probability, e.Probability)
+	}
+	if l != nil {
+		l.Advice = l.advice(units)
+	}
+	return l
+}
+
+// advice returns the reminders for the leg. The thresholds are metric.
+func (l *commuteLeg) advice(units string) []string {
+	m := toMetric(&Weather{Temperature: l.Low, WindSpeed: l.WindSpeed}, units)
+	wet := l.Precipitation > 0 && l.Probability >= rainProbability
+	advice := []string{}
+	switch {
+	case wet && strings.HasPrefix(l.Icon, "13"):
+		advice = append(advice, "snow on the way")
+	case wet:
+		advice = append(advice, "take an umbrella")
+	}
+	if m.Temperature <= 0 {
+		advice = append(advice, "watch for ice")
+	}
+	if m.WindSpeed >= 10 {
+		advice = append(advice, "strong wind")
+	}
+	return advice
+}
+
+// runCommute shows the morning leg at home and the evening leg at work,
+// each at its next window.
+func runCommute(s *session) {
+	if len(s.args()) > 0 {
+		exitWithUsageError("usage: weather commute [-home <city>] [-work <city>]")
+	}
+	if s.opt.commuteHome == "" || s.opt.commuteWork == "" {
+		exitWithUsageError("home and work are required, give them with -home and -work or as commute_home and commute_work in the config")
+	}
+
+	now := time.Now()
+	legs := []struct{ name, city, window string }{
+		{"morning", s.opt.commuteHome, s.opt.commuteMorning},
+		{"evening", s.opt.commuteWork, s.opt.commuteEvening},
+	}
+	var result []*commuteLeg
+	for _, leg := range legs {
+		start, end, err := parseWindow(leg.window)
+		if err != nil {
+			exitWithUsageError(err.Error())
+		}
+		start, end = nextWindow(now, start, end)
+
+		f, err := s.provider().forecast(s.query(s.citiesFrom([]string{leg.city})[0]))
+		if exitOnError(err) {
+			continue
+		}
+		if l := commuteWeather(f, leg.name, start, end, s.opt.units); l != nil {
+			result = append(result, l)
+		}
+	}
+	if s.opt.dryRun || s.opt.raw {
+		return
+	}
+	displayCommute(os.Stdout, result, s.opt)
+}
+
+// displayCommute writes a line per leg, e.g.
+// "Morning 07:30–08:30 Helsinki: 🌧️ light rain, 1–2°C, wind 5.1 m/s, 0.8 mm (70%) — take an umbrella".
+func displayCommute(w io.Writer, legs []*commuteLeg, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, legs, opt.format)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	now := time.Now()
+	for _, l := range legs {
+		day := ""
+		if l.Start.YearDay() != now.YearDay() {
+			day = l.Start.Format("Mon ")
+		}
+		temperature := localSprintf("%.0f°%s", l.High, temperatureSymbol)
+		if localSprintf("%.0f", l.Low) != localSprintf("%.0f", l.High) {
+			temperature = localSprintf("%.0f–%.0f°%s", l.Low, l.High, temperatureSymbol)
+		}
+		localFprintf(w, "%s %s%s–%s %s: %s %s, %s, wind %.1f %s, %.1f mm (%.0f%%)",
+			strings.ToUpper(l.Leg[:1])+l.Leg[1:], day, l.Start.Format("15:04"), l.End.Format("15:04"), l.City,
+			weatherIconIdToEmoji(l.Icon), l.Conditions, temperature, l.WindSpeed, windSpeedSymbol, l.Precipitation, l.Probability*100)
+		if len(l.Advice) > 0 {
+			localFprintf(w, " — %s", strings.Join(l.Advice, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
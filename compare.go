@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// runCompare shows the current weather of two locations side by side.
+// Each argument is a location, so names of several words must be quoted:
+// weather compare "new york" london.
+func runCompare(s *session) {
+	args := s.args()
+	if len(args) != 2 {
+		exitWithUsageError("usage: weather compare <city> <city>")
+	}
+
+	var ws [2]*Weather
+	for i, arg := range args {
+		city := s.citiesFrom([]string{arg})[0]
+		if city == arg {
+			city = s.disambiguate(city)
+		}
+		w, err := s.provider().current(s.query(city))
+		if exitOnError(err) {
+			return
+		}
+		s.recordHistory(w, s.opt.units)
+		ws[i] = w
+	}
+	displayCompare(os.Stdout, ws[0], ws[1], s.opt)
+}
+
+// displayCompare writes a and b in adjacent columns followed by how much b
+// differs from a, highlighted on a color terminal.
+func displayCompare(w io.Writer, a, b *Weather, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, []*Weather{a, b}, opt.format)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	m := &changeMarker{color: colorOutput(w)}
+	var buf bytes.Buffer
+	tw := newTable(&buf, 3)
+	row := func(name, va, vb, difference string) {
+		if difference != "" {
+			difference = m.highlight(difference)
+		}
+		localFprintf(tw, "%s\t%s\t%s\t%s\n", name, va, vb, difference)
+	}
+	number := func(name, format string, va, vb float64) {
+		sa, sb := localSprintf(format, va), localSprintf(format, vb)
+		difference := ""
+		// Compare the values as displayed, like changeMarker.
+		if sa != sb {
+			difference = localSprintf("%+"+format[1:], vb-va)
+		}
+		row(name, sa, sb, difference)
+	}
+
+	localFprintf(tw, "\t%s%s\t%s%s\t\n", a.CityName, staleNote(a.CachedAt), b.CityName, staleNote(b.CachedAt))
+	conditions := ""
+	if a.Conditions != b.Conditions {
+		conditions = "≠"
+	}
+	row("conditions", weatherIconIdToEmoji(a.Icon)+" "+a.Conditions, weatherIconIdToEmoji(b.Icon)+" "+b.Conditions, conditions)
+	number("temperature", "%.0f°"+temperatureSymbol, a.Temperature, b.Temperature)
+	number("humidity", "%.0f%%", a.Humidity, b.Humidity)
+	number("wind", "%.1f "+windSpeedSymbol, a.WindSpeed, b.WindSpeed)
+	number("pressure", "%.0f hPa", a.Pressure, b.Pressure)
+	number("visibility", "%.1f km", a.Visibility/1000, b.Visibility/1000)
+
+	now := time.Now()
+	offset := ""
+	if hours := float64(b.TimeZone-a.TimeZone) / 3600; hours != 0 {
+		offset = localSprintf("%+gh", math.Round(hours*100)/100)
+	}
+	row("local time", localTime(now, a.TimeZone).Format("15:04"), localTime(now, b.TimeZone).Format("15:04"), offset)
+	tw.Flush()
+
+	// Rows without a difference end in padding.
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		io.WriteString(w, strings.TrimRight(line, " \n"))
+		if strings.HasSuffix(line, "\n") {
+			io.WriteString(w, "\n")
+		}
+	}
+}
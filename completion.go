@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// flagValues lists the accepted values of enumerated flags whose usage does
+// not list them, see flagUsageValues.
+var flagValues = map[string][]string{
+	"lang":     langValues,
+	"provider": providerValues,
+}
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// The scripts call the hidden __complete command with the words typed so
+// far, the last one being the word under the cursor, and offer the lines it
+// prints as candidates.
+const bashCompletion = `# bash completion for weather
+_weather() {
+	local IFS=$'\n'
+	COMPREPLY=($(weather __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	COMPREPLY=("${COMPREPLY[@]// /\\ }")
+}
+complete -o default -F _weather weather
+`
+
+const zshCompletion = `#compdef weather
+# zsh completion for weather
+_weather() {
+	local -a candidates
+	candidates=("${(@f)$(weather __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a candidates
+}
+compdef _weather weather
+`
+
+const fishCompletion = `# fish completion for weather
+function __weather_complete
+	set -l tokens (commandline -opc) (commandline -ct)
+	weather __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c weather -f -a '(__weather_complete)'
+`
+
+const powershellCompletion = `# PowerShell completion for weather
+Register-ArgumentCompleter -Native -CommandName weather -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+	if ($wordToComplete -eq '') { $words += '""' }
+	& weather __complete @words 2>$null | ForEach-Object {
+		$text = if ($_ -match '\s') { "'$_'" } else { $_ }
+		[System.Management.Automation.CompletionResult]::new($text, $_, 'ParameterValue', $_)
+	}
+}
+`
+
+func runCompletion(s *session) {
+	args := s.args()
+	if len(args) != 1 {
+		exitWithUsageError("usage: weather completion " + strings.Join(completionShells, "|"))
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	case "powershell":
+		fmt.Print(powershellCompletion)
+	default:
+		exitWithUsageError(checkEnum("shell", args[0], completionShells).Error())
+	}
+}
+
+// runComplete prints the completion candidates for the words typed so far.
+func runComplete(s *session) {
+	words := s.args()
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	complete(os.Stdout, words, completionLocations())
+}
+
+// completionLocations returns the favorites, default city and aliases from
+// the config. The config is read on a best-effort basis.
+func completionLocations() []string {
+	path := os.Getenv("WEATHER_CONFIG")
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil
+	}
+
+	var locations []string
+	locations = append(locations, cfg.list("favorites")...)
+	locations = append(locations, cfg.list("city")...)
+	for key := range cfg.values {
+		if name, ok := strings.CutPrefix(key, "aliases."); ok {
+			locations = append(locations, name)
+		}
+	}
+	return locations
+}
+
+func complete(w io.Writer, words []string, locations []string) {
+	current, previous := words[len(words)-1], words[:len(words)-1]
+
+	cmd := commands[0]
+	if len(previous) > 0 {
+		if c := findCommand(previous[0]); c != nil {
+			cmd, previous = c, previous[1:]
+		}
+	}
+
+	var candidates []string
+	switch {
+	case len(previous) > 0 && strings.HasPrefix(previous[len(previous)-1], "-"):
+		name := strings.TrimLeft(previous[len(previous)-1], "-")
+		if values := flagUsageValues(cmd, name); values != nil {
+			candidates = values
+			break
+		}
+		if values, ok := flagValues[name]; ok {
+			candidates = values
+			break
+		}
+		fallthrough
+
+	default:
+		if strings.HasPrefix(current, "-") {
+			candidates = commandFlagNames(cmd)
+			break
+		}
+
+		candidates = completionArgs(cmd, previous, locations)
+		// The first word may also be a command name.
+		if len(words) == 1 {
+			for _, c := range commands {
+				if !c.hidden {
+					candidates = append(candidates, c.name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(candidates)
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, current) && !seen[c] {
+			seen[c] = true
+			fmt.Fprintln(w, c)
+		}
+	}
+}
+
+func completionArgs(cmd *command, previous []string, locations []string) []string {
+	positional := 0
+	for _, word := range previous {
+		if !strings.HasPrefix(word, "-") {
+			positional++
+		}
+	}
+
+	switch cmd.name {
+	case "auth":
+		if positional == 0 {
+			return []string{"check", "set-key"}
+		}
+	case "config":
+		if positional == 0 {
+			return []string{"get", "set", "unset", "list", "edit", "path"}
+		}
+		if positional == 1 {
+			var keys []string
+			for key := range configKeys {
+				keys = append(keys, key)
+			}
+			return keys
+		}
+	case "history":
+		if positional == 0 {
+			return []string{"show", "export"}
+		}
+		return locations
+	case "cache":
+		if positional == 0 {
+			return []string{"clear", "stats", "forget"}
+		}
+	case "completion":
+		if positional == 0 {
+			return completionShells
+		}
+	default:
+		return locations
+	}
+	return nil
+}
+
+// commandFlags returns the flags of cmd, or nil when it has none.
+func commandFlags(cmd *command) *flag.FlagSet {
+	if cmd.flags == nil {
+		return nil
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.flags(fs, &options{})
+	return fs
+}
+
+func commandFlagNames(cmd *command) []string {
+	fs := commandFlags(cmd)
+	if fs == nil {
+		return nil
+	}
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	return names
+}
+
+// usageValuesPattern matches the values an enumerated flag lists in its
+// usage, e.g. "(text|json|csv)".
+var usageValuesPattern = regexp.MustCompile(`\(([^()|\s]+(?:\|[^()|\s]+)+)\)`)
+
+// flagUsageValues returns the values of the flag name of cmd. Enumerated
+// flags list the values they check against in their usage, so that e.g. -o
+// completes to the output formats of cmd only. Placeholders such as
+// <file>.png are left out.
+func flagUsageValues(cmd *command, name string) []string {
+	fs := commandFlags(cmd)
+	if fs == nil {
+		return nil
+	}
+	f := fs.Lookup(name)
+	if f == nil {
+		return nil
+	}
+	m := usageValuesPattern.FindStringSubmatch(f.Usage)
+	if m == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range strings.Split(m[1], "|") {
+		if !strings.HasPrefix(v, "<") {
+			values = append(values, v)
+		}
+	}
+	return values
+}
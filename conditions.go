@@ -0,0 +1,372 @@
+package main
+
+import "strings"
+
+// OpenWeather describes the conditions in the language asked for, but a
+// response cached in English, as served offline for another language, or
+// a provider answering only in English would show the English description.
+// The descriptions of the condition codes are bundled for the most used
+// languages so that such descriptions are translated locally.
+//
+// Condition codes: https://openweathermap.org/weather-conditions
+
+// conditionDescriptions are the descriptions of the condition codes by
+// language.
+var conditionDescriptions = map[string]map[int]string{
+	"en": {
+		200: "thunderstorm with light rain",
+		201: "thunderstorm with rain",
+		202: "thunderstorm with heavy rain",
+		210: "light thunderstorm",
+		211: "thunderstorm",
+		212: "heavy thunderstorm",
+		221: "ragged thunderstorm",
+		230: "thunderstorm with light drizzle",
+		231: "thunderstorm with drizzle",
+		232: "thunderstorm with heavy drizzle",
+		300: "light intensity drizzle",
+		301: "drizzle",
+		302: "heavy intensity drizzle",
+		310: "light intensity drizzle rain",
+		311: "drizzle rain",
+		312: "heavy intensity drizzle rain",
+		313: "shower rain and drizzle",
+		314: "heavy shower rain and drizzle",
+		321: "shower drizzle",
+		500: "light rain",
+		501: "moderate rain",
+		502: "heavy intensity rain",
+		503: "very heavy rain",
+		504: "extreme rain",
+		511: "freezing rain",
+		520: "light intensity shower rain",
+		521: "shower rain",
+		522: "heavy intensity shower rain",
+		531: "ragged shower rain",
+		600: "light snow",
+		601: "snow",
+		602: "heavy snow",
+		611: "sleet",
+		612: "light shower sleet",
+		613: "shower sleet",
+		615: "light rain and snow",
+		616: "rain and snow",
+		620: "light shower snow",
+		621: "shower snow",
+		622: "heavy shower snow",
+		701: "mist",
+		711: "smoke",
+		721: "haze",
+		731: "sand/dust whirls",
+		741: "fog",
+		751: "sand",
+		761: "dust",
+		762: "volcanic ash",
+		771: "squalls",
+		781: "tornado",
+		800: "clear sky",
+		801: "few clouds",
+		802: "scattered clouds",
+		803: "broken clouds",
+		804: "overcast clouds",
+	},
+	"fi": {
+		200: "ukkosta ja heikkoa sadetta",
+		201: "ukkosta ja sadetta",
+		202: "ukkosta ja voimakasta sadetta",
+		210: "heikkoa ukkosta",
+		211: "ukkosta",
+		212: "voimakasta ukkosta",
+		221: "paikoittaista ukkosta",
+		230: "ukkosta ja heikkoa tihkusadetta",
+		231: "ukkosta ja tihkusadetta",
+		232: "ukkosta ja voimakasta tihkusadetta",
+		300: "heikkoa tihkusadetta",
+		301: "tihkusadetta",
+		302: "voimakasta tihkusadetta",
+		310: "heikkoa tihkua ja sadetta",
+		311: "tihkua ja sadetta",
+		312: "voimakasta tihkua ja sadetta",
+		313: "sadekuuroja ja tihkua",
+		314: "voimakkaita sadekuuroja ja tihkua",
+		321: "tihkukuuroja",
+		500: "heikkoa sadetta",
+		501: "kohtalaista sadetta",
+		502: "voimakasta sadetta",
+		503: "erittäin voimakasta sadetta",
+		504: "äärimmäisen voimakasta sadetta",
+		511: "jäätävää sadetta",
+		520: "heikkoja sadekuuroja",
+		521: "sadekuuroja",
+		522: "voimakkaita sadekuuroja",
+		531: "paikoittaisia sadekuuroja",
+		600: "heikkoa lumisadetta",
+		601: "lumisadetta",
+		602: "voimakasta lumisadetta",
+		611: "räntää",
+		612: "heikkoja räntäkuuroja",
+		613: "räntäkuuroja",
+		615: "heikkoa vesi- ja lumisadetta",
+		616: "vesi- ja lumisadetta",
+		620: "heikkoja lumikuuroja",
+		621: "lumikuuroja",
+		622: "voimakkaita lumikuuroja",
+		701: "utua",
+		711: "savua",
+		721: "auerta",
+		731: "hiekka- tai pölypyörteitä",
+		741: "sumua",
+		751: "hiekkaa",
+		761: "pölyä",
+		762: "tulivuoren tuhkaa",
+		771: "voimakkaita puuskia",
+		781: "trombi",
+		800: "selkeää",
+		801: "enimmäkseen selkeää",
+		802: "puolipilvistä",
+		803: "enimmäkseen pilvistä",
+		804: "pilvistä",
+	},
+	"sv": {
+		200: "åska med lätt regn",
+		201: "åska med regn",
+		202: "åska med kraftigt regn",
+		210: "lätt åska",
+		211: "åska",
+		212: "kraftig åska",
+		221: "spridd åska",
+		230: "åska med lätt duggregn",
+		231: "åska med duggregn",
+		232: "åska med kraftigt duggregn",
+		300: "lätt duggregn",
+		301: "duggregn",
+		302: "kraftigt duggregn",
+		310: "lätt duggregn och regn",
+		311: "duggregn och regn",
+		312: "kraftigt duggregn och regn",
+		313: "regnskurar och duggregn",
+		314: "kraftiga regnskurar och duggregn",
+		321: "duggregnsskurar",
+		500: "lätt regn",
+		501: "måttligt regn",
+		502: "kraftigt regn",
+		503: "mycket kraftigt regn",
+		504: "extremt regn",
+		511: "underkylt regn",
+		520: "lätta regnskurar",
+		521: "regnskurar",
+		522: "kraftiga regnskurar",
+		531: "spridda regnskurar",
+		600: "lätt snöfall",
+		601: "snöfall",
+		602: "kraftigt snöfall",
+		611: "snöblandat regn",
+		612: "lätta skurar av snöblandat regn",
+		613: "skurar av snöblandat regn",
+		615: "lätt regn och snö",
+		616: "regn och snö",
+		620: "lätta snöbyar",
+		621: "snöbyar",
+		622: "kraftiga snöbyar",
+		701: "fuktdis",
+		711: "rök",
+		721: "dis",
+		731: "sand- eller dammvirvlar",
+		741: "dimma",
+		751: "sand",
+		761: "damm",
+		762: "vulkanisk aska",
+		771: "kraftiga vindbyar",
+		781: "tromb",
+		800: "klart",
+		801: "nästan klart",
+		802: "halvklart",
+		803: "växlande molnighet",
+		804: "mulet",
+	},
+	"de": {
+		200: "Gewitter mit leichtem Regen",
+		201: "Gewitter mit Regen",
+		202: "Gewitter mit Starkregen",
+		210: "leichtes Gewitter",
+		211: "Gewitter",
+		212: "schweres Gewitter",
+		221: "vereinzelte Gewitter",
+		230: "Gewitter mit leichtem Nieselregen",
+		231: "Gewitter mit Nieselregen",
+		232: "Gewitter mit starkem Nieselregen",
+		300: "leichter Nieselregen",
+		301: "Nieselregen",
+		302: "starker Nieselregen",
+		310: "leichter Nieselregen und Regen",
+		311: "Nieselregen und Regen",
+		312: "starker Nieselregen und Regen",
+		313: "Regenschauer und Nieselregen",
+		314: "starke Regenschauer und Nieselregen",
+		321: "Nieselschauer",
+		500: "leichter Regen",
+		501: "mäßiger Regen",
+		502: "starker Regen",
+		503: "sehr starker Regen",
+		504: "extremer Regen",
+		511: "gefrierender Regen",
+		520: "leichte Regenschauer",
+		521: "Regenschauer",
+		522: "starke Regenschauer",
+		531: "vereinzelte Regenschauer",
+		600: "leichter Schneefall",
+		601: "Schneefall",
+		602: "starker Schneefall",
+		611: "Schneeregen",
+		612: "leichte Schneeregenschauer",
+		613: "Schneeregenschauer",
+		615: "leichter Regen und Schnee",
+		616: "Regen und Schnee",
+		620: "leichte Schneeschauer",
+		621: "Schneeschauer",
+		622: "starke Schneeschauer",
+		701: "feuchter Dunst",
+		711: "Rauch",
+		721: "Dunst",
+		731: "Sand- oder Staubwirbel",
+		741: "Nebel",
+		751: "Sand",
+		761: "Staub",
+		762: "Vulkanasche",
+		771: "Sturmböen",
+		781: "Tornado",
+		800: "klarer Himmel",
+		801: "ein paar Wolken",
+		802: "aufgelockerte Bewölkung",
+		803: "durchbrochene Bewölkung",
+		804: "bedeckt",
+	},
+	"fr": {
+		200: "orage avec pluie légère",
+		201: "orage avec pluie",
+		202: "orage avec fortes pluies",
+		210: "orage faible",
+		211: "orage",
+		212: "orage violent",
+		221: "orages isolés",
+		230: "orage avec bruine légère",
+		231: "orage avec bruine",
+		232: "orage avec forte bruine",
+		300: "bruine légère",
+		301: "bruine",
+		302: "forte bruine",
+		310: "bruine et pluie légères",
+		311: "bruine et pluie",
+		312: "forte bruine et pluie",
+		313: "averses de pluie et bruine",
+		314: "fortes averses de pluie et bruine",
+		321: "averses de bruine",
+		500: "pluie légère",
+		501: "pluie modérée",
+		502: "forte pluie",
+		503: "très forte pluie",
+		504: "pluie extrême",
+		511: "pluie verglaçante",
+		520: "légères averses de pluie",
+		521: "averses de pluie",
+		522: "fortes averses de pluie",
+		531: "averses isolées",
+		600: "légères chutes de neige",
+		601: "neige",
+		602: "fortes chutes de neige",
+		611: "neige fondue",
+		612: "légères averses de neige fondue",
+		613: "averses de neige fondue",
+		615: "pluie et neige légères",
+		616: "pluie et neige",
+		620: "légères averses de neige",
+		621: "averses de neige",
+		622: "fortes averses de neige",
+		701: "brume",
+		711: "fumée",
+		721: "brume sèche",
+		731: "tourbillons de sable ou de poussière",
+		741: "brouillard",
+		751: "sable",
+		761: "poussière",
+		762: "cendres volcaniques",
+		771: "grains",
+		781: "tornade",
+		800: "ciel dégagé",
+		801: "peu nuageux",
+		802: "partiellement nuageux",
+		803: "nuageux",
+		804: "couvert",
+	},
+	"es": {
+		200: "tormenta con lluvia ligera",
+		201: "tormenta con lluvia",
+		202: "tormenta con lluvia intensa",
+		210: "tormenta ligera",
+		211: "tormenta",
+		212: "tormenta fuerte",
+		221: "tormentas dispersas",
+		230: "tormenta con llovizna ligera",
+		231: "tormenta con llovizna",
+		232: "tormenta con llovizna intensa",
+		300: "llovizna ligera",
+		301: "llovizna",
+		302: "llovizna intensa",
+		310: "llovizna y lluvia ligeras",
+		311: "llovizna y lluvia",
+		312: "llovizna y lluvia intensas",
+		313: "chubascos y llovizna",
+		314: "chubascos intensos y llovizna",
+		321: "chubascos de llovizna",
+		500: "lluvia ligera",
+		501: "lluvia moderada",
+		502: "lluvia intensa",
+		503: "lluvia muy intensa",
+		504: "lluvia extrema",
+		511: "lluvia helada",
+		520: "chubascos ligeros",
+		521: "chubascos",
+		522: "chubascos intensos",
+		531: "chubascos dispersos",
+		600: "nevada ligera",
+		601: "nieve",
+		602: "nevada intensa",
+		611: "aguanieve",
+		612: "chubascos ligeros de aguanieve",
+		613: "chubascos de aguanieve",
+		615: "lluvia y nieve ligeras",
+		616: "lluvia y nieve",
+		620: "chubascos ligeros de nieve",
+		621: "chubascos de nieve",
+		622: "chubascos intensos de nieve",
+		701: "neblina",
+		711: "humo",
+		721: "calima",
+		731: "remolinos de arena o polvo",
+		741: "niebla",
+		751: "arena",
+		761: "polvo",
+		762: "ceniza volcánica",
+		771: "turbonadas",
+		781: "tornado",
+		800: "cielo despejado",
+		801: "algunas nubes",
+		802: "nubes dispersas",
+		803: "nuboso",
+		804: "cubierto",
+	},
+}
+
+// localizeConditions returns the description of the condition code id in
+// lang when description is the English one, and description otherwise.
+func localizeConditions(id int, description, lang string) string {
+	if lang == "sp" {
+		// OpenWeather takes both for Spanish.
+		lang = "es"
+	}
+	localized, ok := conditionDescriptions[lang][id]
+	if !ok || !strings.EqualFold(description, conditionDescriptions["en"][id]) {
+		return description
+	}
+	return localized
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type config struct {
+	values map[string]any
+
+	// keys holds the parts of the keys read from the file, see parseTOML.
+	keys map[string][]string
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/weather/config.toml, falling
+// back to ~/.config/weather/config.toml.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "weather", "config.toml")
+}
+
+// loadConfig reads the config file at path. A missing file is not an error
+// and results in an empty config.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{values: map[string]any{}}
+	if path == "" {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values, keys, err := parseTOML(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.values, cfg.keys = values, keys
+	return cfg, nil
+}
+
+// withProfile returns the config with the settings of the named profile,
+// stored under [profile.<name>], taking precedence over top-level ones.
+func (c *config) withProfile(name string) (*config, error) {
+	prefix := "profile." + name + "."
+	merged := &config{values: map[string]any{}}
+	found := false
+	for k, v := range c.values {
+		if !strings.HasPrefix(k, "profile.") {
+			merged.values[k] = v
+		}
+	}
+	for k, v := range c.values {
+		if strings.HasPrefix(k, prefix) {
+			merged.values[strings.TrimPrefix(k, prefix)] = v
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("profile %q not found in config", name)
+	}
+	return merged, nil
+}
+
+// keyParts returns the table and name parts of key. Keys not read from the
+// file are split by the tables they belong to, so that e.g. the location
+// "St. Louis" in "aliases.St. Louis" stays one name.
+func (c *config) keyParts(key string) []string {
+	if parts, ok := c.keys[key]; ok {
+		return parts
+	}
+	for _, table := range []string{"aliases", "discord"} {
+		if name, ok := strings.CutPrefix(key, table+"."); ok {
+			return []string{table, name}
+		}
+	}
+	if rest, ok := strings.CutPrefix(key, "profile."); ok {
+		if i := strings.LastIndex(rest, "."); i > 0 {
+			return []string{"profile", rest[:i], rest[i+1:]}
+		}
+	}
+	return []string{key}
+}
+
+func (c *config) string(key string) (string, bool) {
+	v, ok := c.values[key]
+	if !ok {
+		return "", false
+	}
+	if list, ok := v.([]string); ok {
+		return strings.Join(list, ","), true
+	}
+	return fmt.Sprint(v), true
+}
+
+func (c *config) list(key string) []string {
+	if list, ok := c.values[key].([]string); ok {
+		return list
+	}
+	if s, ok := c.values[key].(string); ok && s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// configFlags maps config file keys to the flags they provide defaults for.
+var configFlags = map[string]string{
+	"units":            "units",
+	"lang":             "lang",
+	"timeout":          "timeout",
+	"max_requests":     "max-requests",
+	"ipv4":             "4",
+	"ipv6":             "6",
+	"cache_dir":        "cache-dir",
+	"cache_ttl":        "cache-ttl",
+	"stale_fallback":   "stale-fallback",
+	"history":          "history",
+	"history_db":       "history-db",
+	"log_file":         "log-file",
+	"socket":           "socket",
+	"influx_url":       "influx-url",
+	"influx_token":     "influx-token",
+	"graphite":         "graphite",
+	"statsd":           "statsd",
+	"metrics_prefix":   "metrics-prefix",
+	"metrics_tags":     "metrics-tags",
+	"breaker_failures": "breaker-failures",
+	"breaker_cooldown": "breaker-cooldown",
+	"dns_ttl":          "dns-ttl",
+	"http2":            "http2",
+	"max_idle_conns":   "max-idle-conns",
+	"idle_timeout":     "idle-timeout",
+	"webhooks":         "webhook",
+	"webhook_secret":   "webhook-secret",
+	"notify":           "notify",
+	"slack_webhook":    "slack",
+	"discord_webhook":  "discord",
+	"telegram_token":   "telegram-token",
+	"telegram_chats":   "telegram-chats",
+	"summary_time":     "summary-time",
+	"commute_home":     "home",
+	"commute_work":     "work",
+	"commute_morning":  "morning",
+	"commute_evening":  "evening",
+	"activity":         "activity",
+	"score_weights":    "weights",
+	"frost_threshold":  "threshold",
+	"advice":           "advice",
+	"cold_tolerance":   "cold-tolerance",
+	"uv":               "uv",
+	"vs_normal":        "vs-normal",
+	"open_site":        "open-site",
+	"speak":            "speak",
+	"a11y":             "a11y",
+	"no_pager":         "no-pager",
+	"layout":           "layout",
+	"precision":        "precision",
+	"locale":           "locale",
+	"sport":            "sport",
+	"sport_wind":       "wind",
+	"gust_factor":      "gust-factor",
+	"sport_directions": "directions",
+	"smtp_host":        "smtp-host",
+	"smtp_port":        "smtp-port",
+	"smtp_user":        "smtp-user",
+	"smtp_password":    "smtp-password",
+	"smtp_from":        "smtp-from",
+	"smtp_to":          "smtp-to",
+	"twilio_account":   "twilio-account",
+	"twilio_token":     "twilio-token",
+	"twilio_from":      "twilio-from",
+	"sms_to":           "sms-to",
+	"provider":         "provider",
+	"format":           "o",
+	"key_rotation":     "key-rotation",
+	"verbose":          "v",
+}
+
+// applyConfig sets every flag not given on the command line from the
+// config. Values go through flag.Set so they are validated like flags.
+func applyConfig(cfg *config, fs *flag.FlagSet) error {
+	explicit := setFlags(fs)
+
+	for key, name := range configFlags {
+		value, ok := cfg.string(key)
+		if !ok || explicit[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			// The output formats differ by command: a default format
+			// only applies to the commands that have it.
+			if key == "format" {
+				continue
+			}
+			return fmt.Errorf("config %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// configAPIKey resolves the API key from the config. api_key_ref is either
+// "keyring" or "env:NAME"; api_key holds the key itself.
+func configAPIKey(cfg *config) (string, error) {
+	if ref, ok := cfg.string("api_key_ref"); ok {
+		switch {
+		case ref == "keyring":
+			return keyringGet()
+		case strings.HasPrefix(ref, "env:"):
+			return os.Getenv(strings.TrimPrefix(ref, "env:")), nil
+		default:
+			return "", fmt.Errorf("config api_key_ref: must be 'keyring' or 'env:NAME'")
+		}
+	}
+	key, _ := cfg.string("api_key")
+	return key, nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configKeys lists the settings accepted by the config file together with
+// a parser validating values given to `weather config set`.
+var configKeys = map[string]func(args []string) (any, error){
+	"api_key":          stringSetting,
+	"api_key_ref":      parseAPIKeyRef,
+	"units":            enumSetting("units", unitsValues),
+	"lang":             enumSetting("lang", langValues),
+	"timeout":          durationSetting,
+	"max_requests":     intSetting,
+	"ipv4":             boolSetting,
+	"ipv6":             boolSetting,
+	"cache_dir":        stringSetting,
+	"cache_ttl":        durationSetting,
+	"stale_fallback":   boolSetting,
+	"history":          boolSetting,
+	"history_db":       stringSetting,
+	"log_file":         stringSetting,
+	"socket":           stringSetting,
+	"influx_url":       stringSetting,
+	"influx_token":     stringSetting,
+	"graphite":         stringSetting,
+	"statsd":           stringSetting,
+	"metrics_prefix":   stringSetting,
+	"metrics_tags":     stringSetting,
+	"breaker_failures": intSetting,
+	"breaker_cooldown": durationSetting,
+	"dns_ttl":          durationSetting,
+	"http2":            boolSetting,
+	"max_idle_conns":   intSetting,
+	"idle_timeout":     durationSetting,
+	"webhooks":         listSetting,
+	"webhook_secret":   stringSetting,
+	"provider":         enumSetting("provider", providerValues),
+	"format":           enumSetting("format", formatValues),
+	"key_rotation":     enumSetting("key_rotation", keyRotationValues),
+	"verbose":          boolSetting,
+	"notify":           boolSetting,
+	"slack_webhook":    stringSetting,
+	"discord_webhook":  stringSetting,
+	"telegram_token":   stringSetting,
+	"telegram_chats":   listSetting,
+	"summary_time":     stringSetting,
+	"commute_home":     stringSetting,
+	"commute_work":     stringSetting,
+	"commute_morning":  stringSetting,
+	"commute_evening":  stringSetting,
+	"activity":         enumSetting("activity", activityValues),
+	"score_weights":    stringSetting,
+	"frost_threshold":  stringSetting,
+	"advice":           boolSetting,
+	"cold_tolerance":   enumSetting("cold_tolerance", coldToleranceValues),
+	"uv":               boolSetting,
+	"vs_normal":        boolSetting,
+	"open_site":        enumSetting("open_site", openSiteValues),
+	"speak":            boolSetting,
+	"a11y":             a11ySetting,
+	"no_pager":         boolSetting,
+	"layout":           enumSetting("layout", layoutValues),
+	"precision":        precisionSetting,
+	"locale":           stringSetting,
+	"sport":            enumSetting("sport", sportValues),
+	"sport_wind":       stringSetting,
+	"gust_factor":      floatSetting,
+	"sport_directions": stringSetting,
+	"smtp_host":        stringSetting,
+	"smtp_port":        intSetting,
+	"smtp_user":        stringSetting,
+	"smtp_password":    stringSetting,
+	"smtp_from":        stringSetting,
+	"smtp_to":          listSetting,
+	"twilio_account":   stringSetting,
+	"twilio_token":     stringSetting,
+	"twilio_from":      stringSetting,
+	"sms_to":           listSetting,
+	"city":             stringSetting,
+	"favorites":        listSetting,
+	"rules":            listSetting,
+	"schedule":         scheduleSetting,
+}
+
+// configSetting returns the parser of the values of key, which is a
+// setting, a setting of a profile, e.g. "profile.work.units", or an entry
+// of the [aliases] or [discord] table, e.g. "aliases.home".
+func configSetting(cfg *config, key string) (func(args []string) (any, error), bool) {
+	parts := cfg.keyParts(key)
+	switch {
+	case len(parts) == 2 && (parts[0] == "aliases" || parts[0] == "discord"):
+		return stringSetting, true
+	case len(parts) == 3 && parts[0] == "profile":
+		parse, ok := configKeys[parts[2]]
+		return parse, ok
+	case len(parts) == 1:
+		parse, ok := configKeys[key]
+		return parse, ok
+	}
+	return nil, false
+}
+
+func stringSetting(args []string) (any, error) {
+	return strings.Join(args, " "), nil
+}
+
+func listSetting(args []string) (any, error) {
+	if len(args) == 1 {
+		return parseKeys(args[0]), nil
+	}
+	return args, nil
+}
+
+func boolSetting(args []string) (any, error) {
+	return strconv.ParseBool(strings.Join(args, " "))
+}
+
+func intSetting(args []string) (any, error) {
+	return strconv.ParseInt(strings.Join(args, " "), 10, 64)
+}
+
+func floatSetting(args []string) (any, error) {
+	return strconv.ParseFloat(strings.Join(args, " "), 64)
+}
+
+func durationSetting(args []string) (any, error) {
+	value := strings.Join(args, " ")
+	_, err := time.ParseDuration(value)
+	return value, err
+}
+
+func enumSetting(what string, allowed []string) func([]string) (any, error) {
+	return func(args []string) (any, error) {
+		value := strings.Join(args, " ")
+		return value, checkEnum(what, value, allowed)
+	}
+}
+
+func parseAPIKeyRef(args []string) (any, error) {
+	ref := strings.Join(args, " ")
+	if ref != "keyring" && !(strings.HasPrefix(ref, "env:") && len(ref) > len("env:")) {
+		return nil, fmt.Errorf("api_key_ref must be 'keyring' or 'env:NAME'")
+	}
+	return ref, nil
+}
+
+func runConfig(s *session) {
+	path, args := s.opt.configPath, s.args()
+	if len(args) == 0 {
+		exitWithUsageError("usage: weather config get|set|unset|list|edit|path")
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil && args[0] != "edit" {
+		exitWithError(err.Error())
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "path":
+		fmt.Println(path)
+
+	case "list":
+		for _, key := range sortedConfigKeys(cfg) {
+			fmt.Printf("%s = %s\n", key, formatTOMLValue(cfg.values[key]))
+		}
+
+	case "get":
+		if len(args) != 1 {
+			exitWithUsageError("usage: weather config get <key>")
+		}
+		value, ok := cfg.string(args[0])
+		if !ok {
+			exitWithError(fmt.Sprintf("%s is not set", args[0]))
+		}
+		fmt.Println(value)
+
+	case "set":
+		if len(args) < 2 {
+			exitWithUsageError("usage: weather config set <key> <value>")
+		}
+		parse, ok := configSetting(cfg, args[0])
+		if !ok {
+			exitWithUsageError(fmt.Sprintf("unknown config key %q", args[0]))
+		}
+		value, err := parse(args[1:])
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		cfg.values[args[0]] = value
+		if err := writeConfig(path, cfg); err != nil {
+			exitWithError(err.Error())
+		}
+
+	case "unset":
+		if len(args) != 1 {
+			exitWithUsageError("usage: weather config unset <key>")
+		}
+		delete(cfg.values, args[0])
+		if err := writeConfig(path, cfg); err != nil {
+			exitWithError(err.Error())
+		}
+
+	case "edit":
+		if err := editConfig(path); err != nil {
+			exitWithError(err.Error())
+		}
+
+	default:
+		exitWithUsageError(fmt.Sprintf("unknown config command %q", cmd))
+	}
+}
+
+// editConfig opens the config file in $VISUAL or $EDITOR and validates the
+// result once the editor exits.
+func editConfig(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// The editor may be given with arguments, e.g. "code --wait".
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	_, err := loadConfig(path)
+	return err
+}
+
+// writeConfig rewrites the config file from cfg. Comments in the original
+// file are not preserved.
+func writeConfig(path string, cfg *config) error {
+	var buf bytes.Buffer
+	table := ""
+	for _, key := range sortedConfigKeys(cfg) {
+		parts := cfg.keyParts(key)
+		prefix, name := formatTOMLKey(parts[:len(parts)-1]), parts[len(parts)-1]
+		if prefix != table {
+			fmt.Fprintf(&buf, "\n[%s]\n", prefix)
+			table = prefix
+		}
+		fmt.Fprintf(&buf, "%s = %s\n", formatTOMLKey([]string{name}), formatTOMLValue(cfg.values[key]))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// The file may contain an API key.
+	return os.WriteFile(path, bytes.TrimLeft(buf.Bytes(), "\n"), 0o600)
+}
+
+// sortedConfigKeys orders top-level keys before keys inside tables, and
+// the keys of a table together.
+func sortedConfigKeys(cfg *config) []string {
+	keys := make([]string, 0, len(cfg.values))
+	for k := range cfg.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, pj := cfg.keyParts(keys[i]), cfg.keyParts(keys[j])
+		if len(pi) == 1 || len(pj) == 1 {
+			if len(pi) != len(pj) {
+				return len(pi) == 1
+			}
+			return keys[i] < keys[j]
+		}
+		if ti, tj := strings.Join(pi[:len(pi)-1], "\x00"), strings.Join(pj[:len(pj)-1], "\x00"); ti != tj {
+			return ti < tj
+		}
+		return pi[len(pi)-1] < pj[len(pj)-1]
+	})
+	return keys
+}
+
+// formatTOMLKey joins the parts of a dotted key, quoting the ones that are
+// not bare keys.
+func formatTOMLKey(key []string) string {
+	parts := slices.Clone(key)
+	for i, part := range parts {
+		for _, c := range part {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+				parts[i] = strconv.Quote(part)
+				break
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func formatTOMLValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
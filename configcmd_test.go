@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		set   map[string]any
+		want  string
+	}{
+		{
+			name:  "quoted keys with dots",
+			input: "[discord]\n\"St. Louis\" = \"https://discord.com/api/webhooks/1\"\n",
+			set:   map[string]any{"units": "imperial"},
+			want:  "units = \"imperial\"\n\n[discord]\n\"St. Louis\" = \"https://discord.com/api/webhooks/1\"\n",
+		},
+		{
+			name:  "new table entries",
+			input: "city = \"Oulu\"\n",
+			set:   map[string]any{"aliases.St. Louis": "St. Louis,US", "discord.New York": "https://discord.com/api/webhooks/2", "aliases.home": "Oulu"},
+			want:  "city = \"Oulu\"\n\n[aliases]\n\"St. Louis\" = \"St. Louis,US\"\nhome = \"Oulu\"\n\n[discord]\n\"New York\" = \"https://discord.com/api/webhooks/2\"\n",
+		},
+		{
+			name:  "profiles",
+			input: "[profile.work]\nunits = \"metric\"\n",
+			set:   map[string]any{"profile.v1.2.units": "imperial", "profile.work.lang": "fi"},
+			want:  "[profile.\"v1.2\"]\nunits = \"imperial\"\n\n[profile.work]\nlang = \"fi\"\nunits = \"metric\"\n",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(tt.input), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		for key, value := range tt.set {
+			cfg.values[key] = value
+		}
+		if err := writeConfig(path, cfg); err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("%s: wrote\n%s\nwant\n%s", tt.name, b, tt.want)
+		}
+		reread, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: rereading: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(reread.values, cfg.values) {
+			t.Errorf("%s: reread %v, want %v", tt.name, reread.values, cfg.values)
+		}
+	}
+}
+
+func TestConfigSetting(t *testing.T) {
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"units", true},
+		{"profile.work.units", true},
+		{"profile.v1.2.units", true},
+		{"aliases.home", true},
+		{"aliases.St. Louis", true},
+		{"discord.New York", true},
+		{"profile.work.nonsense", false},
+		{"nonsense", false},
+		{"weather.units", false},
+	}
+	cfg := &config{values: map[string]any{}}
+	for _, tt := range tests {
+		if _, ok := configSetting(cfg, tt.key); ok != tt.ok {
+			t.Errorf("configSetting(%q) found %t, want %t", tt.key, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The daemon answers queries over a Unix socket. A query is a line with a
+// location; the answer is a line with the current weather as JSON, or an
+// object with an "error" field.
+
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "weather.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("weather-%d.sock", os.Getuid()))
+}
+
+func socketFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.socketPath, "socket", defaultSocketPath(), "path of the daemon socket")
+}
+
+type daemonResponse struct {
+	*Weather
+	Units string `json:"units,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// daemon keeps the latest weather of each location in memory. Requests for
+// locations it does not poll are fetched on demand and polled from then on.
+type daemon struct {
+	s *session
+
+	// refreshMu serializes what follows a fetch, the history, the
+	// notifications and their state, and the daily jobs. The fetches of
+	// a poll themselves run up to -max-requests at a time.
+	refreshMu sync.Mutex
+
+	mu      sync.RWMutex
+	weather map[string]*Weather
+
+	// Polling intervals of the locations with a fetch job, and when each
+	// location is polled next.
+	intervals map[string]time.Duration
+	due       map[string]time.Time
+
+	// pollStarted is when the running round of poll started, or zero
+	// while it waits for the next one.
+	pollStarted time.Time
+
+	// Alerts already reported, keyed by location and then by alert. noAlerts is set when the API key has no access to alerts.
+	alertsSent map[string]map[string]bool
+	noAlerts   bool
+
+	// Alert rules and whether each was triggered at the last refresh,
+	// keyed by location and then by rule.
+	rules          []*rule
+	rulesTriggered map[string]map[string]bool
+}
+
+func daemonKey(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
+
+func runDaemon(s *session) {
+	if s.opt.interval < minWatchInterval {
+		exitWithUsageError(fmt.Sprintf("interval must be at least %s", minWatchInterval))
+	}
+	jobs := s.scheduledJobs()
+
+	// Without any configured locations the daemon only polls the ones it
+	// is asked about.
+	d := &daemon{
+		s:              s,
+		weather:        map[string]*Weather{},
+		intervals:      map[string]time.Duration{},
+		due:            map[string]time.Time{},
+		alertsSent:     map[string]map[string]bool{},
+		rules:          s.alertRules(),
+		rulesTriggered: map[string]map[string]bool{},
+	}
+	if len(s.args()) > 0 || len(s.cfg.list("city")) > 0 || len(s.cfg.list("favorites")) > 0 {
+		for _, city := range s.cities() {
+			d.weather[daemonKey(city)] = nil
+		}
+	}
+	shortest := s.opt.interval
+	for _, j := range jobs {
+		if j.kind == "fetch" {
+			d.weather[daemonKey(j.city)] = nil
+			d.intervals[daemonKey(j.city)] = j.every
+			shortest = min(shortest, j.every)
+		}
+	}
+
+	// Every poll fetches fresh data, see watch.
+	s.opt.cacheTTL = min(s.opt.cacheTTL, shortest/2)
+	// Polls between updates of the provider's data get the same responses,
+	// which need not be decoded again.
+	s.provider().reuse = true
+
+	// Under systemd socket activation the sockets come from the socket
+	// unit: the one named "metrics" serves metrics and the other one
+	// queries.
+	activated, err := sdListeners()
+	if err != nil {
+		exitWithError("daemon: " + err.Error())
+	}
+	var l, metricsListener net.Listener
+	for _, al := range activated {
+		switch {
+		case al.name == "metrics" && metricsListener != nil:
+			exitWithError("daemon: socket activation passed more than one metrics socket")
+		case al.name == "metrics":
+			metricsListener = al
+		case l != nil:
+			exitWithError("daemon: socket activation passed more than one query socket")
+		default:
+			l = al
+			s.opt.socketPath = l.Addr().String()
+		}
+	}
+	if l == nil {
+		if l, err = listenSocket(s.opt.socketPath); err != nil {
+			exitWithError("daemon: " + err.Error())
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		sdNotify("STOPPING=1")
+		l.Close()
+	}()
+
+	go d.poll(ctx)
+	for _, j := range jobs {
+		if j.kind != "fetch" {
+			go d.runDaily(ctx, j)
+		}
+	}
+
+	if s.opt.metricsListen != "" || metricsListener != nil {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", metricsHandler(d.snapshot, s.opt.units))
+		srv := &http.Server{Addr: s.opt.metricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			serve := srv.ListenAndServe
+			if metricsListener != nil {
+				serve = func() error { return srv.Serve(metricsListener) }
+			}
+			if err := serve(); err != nil {
+				exitWithError("daemon: " + err.Error())
+			}
+		}()
+	}
+
+	if err := sdNotify("READY=1\nSTATUS=answering queries on " + s.opt.socketPath); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: sd_notify: %s\n", err)
+	}
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go d.watchdog(ctx, interval)
+	}
+
+	fmt.Fprintf(os.Stderr, "weather daemon listening on %s\n", s.opt.socketPath)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "WARNING: daemon: %s\n", err)
+			continue
+		}
+		go d.serve(conn)
+	}
+}
+
+// watchdog pings the systemd watchdog twice per timeout for as long as the
+// daemon polls, so that systemd restarts a daemon that has hung. A round of
+// polling that has not finished within the timeout, e.g. stuck in a
+// webhook of notify, counts as hung.
+func (d *daemon) watchdog(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.mu.RLock()
+		started := d.pollStarted
+		d.mu.RUnlock()
+		if !started.IsZero() && time.Since(started) > timeout {
+			fmt.Fprintf(os.Stderr, "WARNING: daemon: polling has not finished since %s, not pinging the watchdog\n", started.Format(time.TimeOnly))
+			continue
+		}
+		if err := sdNotify("WATCHDOG=1"); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: sd_notify: %s\n", err)
+		}
+	}
+}
+
+// listenSocket listens on path, replacing a socket left behind by a daemon
+// that is no longer running.
+func listenSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the listener also removes the socket file. Plan 9 has no
+	// Unix sockets to unlink.
+	if u, ok := l.(interface{ SetUnlinkOnClose(bool) }); ok {
+		u.SetUnlinkOnClose(true)
+	}
+	return l, os.Chmod(path, 0o600)
+}
+
+// poll refreshes every polled location each -interval, or at the interval
+// of its fetch job, the locations due at once in parallel.
+func (d *daemon) poll(ctx context.Context) {
+	for {
+		now := time.Now()
+		var cities []string
+		d.mu.Lock()
+		d.pollStarted = now
+		for city := range d.weather {
+			if !d.due[city].After(now) {
+				cities = append(cities, city)
+				d.due[city] = now.Add(d.interval(city))
+			}
+		}
+		d.mu.Unlock()
+
+		d.s.fetchAll(ctx, len(cities), d.s.opt.maxRequests, func(i int) {
+			if _, err := d.refresh(cities[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", cities[i], redact(err.Error()))
+			}
+		})
+
+		// Locations asked about in the meantime are picked up on the next
+		// wakeup, so it is never far off.
+		wait := minWatchInterval
+		d.mu.Lock()
+		d.pollStarted = time.Time{}
+		for _, due := range d.due {
+			wait = min(wait, time.Until(due))
+		}
+		d.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// interval returns how often a location is polled. Callers hold mu.
+func (d *daemon) interval(city string) time.Duration {
+	if every, ok := d.intervals[city]; ok {
+		return every
+	}
+	return d.s.opt.interval
+}
+
+func (d *daemon) refresh(city string) (*Weather, error) {
+	w, err := d.s.provider().current(d.s.query(city))
+	if err != nil {
+		return nil, err
+	}
+
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+	d.s.recordHistory(w, d.s.opt.units)
+
+	d.mu.Lock()
+	prev := d.weather[daemonKey(city)]
+	d.weather[daemonKey(city)] = w
+	d.due[daemonKey(city)] = time.Now().Add(d.interval(daemonKey(city)))
+	d.mu.Unlock()
+
+	d.notify(city, prev, w)
+	return w, nil
+}
+
+// notify posts webhook events for a refreshed location: when the condition
+// category changes, when an alert rule is triggered and when a new alert is
+// issued. The last two are also reported with -notify and -slack.
+// Callers hold refreshMu.
+func (d *daemon) notify(city string, prev, w *Weather) {
+	urls := parseKeys(d.s.opt.webhooks)
+	if len(urls) == 0 && !d.s.notifying() {
+		return
+	}
+	secret, units := d.s.opt.webhookSecret, d.s.opt.units
+
+	if prev != nil && conditionCategory(prev.Icon) != conditionCategory(w.Icon) {
+		sendWebhooks(urls, secret, &webhookEvent{Type: "condition_changed", Location: w.CityName, Time: w.Time, Units: units, Weather: w, Previous: prev})
+	}
+	if len(d.rules) > 0 {
+		d.checkRules(city, w, urls)
+	}
+
+	if d.noAlerts {
+		return
+	}
+	alerts, err := d.s.provider().alerts(d.s.query(city))
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		d.noAlerts = true
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s: alerts: %s\n", city, redact(err.Error()))
+		return
+	}
+
+	key := daemonKey(city)
+	if d.alertsSent[key] == nil {
+		d.alertsSent[key] = map[string]bool{}
+	}
+	for i := range alerts {
+		a := &alerts[i]
+		id := fmt.Sprintf("%s|%d", a.Event, a.Start.Unix())
+		if d.alertsSent[key][id] {
+			continue
+		}
+		d.alertsSent[key][id] = true
+		sendWebhooks(urls, secret, &webhookEvent{Type: "alert", Location: w.CityName, Time: a.Start, Units: units, Weather: w, Alert: a})
+		d.s.notifyAlert(w.CityName, a)
+	}
+}
+
+// checkRules posts a threshold event for each rule that is triggered now
+// but was not at the previous refresh of city.
+func (d *daemon) checkRules(city string, w *Weather, urls []string) {
+	var a *AirQuality
+	if needsAir(d.rules) {
+		var err error
+		if a, err = d.s.provider().air(d.s.query(city)); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s: air quality: %s\n", city, redact(err.Error()))
+			return
+		}
+	}
+
+	key := daemonKey(city)
+	if d.rulesTriggered[key] == nil {
+		d.rulesTriggered[key] = map[string]bool{}
+	}
+	for _, r := range evaluateRules(d.rules, w, a, d.s.opt.units) {
+		if r.Triggered && !d.rulesTriggered[key][r.Rule] {
+			sendWebhooks(urls, d.s.opt.webhookSecret, &webhookEvent{Type: "threshold", Location: w.CityName, Time: w.Time, Units: d.s.opt.units, Weather: w, Rule: r.Rule})
+			d.s.notifyRule(w, r)
+		}
+		d.rulesTriggered[key][r.Rule] = r.Triggered
+	}
+}
+
+// snapshot returns the latest weather of the polled locations.
+func (d *daemon) snapshot() []*Weather {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var observations []*Weather
+	seen := map[string]bool{}
+	for _, w := range d.weather {
+		if w != nil && !seen[w.CityName] {
+			seen[w.CityName] = true
+			observations = append(observations, w)
+		}
+	}
+	sort.Slice(observations, func(i, j int) bool { return observations[i].CityName < observations[j].CityName })
+	return observations
+}
+
+func (d *daemon) lookup(city string) (*Weather, error) {
+	d.mu.RLock()
+	w := d.weather[daemonKey(city)]
+	d.mu.RUnlock()
+	if w != nil {
+		return w, nil
+	}
+	return d.refresh(city)
+}
+
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	sc := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for sc.Scan() {
+		city := strings.TrimSpace(sc.Text())
+		if alias, ok := d.s.cfg.string("aliases." + city); ok {
+			city = alias
+		}
+
+		var res daemonResponse
+		if w, err := d.lookup(city); err != nil {
+			res.Error = redact(err.Error())
+		} else {
+			res.Weather, res.Units = w, d.s.opt.units
+		}
+		if enc.Encode(res) != nil {
+			return
+		}
+	}
+}
+
+// queryDaemon asks the daemon listening on socketPath for the current
+// weather of city. The weather is in the units of the daemon, which are
+// returned as well.
+func queryDaemon(socketPath, city string) (*Weather, string, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, "", fmt.Errorf("daemon is not running: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, city); err != nil {
+		return nil, "", err
+	}
+	var res daemonResponse
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, "", err
+	}
+	if res.Error != "" {
+		return nil, "", errors.New(res.Error)
+	}
+	return res.Weather, res.Units, nil
+}
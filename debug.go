@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// httpClient is used for all upstream requests. main replaces it with a
+// tracing client when -debug-http is given.
+var httpClient = &http.Client{}
+
+// debugTransport logs every request and response passing through it.
+type debugTransport struct {
+	next http.RoundTripper
+	out  io.Writer
+	dump bool
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(t.out, "http: --> %s %s\n", req.Method, redactURL(req.URL))
+	if t.dump {
+		if b, err := httputil.DumpRequestOut(req, true); err == nil {
+			fmt.Fprintf(t.out, "%s\n", redact(string(b)))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(t.out, "http: <-- error after %s: %s\n", elapsed, redact(err.Error()))
+		return nil, err
+	}
+
+	fmt.Fprintf(t.out, "http: <-- %s (%s)\n", resp.Status, elapsed)
+	if t.dump {
+		if b, err := httputil.DumpResponse(resp, true); err == nil {
+			fmt.Fprintf(t.out, "%s\n", redact(string(b)))
+		}
+	}
+
+	return resp, nil
+}
+
+func newDebugClient(out io.Writer, dump bool) *http.Client {
+	return &http.Client{Transport: &debugTransport{next: http.DefaultTransport, out: out, dump: dump}}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// weather degree-days computes heating and cooling degree days, the usual
+// measure of the energy needed to heat or cool a building: for each day,
+// how far the mean temperature is below (HDD) or above (CDD) a base. The
+// mean is that of the daily low and high, taken from the history for past
+// days and from the forecast for the coming ones.
+
+// defaultDegreeDaysSince is how far back degree days are computed by default.
+const defaultDegreeDaysSince = 30 * 24 * time.Hour
+
+func degreeDaysFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("base", "base temperature in -units (default 17°C or 65°F)", func(value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("expected a temperature, e.g. 17")
+		}
+		opt.degreeBase = &v
+		return nil
+	})
+	fs.Func("since", "first day, e.g. 30d or 2024-01-02 (default 30d)", sinceFlag(&opt.since))
+}
+
+// degreeDay is a day of degree days in the units of the query. Source is
+// "history", "forecast" or "history+forecast" for today.
+type degreeDay struct {
+	Date    string  `json:"date"`
+	Low     float64 `json:"low"`
+	High    float64 `json:"high"`
+	Mean    float64 `json:"mean"`
+	Heating float64 `json:"hdd"`
+	Cooling float64 `json:"cdd"`
+	Source  string  `json:"source"`
+
+	entries int // forecast entries of the day
+}
+
+// degreeDays is the degree days of a location.
+type degreeDays struct {
+	City    string      `json:"city"`
+	Base    float64     `json:"base"`
+	Units   string      `json:"units"`
+	Days    []degreeDay `json:"days"`
+	Heating float64     `json:"hdd"`
+	Cooling float64     `json:"cdd"`
+}
+
+// runDegreeDays computes the degree days of each location from -since
+// through the forecast.
+func runDegreeDays(s *session) {
+	base := 17.0
+	if s.opt.units == "imperial" {
+		base = 65
+	}
+	if s.opt.degreeBase != nil {
+		base = *s.opt.degreeBase
+	}
+	since := s.opt.since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultDegreeDaysSince)
+	}
+
+	for _, city := range s.cities() {
+		f, err := s.provider().forecast(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		observations, err := s.historyDB().observations(f.CityName, since, time.Time{})
+		if err != nil {
+			exitWithError("history: " + err.Error())
+		}
+		displayDegreeDays(os.Stdout, computeDegreeDays(f, observations, base, time.Now(), s.opt.units), s.opt)
+	}
+}
+
+// computeDegreeDays combines the daily lows and highs of the observations,
+// which are metric, with those of the forecast entries after now. Forecast
+// days other than today are left out unless the forecast covers all of
+// them, so that a partial last day does not skew the total.
+func computeDegreeDays(f *Forecast, observations []*observation, base float64, now time.Time, units string) *degreeDays {
+	days := map[string]*degreeDay{}
+	add := func(t time.Time, temperature float64, source string) *degreeDay {
+		date := localTime(t, f.TimeZone).Format("2006-01-02")
+		d, ok := days[date]
+		if !ok {
+			d = &degreeDay{Date: date, Low: temperature, High: temperature, Source: source}
+			days[date] = d
+		}
+		d.Low, d.High = min(d.Low, temperature), max(d.High, temperature)
+		if d.Source != source {
+			d.Source = "history+forecast"
+		}
+		return d
+	}
+	for _, o := range observations {
+		add(o.Time, fromMetric(&o.Weather, units).Temperature, "history")
+	}
+	today := localTime(now, f.TimeZone).Format("2006-01-02")
+	for _, e := range f.Entries {
+		if e.Time.Add(forecastPeriod).After(now) {
+			add(e.Time, e.Temperature, "forecast").entries++
+		}
+	}
+
+	dd := &degreeDays{City: f.CityName, Base: base, Units: units}
+	for _, d := range days {
+		if d.Source == "forecast" && d.Date != today && d.entries < int(24*time.Hour/forecastPeriod) {
+			continue
+		}
+		d.Mean = (d.Low + d.High) / 2
+		d.Heating, d.Cooling = max(0, base-d.Mean), max(0, d.Mean-base)
+		dd.Heating += d.Heating
+		dd.Cooling += d.Cooling
+		dd.Days = append(dd.Days, *d)
+	}
+	sort.Slice(dd.Days, func(i, j int) bool { return dd.Days[i].Date < dd.Days[j].Date })
+	return dd
+}
+
+func displayDegreeDays(w io.Writer, dd *degreeDays, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, dd, opt.format)
+		return
+	}
+
+	temperatureSymbol, _ := unitSymbols(opt.units)
+	localFprintf(w, "%s, base %s°%s\n\n", dd.City, formatFloat(dd.Base), temperatureSymbol)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "DATE\tLOW\tHIGH\tMEAN\tHDD\tCDD\tSOURCE")
+	for _, d := range dd.Days {
+		localFprintf(tw, "%s\t%.1f°\t%.1f°\t%.1f°\t%.1f\t%.1f\t%s\n", d.Date, d.Low, d.High, d.Mean, d.Heating, d.Cooling, d.Source)
+	}
+	localFprintf(tw, "total\t\t\t\t%.1f\t%.1f\n", dd.Heating, dd.Cooling)
+	tw.Flush()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// -4 and -6 make the connections to the provider and the other services
+// use only IPv4 or IPv6, for networks where one of them is broken and
+// connections over it hang until the timeout.
+
+// tcpNetwork is the network of outgoing TCP connections: tcp, or tcp4 or
+// tcp6 with -4 or -6.
+var tcpNetwork = "tcp"
+
+// useAddressFamily makes the default transport dial network, tcp4 or tcp6.
+func useAddressFamily(network string) {
+	tcpNetwork = network
+	updateTransport(func(t *http.Transport) {
+		dial := t.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		t.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		}
+	})
+}
+
+// inAddressFamily reports whether the IP address a can be dialed on
+// network.
+func inAddressFamily(network, a string) bool {
+	ip := net.ParseIP(a)
+	switch {
+	case ip == nil:
+		return true
+	case strings.HasSuffix(network, "4"):
+		return ip.To4() != nil
+	case strings.HasSuffix(network, "6"):
+		return ip.To4() == nil
+	}
+	return true
+}
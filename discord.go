@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Messages are posted to Discord webhooks as embeds:
+// https://discord.com/developers/docs/resources/webhook#execute-webhook
+// https://discord.com/developers/docs/resources/message#embed-object
+
+func discordFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.discordWebhook, "discord", "", "Discord webhook URL to post summaries, triggered rules and weather warnings to")
+}
+
+type discordMessage struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Thumbnail   *discordImage  `json:"thumbnail,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordDescriptionLength is the maximum length of an embed description.
+const discordDescriptionLength = 4096
+
+// Embed colors by condition category, and for warnings.
+var (
+	discordColors = map[string]int{
+		"clear":        0xf1c40f,
+		"clouds":       0x95a5a6,
+		"rain":         0x3498db,
+		"thunderstorm": 0x9b59b6,
+		"snow":         0xecf0f1,
+		"mist":         0xbdc3c7,
+	}
+	discordWarningColor = 0xe67e22
+)
+
+// discordWeatherEmbed is the weather card: the conditions with the
+// condition icon and the key metrics.
+func discordWeatherEmbed(title string, w *Weather, today *forecastDay, opt *options) discordEmbed {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	description := fmt.Sprintf("**%.0f°%s** %s", w.Temperature, temperatureSymbol, w.Conditions)
+	if today != nil {
+		description += fmt.Sprintf("\n%s: %s", localDate(today.date, "Mon 2 Jan"), today.summary())
+	}
+	e := discordEmbed{
+		Title:       title,
+		Description: description,
+		Color:       discordColors[conditionCategory(w.Icon)],
+		Fields: []discordField{
+			{"Wind", fmt.Sprintf("%.1f %s from %.0f°", w.WindSpeed, windSpeedSymbol, w.WindDegrees), true},
+			{"Humidity", fmt.Sprintf("%.0f%%", w.Humidity), true},
+			{"Pressure", fmt.Sprintf("%.0f hPa", w.Pressure), true},
+			{"Visibility", fmt.Sprintf("%.1f km", w.Visibility/1000), true},
+		},
+		Footer:    &discordFooter{"OpenWeather"},
+		Timestamp: w.Time.Format(time.RFC3339),
+	}
+	if w.Icon != "" {
+		e.Thumbnail = &discordImage{fmt.Sprintf(ICON_URL, w.Icon)}
+	}
+	return e
+}
+
+func discordSummary(sum *dailySummary, opt *options) *discordMessage {
+	w := sum.weather
+	return &discordMessage{Username: "weather", Embeds: []discordEmbed{
+		discordWeatherEmbed(weatherIconIdToEmoji(w.Icon)+" "+w.CityName, w, sum.today, opt),
+	}}
+}
+
+func discordRule(w *Weather, r ruleResult, opt *options) *discordMessage {
+	e := discordWeatherEmbed(fmt.Sprintf("⚠️ %s: %s", w.CityName, r.Rule), w, nil, opt)
+	e.Color = discordWarningColor
+	return &discordMessage{Username: "weather", Embeds: []discordEmbed{e}}
+}
+
+func discordAlert(location string, a *Alert) *discordMessage {
+	return &discordMessage{Username: "weather", Embeds: []discordEmbed{{
+		Title:       fmt.Sprintf("⚠️ %s: %s", location, a.Event),
+		Description: truncate(a.Description, discordDescriptionLength),
+		Color:       discordWarningColor,
+		Fields: []discordField{
+			{"From", a.Start.Local().Format("Mon 2 Jan 15:04"), true},
+			{"Until", a.End.Local().Format("Mon 2 Jan 15:04"), true},
+		},
+		Footer:    &discordFooter{a.Sender},
+		Timestamp: a.Start.Format(time.RFC3339),
+	}}}
+}
+
+// discordWebhook returns the webhook URL of location: the entry of the
+// [discord] table whose key matches the location name, case insensitively,
+// or else -discord.
+func (s *session) discordWebhook(location string) string {
+	for key, value := range s.cfg.values {
+		if name, ok := strings.CutPrefix(key, "discord."); ok && strings.EqualFold(name, location) {
+			return fmt.Sprint(value)
+		}
+	}
+	return s.opt.discordWebhook
+}
+
+// hasDiscord reports whether any Discord webhook is configured.
+func (s *session) hasDiscord() bool {
+	for key := range s.cfg.values {
+		if strings.HasPrefix(key, "discord.") {
+			return true
+		}
+	}
+	return s.opt.discordWebhook != ""
+}
+
+// postDiscord posts m to the Discord webhook of location, if it has one.
+func (s *session) postDiscord(location string, m *discordMessage) {
+	u := s.discordWebhook(location)
+	if u == "" {
+		return
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: discord: %s\n", err)
+		return
+	}
+	deliver("discord", u, nil, body)
+}
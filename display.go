@@ -0,0 +1,437 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+func weatherIconIdToEmoji(id string) string {
+	// https://openweathermap.org/weather-conditions
+	switch id {
+	case "01d":
+		return "☀️" // clear sky day
+	case "02d":
+		return "⛅" // few clouds day
+	case "03d":
+		return "☁️" // scattered clouds day
+	case "04d":
+		return "☁️" // broken clouds day
+	case "09d":
+		return "🌧️" // shower rain day
+	case "10d":
+		return "🌦️" // rain day
+	case "11d":
+		return "⛈️" // thunderstorm day
+	case "13d":
+		return "❄️" // snow day
+	case "50d":
+		return "🌫️" // mist day
+	case "01n":
+		return "🌑" // clear sky night
+	case "02n":
+		return "⛅" // few clouds night
+	case "03n":
+		return "☁️" // scattered clouds night
+	case "04n":
+		return "☁️" // broken clouds night
+	case "09n":
+		return "🌧️" // shower rain night
+	case "10n":
+		return "🌦️" // rain night
+	case "11n":
+		return "⛈️" // thunderstorm night
+	case "13n":
+		return "❄️" // snow night
+	case "50n":
+		return "🌫️" // mist night
+	default:
+		return ""
+	}
+}
+
+func unitSymbols(units string) (temperature, windSpeed string) {
+	if units == "imperial" {
+		return "F", "mi/h"
+	}
+	return "C", "m/s"
+}
+
+// localTime converts t to the location's time zone given as an offset from
+// UTC in seconds.
+func localTime(t time.Time, offset int) time.Time {
+	return t.In(time.FixedZone("", offset))
+}
+
+// staleNote labels data served from the cache, e.g. " (cached 42 min ago)".
+func staleNote(cachedAt *time.Time) string {
+	if cachedAt == nil {
+		return ""
+	}
+	return " (cached " + formatAge(time.Since(*cachedAt)) + " ago)"
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1 min"
+	case d < time.Hour:
+		return localSprintf("%d min", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return localSprintf("%d h", int(d.Hours()))
+	default:
+		return localSprintf("%d days", int(d.Hours()/24))
+	}
+}
+
+// display prints the current weather. recent holds the temperatures
+// recorded in the history over the last day, oldest first, and is shown as a
+// sparkline when there are enough of them. In watch mode prev is the reading
+// of the previous refresh and the values that changed since are marked.
+func display(w io.Writer, wt, prev *Weather, recent []float64, opt *options) {
+	if isJSON(opt.format) {
+		displayJSON(w, wt, opt)
+		return
+	}
+	if opt.format == "influx" {
+		io.WriteString(w, influxWeather(wt, opt))
+		return
+	}
+	if opt.a11y {
+		displayA11y(w, wt, prev, recent, opt)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	weatherEmoji := weatherIconIdToEmoji(wt.Icon)
+
+	m := &changeMarker{prev: prev, color: colorOutput(w)}
+	temperature := m.number("%.0f°"+temperatureSymbol, wt.Temperature, func(p *Weather) float64 { return p.Temperature })
+	conditions := m.text(wt.Conditions, func(p *Weather) string { return p.Conditions })
+
+	if opt.verbose > 0 {
+		t := localTime(time.Now(), wt.TimeZone)
+
+		localFprintf(w, "%s %s%s\n", wt.CityName, localDate(t, time.Stamp), staleNote(wt.CachedAt))
+		localFprintf(w, "========================\n")
+		localFprintf(w, "condition: %s %s\n", weatherEmoji, conditions)
+		localFprintf(w, "temperature: %s\n", temperature)
+		localFprintf(w, "pressure: %s\n", m.number("%.0f hPa", wt.Pressure, func(p *Weather) float64 { return p.Pressure }))
+		localFprintf(w, "humidity: %s\n", m.number("%.1f%%", wt.Humidity, func(p *Weather) float64 { return p.Humidity }))
+		localFprintf(w, "wind: %s %s\n",
+			m.text(localSprintf("%.0f°", wt.WindDegrees), func(p *Weather) string { return localSprintf("%.0f°", p.WindDegrees) }),
+			m.number("%.1f "+windSpeedSymbol, wt.WindSpeed, func(p *Weather) float64 { return p.WindSpeed }))
+		if spark := sparkline(recent); spark != "" {
+			localFprintf(w, "last 24h: %s\n", spark)
+		}
+	} else {
+		spark := sparkline(recent)
+		if spark != "" {
+			spark = " " + spark
+		}
+		localFprintf(w, "%s %s %s %s%s%s\n", wt.CityName, temperature, weatherEmoji, conditions, spark, staleNote(wt.CachedAt))
+	}
+}
+
+// changeMarker marks the displayed values that differ from the previous
+// reading, which is nil outside watch mode. Numbers get an arrow showing the
+// direction of the change and, on a color terminal, changed values are
+// highlighted.
+type changeMarker struct {
+	prev  *Weather
+	color bool
+}
+
+// number formats value, comparing it as displayed so that changes hidden by
+// rounding are not marked.
+func (m *changeMarker) number(format string, value float64, field func(*Weather) float64) string {
+	s := localSprintf(format, value)
+	if m.prev == nil || localSprintf(format, field(m.prev)) == s {
+		return s
+	}
+	if value > field(m.prev) {
+		return m.highlight(s + " ↑")
+	}
+	return m.highlight(s + " ↓")
+}
+
+func (m *changeMarker) text(value string, field func(*Weather) string) string {
+	if m.prev == nil || field(m.prev) == value {
+		return value
+	}
+	return m.highlight(value)
+}
+
+func (m *changeMarker) highlight(s string) string {
+	if !m.color {
+		return s
+	}
+	return "\x1b[1;33m" + s + "\x1b[0m"
+}
+
+// colorOutput reports whether w is a terminal that may be written ANSI
+// colors, honoring https://no-color.org.
+func colorOutput(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if ok && f == os.Stdout {
+		f = terminalStdout()
+	}
+	return ok && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(f.Fd()))
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a line of block characters scaled between
+// their minimum and maximum. Fewer than two values render as nothing.
+func sparkline(values []float64) string {
+	if len(values) < 2 {
+		return ""
+	}
+
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = min(low, v)
+		high = max(high, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if high > low {
+			i = int((v - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		sb.WriteRune(sparkBlocks[i])
+	}
+	return sb.String()
+}
+
+func displayJSON(w io.Writer, wt *Weather, opt *options) {
+	writeJSON(w, struct {
+		*Weather
+		Units string `json:"units"`
+		unitValues
+	}{wt, opt.units, newUnitValues(wt, opt.units)}, opt.format)
+}
+
+func isJSON(format string) bool {
+	return format == "json" || format == "jsonl"
+}
+
+// writeJSON writes v indented, or on a single line in jsonl format. With
+// -jsonpath it writes the selected values instead, one per line, strings
+// without quotes.
+func writeJSON(w io.Writer, v any, format string) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if format != "jsonl" {
+		enc.SetIndent("", "  ")
+	}
+	v = outputPrecision.apply(v)
+	if outputJSONPath == nil {
+		enc.Encode(v)
+		return
+	}
+	values, err := selectJSONPath(v, outputJSONPath)
+	if err != nil {
+		exitWithError("jsonpath: " + err.Error())
+	}
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			fmt.Fprintln(w, s)
+			continue
+		}
+		enc.Encode(value)
+	}
+}
+
+func displayForecast(w io.Writer, f *Forecast, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, struct {
+			*Forecast
+			Entries []forecastEntryValues `json:"entries"`
+			Units   string                `json:"units"`
+		}{f, forecastValues(f, opt.units), opt.units}, opt.format)
+		return
+	}
+	if opt.format == "influx" {
+		io.WriteString(w, influxForecast(f, opt))
+		return
+	}
+	if opt.a11y {
+		displayForecastA11y(w, f, opt)
+		return
+	}
+	switch displayLayout(opt) {
+	case "wide":
+		displayForecastWide(w, f, opt)
+		return
+	case "narrow":
+		displayForecastNarrow(w, f, opt)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	localFprintf(w, "%s forecast%s\n", f.CityName, staleNote(f.CachedAt))
+	localFprintf(w, "========================\n")
+
+	day := ""
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		if d := localDate(t, "Mon Jan _2"); d != day {
+			if day != "" {
+				fmt.Fprintln(w)
+			}
+			localFprintf(w, "%s\n", d)
+			day = d
+		}
+
+		localFprintf(w, "  %s %s %3.0f°%s %s", t.Format("15:04"), weatherIconIdToEmoji(e.Icon), e.Temperature, temperatureSymbol, e.Conditions)
+		if opt.verbose > 0 {
+			localFprintf(w, ", wind %.0f° %.1f %s, precipitation %.1f mm (%.0f%%)", e.WindDegrees, e.WindSpeed, windSpeedSymbol, e.Precipitation, e.Probability*100)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// filterForecastDays keeps the entries of the first days calendar days in
+// the location's time zone, today included.
+func filterForecastDays(f *Forecast, days int, now time.Time) {
+	t := localTime(now, f.TimeZone)
+	end := time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+
+	kept := f.Entries[:0]
+	for _, e := range f.Entries {
+		if e.Time.Before(end) {
+			kept = append(kept, e)
+		}
+	}
+	f.Entries = kept
+}
+
+// filterForecastNext keeps the entries overlapping the next d from now.
+func filterForecastNext(f *Forecast, d time.Duration, now time.Time) {
+	kept := f.Entries[:0]
+	for _, e := range f.Entries {
+		if e.Time.Add(forecastPeriod).After(now) && e.Time.Before(now.Add(d)) {
+			kept = append(kept, e)
+		}
+	}
+	f.Entries = kept
+}
+
+// parseBetween parses a local time window of -between, e.g. "16:00-20:00".
+// A window ending before it starts, e.g. "22:00-06:00", ends the next day.
+func parseBetween(value string) (start, length time.Duration, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	var s, e time.Time
+	if ok {
+		s, err = time.Parse("15:04", strings.TrimSpace(from))
+	}
+	if ok && err == nil {
+		e, err = time.Parse("15:04", strings.TrimSpace(to))
+	}
+	if !ok || err != nil || e.Equal(s) {
+		return 0, 0, fmt.Errorf("invalid window %q, expected e.g. 16:00-20:00", value)
+	}
+	length = e.Sub(s)
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+	return time.Duration(s.Hour())*time.Hour + time.Duration(s.Minute())*time.Minute, length, nil
+}
+
+// filterForecastBetween keeps the entries overlapping the daily window of
+// length starting at start, in the location's time zone.
+func filterForecastBetween(f *Forecast, start, length time.Duration) {
+	kept := f.Entries[:0]
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		// The window of the day before may still be going on, and the one
+		// of the next day may start before the entry ends.
+		for _, day := range []time.Time{midnight.AddDate(0, 0, -1), midnight, midnight.AddDate(0, 0, 1)} {
+			from := day.Add(start)
+			if t.Add(forecastPeriod).After(from) && t.Before(from.Add(length)) {
+				kept = append(kept, e)
+				break
+			}
+		}
+	}
+	f.Entries = kept
+}
+
+// https://openweathermap.org/api/air-pollution
+var airQualityNames = []string{"", "good", "fair", "moderate", "poor", "very poor"}
+
+// Pollutants in display order.
+var airComponents = []string{"pm2_5", "pm10", "o3", "no2", "so2", "co", "no", "nh3"}
+
+func airQualityName(index int) string {
+	if index < 1 || index >= len(airQualityNames) {
+		return "unknown"
+	}
+	return airQualityNames[index]
+}
+
+func displayAir(w io.Writer, a *AirQuality, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, a, opt.format)
+		return
+	}
+	if opt.format == "influx" {
+		io.WriteString(w, influxAir(a, opt, time.Now()))
+		return
+	}
+	if opt.a11y {
+		displayAirA11y(w, a, airComponentNames(a), opt)
+		return
+	}
+
+	if opt.verbose == 0 {
+		localFprintf(w, "%s air quality %d (%s)%s\n", a.CityName, a.Index, airQualityName(a.Index), staleNote(a.CachedAt))
+		displayAirHealth(w, a.Index, opt.lang)
+		return
+	}
+
+	localFprintf(w, "%s air quality%s\n", a.CityName, staleNote(a.CachedAt))
+	localFprintf(w, "========================\n")
+	localFprintf(w, "index: %d (%s)\n", a.Index, airQualityName(a.Index))
+	displayAirHealth(w, a.Index, opt.lang)
+
+	for _, name := range airComponentNames(a) {
+		localFprintf(w, "%s: %.1f μg/m³\n", strings.ReplaceAll(name, "_", "."), a.Components[name])
+	}
+}
+
+// airComponentNames returns the pollutants of a in display order.
+func airComponentNames(a *AirQuality) []string {
+	names := make([]string, 0, len(a.Components))
+	for name := range a.Components {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := componentOrder(names[i]), componentOrder(names[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func componentOrder(name string) int {
+	for i, c := range airComponents {
+		if c == name {
+			return i
+		}
+	}
+	return len(airComponents)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var forecastStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testForecast returns a forecast of n entries every forecastPeriod from
+// forecastStart, in the time zone at offset seconds from UTC.
+func testForecast(offset, n int) *Forecast {
+	f := &Forecast{CityName: "Helsinki", TimeZone: offset}
+	for i := 0; i < n; i++ {
+		f.Entries = append(f.Entries, ForecastEntry{Time: forecastStart.Add(time.Duration(i) * forecastPeriod)})
+	}
+	return f
+}
+
+// entryTimes returns the times of the entries of f as hours since
+// forecastStart.
+func entryTimes(f *Forecast) []int {
+	hours := []int{}
+	for _, e := range f.Entries {
+		hours = append(hours, int(e.Time.Sub(forecastStart).Hours()))
+	}
+	return hours
+}
+
+func TestParseBetween(t *testing.T) {
+	tests := []struct {
+		value         string
+		start, length time.Duration
+		err           bool
+	}{
+		{value: "16:00-20:00", start: 16 * time.Hour, length: 4 * time.Hour},
+		{value: " 06:30 - 07:15 ", start: 6*time.Hour + 30*time.Minute, length: 45 * time.Minute},
+		{value: "22:00-02:00", start: 22 * time.Hour, length: 4 * time.Hour},
+		{value: "23:30-00:00", start: 23*time.Hour + 30*time.Minute, length: 30 * time.Minute},
+		{value: "08:00-08:00", err: true},
+		{value: "16:00", err: true},
+		{value: "16-20", err: true},
+		{value: "16:00-24:30", err: true},
+		{value: "", err: true},
+	}
+	for _, tt := range tests {
+		start, length, err := parseBetween(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseBetween(%q) = %s, %s, want an error", tt.value, start, length)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBetween(%q): %s", tt.value, err)
+			continue
+		}
+		if start != tt.start || length != tt.length {
+			t.Errorf("parseBetween(%q) = %s, %s, want %s, %s", tt.value, start, length, tt.start, tt.length)
+		}
+	}
+}
+
+func TestFilterForecastNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries int
+		now     time.Duration
+		next    time.Duration
+		want    []int
+	}{
+		{"within an entry", 8, 4 * time.Hour, 3 * time.Hour, []int{3, 6}},
+		{"at an entry", 8, 3 * time.Hour, 3 * time.Hour, []int{3}},
+		{"longer than the forecast", 8, time.Hour, 72 * time.Hour, []int{0, 3, 6, 9, 12, 15, 18, 21}},
+		{"after the forecast", 8, 30 * time.Hour, 6 * time.Hour, []int{}},
+		{"empty forecast", 0, 0, 6 * time.Hour, []int{}},
+	}
+	for _, tt := range tests {
+		f := testForecast(0, tt.entries)
+		filterForecastNext(f, tt.next, forecastStart.Add(tt.now))
+		if got := entryTimes(f); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: kept the entries at %v h, want %v h", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterForecastBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  int
+		between string
+		want    []int
+	}{
+		{"afternoon", 0, "12:00-15:00", []int{12, 36}},
+		{"within an entry", 0, "13:00-14:00", []int{12, 36}},
+		{"past midnight", 0, "22:00-02:00", []int{0, 21, 24, 45}},
+		{"east of UTC", 2 * 3600, "12:00-13:00", []int{9, 33}},
+		{"west of UTC", -5 * 3600, "00:00-01:00", []int{3, 27}},
+		{"past midnight west of UTC", -5 * 3600, "23:00-02:00", []int{3, 6, 27, 30}},
+		{"past midnight east of UTC", 3 * 3600, "23:00-01:00", []int{18, 21, 42, 45}},
+	}
+	for _, tt := range tests {
+		start, length, err := parseBetween(tt.between)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		f := testForecast(tt.offset, 16)
+		filterForecastBetween(f, start, length)
+		if got := entryTimes(f); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: -between %s kept the entries at %v h UTC, want %v h", tt.name, tt.between, got, tt.want)
+		}
+	}
+}
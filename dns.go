@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The daemon and the server resolve host names through a cache, so that a
+// slow or flaky local resolver does not add its latency to every refresh.
+// Addresses are kept for -dns-ttl; when the resolver fails, the addresses
+// resolved before are used however old.
+
+func dnsFlags(fs *flag.FlagSet, opt *options) {
+	fs.DurationVar(&opt.dnsTTL, "dns-ttl", 5*time.Minute, "how long resolved host names are cached, 0 disables the cache")
+}
+
+// dnsCache resolves host names, caching the addresses for ttl.
+type dnsCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+
+	mu    sync.Mutex
+	hosts map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs    []string
+	resolved time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, resolver: net.DefaultResolver, hosts: map[string]dnsEntry{}}
+}
+
+// lookup returns the addresses of host.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	c.mu.Lock()
+	e, ok := c.hosts[host]
+	c.mu.Unlock()
+	if ok && time.Since(e.resolved) < c.ttl {
+		dnsLookups.inc("hit")
+		return e.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		if ok {
+			dnsLookups.inc("stale")
+			return e.addrs, nil
+		}
+		return nil, err
+	}
+	dnsLookups.inc("miss")
+	c.mu.Lock()
+	c.hosts[host] = dnsEntry{addrs: addrs, resolved: time.Now()}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// dialContext returns a DialContext for http.Transport dialing the
+// addresses of the host on the network in turn.
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		err = &net.AddrError{Err: "no suitable address found", Addr: host}
+		for _, a := range addrs {
+			if !inAddressFamily(network, a) {
+				continue
+			}
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(a, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// useDNSCache makes the default transport resolve host names through a
+// cache with ttl.
+func useDNSCache(ttl time.Duration) {
+	updateTransport(func(t *http.Transport) {
+		// The dialer of http.DefaultTransport.
+		t.DialContext = newDNSCache(ttl).dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	})
+}
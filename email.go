@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func smtpFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.smtpHost, "smtp-host", "", "SMTP server to send the digest through")
+	fs.IntVar(&opt.smtpPort, "smtp-port", 587, "SMTP port; 465 uses implicit TLS, others STARTTLS when offered")
+	fs.StringVar(&opt.smtpUser, "smtp-user", "", "SMTP user name")
+	fs.StringVar(&opt.smtpPassword, "smtp-password", "", "SMTP password")
+	fs.StringVar(&opt.smtpFrom, "smtp-from", "", "sender address of the digest (default -smtp-user)")
+	fs.StringVar(&opt.smtpTo, "smtp-to", "", "comma separated recipients of the digest")
+}
+
+// runDigest emails the forecast of the day for the favorites, once or with
+// -schedule every day at -summary-time.
+func runDigest(s *session) {
+	checkSummaryTime(s.opt)
+	checkSMTP(s.opt)
+	cities := s.digestCities()
+
+	if !s.opt.schedule {
+		if err := s.sendDigest(cities, time.Now()); err != nil {
+			exitWithError("digest: " + err.Error())
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		next := nextSummaryTime(time.Now(), s.opt.summaryTime)
+		fmt.Fprintf(os.Stderr, "next digest at %s\n", next.Format("2006-01-02 15:04"))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if err := s.sendDigest(cities, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: digest: %s\n", redact(err.Error()))
+		}
+	}
+}
+
+// checkSMTP exits unless the SMTP settings needed for sending mail are set.
+func checkSMTP(opt *options) {
+	if opt.smtpHost == "" || opt.smtpTo == "" {
+		exitWithError("smtp-host and smtp-to are required, set them with \"weather config set smtp_host ...\"")
+	}
+	if opt.smtpFrom == "" {
+		opt.smtpFrom = opt.smtpUser
+	}
+	if opt.smtpFrom == "" {
+		exitWithError("smtp-from is required")
+	}
+}
+
+// digestCities returns the favorites, or the locations given as arguments
+// or the default city.
+func (s *session) digestCities() []string {
+	cities := s.cfg.list("favorites")
+	if len(s.args()) > 0 || len(cities) == 0 {
+		cities = s.cities()
+	}
+	return cities
+}
+
+// sendDigest emails the summaries of cities. Locations that cannot be
+// fetched are noted in the message rather than failing the whole digest.
+func (s *session) sendDigest(cities []string, now time.Time) error {
+	var body bytes.Buffer
+	var headlines []string
+	for i, city := range cities {
+		if i > 0 {
+			body.WriteString("\n")
+		}
+		sum, err := s.dailySummary(city)
+		if err != nil {
+			fmt.Fprintf(&body, "%s: %s\n", city, redact(err.Error()))
+			continue
+		}
+		writeDigest(&body, sum, s.opt, now)
+
+		temperatureSymbol, _ := unitSymbols(s.opt.units)
+		headlines = append(headlines, fmt.Sprintf("%s %.0f°%s", sum.weather.CityName, sum.weather.Temperature, temperatureSymbol))
+	}
+
+	subject := fmt.Sprintf("Weather for %s: %s", localDate(now, "Mon 2 Jan"), strings.Join(headlines, ", "))
+	return sendMail(s.opt, subject, body.String(), now)
+}
+
+// writeDigest writes the current weather of a location followed by the
+// forecast of the rest of the day.
+func writeDigest(w *bytes.Buffer, sum *dailySummary, opt *options, now time.Time) {
+	textOpt := *opt
+	textOpt.format = "text"
+	textOpt.verbose = 0
+	displaySummary(w, sum, &textOpt)
+
+	f := *sum.forecast
+	f.Entries = append([]ForecastEntry(nil), f.Entries...)
+	filterForecastDays(&f, 1, now)
+	if len(f.Entries) > 0 {
+		displayForecast(w, &f, &textOpt)
+	}
+}
+
+func sendMail(opt *options, subject, body string, now time.Time) error {
+	to := parseKeys(opt.smtpTo)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", opt.smtpFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+
+	addr := net.JoinHostPort(opt.smtpHost, strconv.Itoa(opt.smtpPort))
+	conn, err := net.DialTimeout(tcpNetwork, addr, opt.timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(opt.timeout))
+	tlsConfig := &tls.Config{ServerName: opt.smtpHost}
+	if opt.smtpPort == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, opt.smtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && opt.smtpPort != 465 {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if opt.smtpUser != "" {
+		// PlainAuth refuses to send the password without TLS, except to
+		// localhost.
+		if err := c.Auth(smtp.PlainAuth("", opt.smtpUser, opt.smtpPassword, opt.smtpHost)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(opt.smtpFrom); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envNames overrides the environment variable of flags whose names would
+// not make sensible variable names.
+var envNames = map[string]string{
+	"key": "WEATHER_API_KEY",
+	"o":   "WEATHER_FORMAT",
+	"v":   "WEATHER_VERBOSE",
+}
+
+// envName returns the WEATHER_* variable providing the value of a flag,
+// e.g. WEATHER_KEY_ROTATION for -key-rotation.
+func envName(flagName string) string {
+	if name, ok := envNames[flagName]; ok {
+		return name
+	}
+	return "WEATHER_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets every flag not given on the command line from its
+// environment variable. It runs before applyConfig, which skips flags set
+// here, so the environment takes precedence over the config file.
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := setFlags(fs)
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || explicit[f.Name] || err != nil {
+			return
+		}
+		// Like the config format, WEATHER_FORMAT only applies to the
+		// commands that have the format.
+		if setErr := fs.Set(f.Name, value); setErr != nil && f.Name != "o" {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// setFlags returns the names of the flags already set. The verbosity flags
+// count as one, as they add up: with -vv given, neither WEATHER_VERBOSE nor
+// the config verbose may raise the level further.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["v"] || set["vv"] || set["vvv"] {
+		set["v"], set["vv"], set["vvv"] = true, true, true
+	}
+	return set
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Exit statuses, documented in the readme so that scripts can tell failures
+// apart, e.g. retry later when rate limited but not when the key is wrong.
+const (
+	exitFailure     = 1 // any other error
+	exitTriggered   = 2 // weather check: an alert rule is triggered
+	exitUsage       = 3 // invalid options or arguments
+	exitAuth        = 4 // missing or rejected API key
+	exitNotFound    = 5 // unknown location
+	exitRateLimited = 6 // every API key is rate limited
+	exitNetwork     = 7 // the provider cannot be reached
+)
+
+// exitStatuses describes the exit statuses in the man page.
+var exitStatuses = []struct {
+	status  int
+	meaning string
+}{
+	{0, "Success."},
+	{exitFailure, "An error not listed below."},
+	{exitTriggered, "weather check: an alert rule is triggered."},
+	{exitUsage, "Invalid options or arguments, or an ambiguous location."},
+	{exitAuth, "The API key is missing or was rejected."},
+	{exitNotFound, "The location was not found."},
+	{exitRateLimited, "Every API key is rate limited."},
+	{exitNetwork, "The provider cannot be reached or is unavailable."},
+}
+
+// exitWithStatus prints errorMessage and exits with status.
+func exitWithStatus(status int, errorMessage string) {
+	discardOutput()
+	showPage(false)
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", redact(errorMessage))
+	if watching {
+		panic(watchExit{status: status, failed: true})
+	}
+	exit(status)
+}
+
+func exitWithUsageError(errorMessage string) {
+	exitWithStatus(exitUsage, errorMessage)
+}
+
+// exitStatus returns the exit status for a failed request to the provider.
+func exitStatus(err error) int {
+	var se *statusError
+	var nf *notFoundError
+	switch {
+	case errors.As(err, &nf):
+		return exitNotFound
+	case errors.As(err, &se) && se.code == http.StatusUnauthorized:
+		return exitAuth
+	case errors.As(err, &se) && se.code == http.StatusNotFound:
+		return exitNotFound
+	case errors.As(err, &se) && se.code == http.StatusTooManyRequests:
+		return exitRateLimited
+	case isUnavailable(err):
+		return exitNetwork
+	}
+	return exitFailure
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Atom feed documents, see https://datatracker.ietf.org/doc/html/rfc4287
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Author  atomAuthor   `xml:"author"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary"`
+	Content atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedItem is the data of one location in a feed.
+type feedItem struct {
+	weather *Weather
+	alerts  []Alert
+}
+
+func runFeed(s *session) {
+	var items []feedItem
+	for _, city := range s.cities() {
+		item, err := s.feedItem(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) > 0 {
+		writeFeed(os.Stdout, items, s.opt, time.Now())
+	}
+}
+
+// feedItem fetches the current weather and alerts of a location. Alerts
+// need a One Call API 3.0 subscription; without one the feed has no alerts.
+func (s *session) feedItem(q query) (feedItem, error) {
+	w, err := s.provider().current(q)
+	if err != nil {
+		return feedItem{}, err
+	}
+	s.recordHistory(w, q.units)
+
+	alerts, err := s.provider().alerts(q)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		err = nil
+	}
+	return feedItem{weather: w, alerts: alerts}, err
+}
+
+func writeFeed(w io.Writer, items []feedItem, opt *options, now time.Time) {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	var names []string
+	for _, item := range items {
+		names = append(names, item.weather.CityName)
+	}
+
+	feed := &atomFeed{
+		ID:      "tag:weather,2024:" + feedSlug(strings.Join(names, ",")),
+		Title:   strings.Join(names, ", ") + " weather",
+		Updated: now.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "weather"},
+	}
+
+	for _, item := range items {
+		wt := item.weather
+		slug := feedSlug(wt.CityName)
+
+		for _, a := range item.alerts {
+			summary := fmt.Sprintf("%s from %s until %s", a.Event, a.Sender, localTime(a.End, wt.TimeZone).Format("Mon Jan _2 15:04"))
+			feed.Entries = append(feed.Entries, &atomEntry{
+				ID:      fmt.Sprintf("tag:weather,2024:%s/alert/%d/%s", slug, a.Start.Unix(), feedSlug(a.Event)),
+				Title:   fmt.Sprintf("⚠️ %s: %s", wt.CityName, a.Event),
+				Updated: a.Start.Format(time.RFC3339),
+				Summary: summary,
+				Content: atomText{Type: "text", Body: summary + "\n\n" + a.Description},
+			})
+		}
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "condition: %s\n", wt.Conditions)
+		fmt.Fprintf(&content, "temperature: %.0f°%s\n", wt.Temperature, temperatureSymbol)
+		fmt.Fprintf(&content, "pressure: %.0f hPa\n", wt.Pressure)
+		fmt.Fprintf(&content, "humidity: %.0f%%\n", wt.Humidity)
+		fmt.Fprintf(&content, "wind: %.0f° %.1f %s\n", wt.WindDegrees, wt.WindSpeed, windSpeedSymbol)
+
+		feed.Entries = append(feed.Entries, &atomEntry{
+			ID:      fmt.Sprintf("tag:weather,2024:%s/%d", slug, wt.Time.Unix()),
+			Title:   fmt.Sprintf("%s %.0f°%s %s %s", wt.CityName, wt.Temperature, temperatureSymbol, weatherIconIdToEmoji(wt.Icon), wt.Conditions),
+			Updated: wt.Time.Format(time.RFC3339),
+			Summary: fmt.Sprintf("%s, %.0f°%s", wt.Conditions, wt.Temperature, temperatureSymbol),
+			Content: atomText{Type: "text", Body: content.String()},
+		})
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(feed)
+	io.WriteString(w, "\n")
+}
+
+// feedSlug turns a name into the lower case, dash separated form used in
+// entry IDs.
+func feedSlug(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(name, ",", " ")), "-"))
+}
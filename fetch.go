@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Commands fetching many locations, batch mode, the favorites, the daemon
+// and the server, share one scheduler: a pool of -parallel workers per
+// command, while the provider keeps at most -max-requests of its requests
+// in flight across all of them, so that a long list completes quickly
+// without tripping the rate limits of the provider.
+
+// fetchAll calls fetch for each of n locations, parallel at a time, and
+// waits for them to finish. Once ctx is done no more are started. Failures
+// are up to fetch to record, they do not stop the others.
+func (s *session) fetchAll(ctx context.Context, n, parallel int, fetch func(i int)) {
+	// The provider is created up front rather than by the first workers
+	// racing for it.
+	s.provider()
+
+	var g errgroup.Group
+	var exits workerExits
+	g.SetLimit(max(parallel, 1))
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		i := i
+		g.Go(func() error {
+			defer exits.catch()
+			fetch(i)
+			return nil
+		})
+	}
+	g.Wait()
+	exits.rethrow()
+}
+
+// requestSlots limits the requests in flight to a provider.
+type requestSlots chan struct{}
+
+func newRequestSlots(n int) requestSlots {
+	return make(requestSlots, max(n, 1))
+}
+
+// acquire waits for a free slot; release frees it.
+func (r requestSlots) acquire() { r <- struct{}{} }
+func (r requestSlots) release() { <-r }
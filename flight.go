@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls sharing the same key into a single
+// call. Callers arriving while a call is in flight wait for it and receive
+// the same result instead of issuing their own upstream request.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// errFlightAborted is returned to the callers waiting for a call that
+// panicked; the panic itself goes on in the caller that made the call.
+var errFlightAborted = errors.New("the request in flight was aborted")
+
+type flightCall[T any] struct {
+	wg  sync.WaitGroup
+	val T
+	err error
+}
+
+func (g *flightGroup[T]) do(key string, fn func() (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+
+	c := &flightCall[T]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	returned := false
+	defer func() {
+		if !returned {
+			c.err = errFlightAborted
+		}
+		c.wg.Done()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+	c.val, c.err = fn()
+	returned = true
+	return c.val, c.err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup[int]
+	started, release := make(chan struct{}), make(chan struct{})
+
+	recovered := make(chan any)
+	go func() {
+		defer func() { recovered <- recover() }()
+		g.do("key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waited := make(chan error)
+	go func() {
+		_, err := g.do("key", func() (int, error) { return 2, nil })
+		waited <- err
+	}()
+	// Give the second caller time to join the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if r := <-recovered; r != "boom" {
+		t.Errorf("the caller of the call recovered %v, want its panic", r)
+	}
+	select {
+	case err := <-waited:
+		if !errors.Is(err, errFlightAborted) {
+			t.Errorf("the waiting caller got %v, want %v", err, errFlightAborted)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the waiting caller is still blocked")
+	}
+
+	if v, err := g.do("key", func() (int, error) { return 3, nil }); v != 3 || err != nil {
+		t.Errorf("a call after the panic got %d, %v, want 3, nil", v, err)
+	}
+}
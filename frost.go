@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// weather frost warns gardeners of frost in the coming night: when the
+// lowest forecast temperature falls to -threshold, or approaches it in air
+// dry enough for the ground to freeze first. Like weather check it exits
+// with status 2 when it warns and can notify with the same channels.
+
+// frostMargin is how far above the threshold the night low can stay with
+// ground frost still possible when the dew point is below freezing.
+const frostMargin = 2
+
+func frostFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.frostThreshold, "threshold", "2°C", "night low that warns of frost, e.g. 0 or 36°F (unitless in -units)")
+}
+
+// dewPoint returns the dew point in °C of air at a temperature in °C and a
+// relative humidity in %, by the Magnus formula.
+func dewPoint(temperature, humidity float64) float64 {
+	const b, c = 17.62, 243.12
+	gamma := math.Log(max(humidity, 1)/100) + b*temperature/(c+temperature)
+	return c * gamma / (b - gamma)
+}
+
+// frostOutlook is the frost risk of the coming night at a location. Frost
+// is "likely", "possible" or empty; CoverBy is when the temperature first
+// falls to the threshold.
+type frostOutlook struct {
+	City      string     `json:"city"`
+	Frost     string     `json:"frost,omitempty"`
+	Low       float64    `json:"low"`
+	LowAt     time.Time  `json:"low_at"`
+	DewPoint  float64    `json:"dew_point"`
+	Threshold float64    `json:"threshold"`
+	CoverBy   *time.Time `json:"cover_by,omitempty"`
+
+	timeZone int
+}
+
+// nightWindow returns the coming night at a location from 18:00 to 09:00
+// local time, or from now when the night has begun.
+func nightWindow(now time.Time, timeZone int) (time.Time, time.Time) {
+	t := localTime(now, timeZone)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	start, end := day.Add(18*time.Hour), day.Add(33*time.Hour)
+	if t.Hour() < 9 {
+		start, end = start.AddDate(0, 0, -1), end.AddDate(0, 0, -1)
+	}
+	if start.Before(t) {
+		start = t
+	}
+	return start.UTC(), end.UTC()
+}
+
+// outlookFrost tests the night low of f against the threshold rule r,
+// whose value is metric. It returns nil when the night is beyond f.
+func outlookFrost(f *Forecast, r *rule, now time.Time, units string) *frostOutlook {
+	start, end := nightWindow(now, f.TimeZone)
+	var o *frostOutlook
+	for _, e := range f.Entries {
+		if !e.Time.Add(forecastPeriod).After(start) || !e.Time.Before(end) {
+			continue
+		}
+		m := toMetric(&Weather{Temperature: e.Temperature}, units)
+		if o == nil || e.Temperature < o.Low {
+			if o == nil {
+				o = &frostOutlook{City: f.CityName, timeZone: f.TimeZone}
+			}
+			o.Low, o.LowAt = e.Temperature, e.Time
+			if o.LowAt.Before(start) {
+				o.LowAt = start
+			}
+			o.DewPoint = dewPoint(m.Temperature, e.Humidity)
+		}
+		if o.CoverBy == nil && r.matches(m.Temperature) {
+			at := e.Time
+			if at.Before(now) {
+				at = now
+			}
+			o.CoverBy = &at
+		}
+	}
+	if o == nil {
+		return nil
+	}
+
+	low := toMetric(&Weather{Temperature: o.Low}, units).Temperature
+	switch {
+	case r.matches(low):
+		o.Frost = "likely"
+	case o.DewPoint <= 0 && r.matches(low-frostMargin):
+		o.Frost = "possible"
+	}
+	o.DewPoint = fromMetric(&Weather{Temperature: o.DewPoint}, units).Temperature
+	o.Threshold = fromMetric(&Weather{Temperature: r.value}, units).Temperature
+	return o
+}
+
+// runFrost checks the coming night at each location and exits with status
+// 2 when frost is likely or possible at any of them.
+func runFrost(s *session) {
+	r, err := parseRule("temp <= "+s.opt.frostThreshold, s.opt.units)
+	if err != nil {
+		exitWithUsageError(fmt.Sprintf("invalid threshold %q, expected e.g. 0 or 36°F", s.opt.frostThreshold))
+	}
+	r.text = "frost"
+
+	warned := false
+	cities := s.cities()
+	for _, city := range cities {
+		f, err := s.provider().forecast(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		o := outlookFrost(f, r, time.Now(), s.opt.units)
+		if o == nil {
+			continue
+		}
+		displayFrost(os.Stdout, o, len(cities) > 1, s.opt)
+
+		if o.Frost != "" {
+			warned = true
+			w := &Weather{Time: o.LowAt, CityName: o.City, TimeZone: o.timeZone, Temperature: o.Low}
+			sendWebhooks(parseKeys(s.opt.webhooks), s.opt.webhookSecret, &webhookEvent{Type: "frost", Location: o.City, Time: o.LowAt, Units: s.opt.units, Weather: w, Rule: r.text})
+			s.notifyRule(w, ruleResult{City: o.City, Rule: "frost " + o.Frost + " tonight", Value: o.Low, Triggered: true, quantity: "temperature"})
+		}
+	}
+
+	webhookDeliveries.Wait()
+	if warned {
+		exit(exitTriggered)
+	}
+}
+
+// displayFrost writes e.g. "Frost likely tonight, low -1°C around 05:00
+// (dew point -3°C), cover plants before 23:00", prefixed with the location
+// when withCity is set.
+func displayFrost(w io.Writer, o *frostOutlook, withCity bool, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, o, opt.format)
+		return
+	}
+
+	temperatureSymbol, _ := unitSymbols(opt.units)
+	if withCity {
+		localFprintf(w, "%s: ", o.City)
+	}
+	low := localSprintf("low %.0f°%s around %s (dew point %.0f°%s)", o.Low, temperatureSymbol,
+		localTime(o.LowAt, o.timeZone).Format("15:04"), o.DewPoint, temperatureSymbol)
+	switch {
+	case o.Frost == "likely":
+		localFprintf(w, "Frost likely tonight, %s, cover plants before %s\n", low, localTime(*o.CoverBy, o.timeZone).Format("15:04"))
+	case o.Frost == "possible":
+		localFprintf(w, "Ground frost possible tonight, %s, consider covering plants\n", low)
+	default:
+		localFprintf(w, "No frost expected tonight, %s\n", low)
+	}
+}
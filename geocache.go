@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Places do not move like the weather does, so the results of geocoding,
+// the coordinates of a name and the name of coordinates, are kept in a
+// cache of their own without a time limit, and repeated queries for a city
+// skip the round trip. `weather cache forget` removes the results for a
+// location and `weather cache clear` all of them.
+
+// newGeocodingCache returns the geocoding cache under the response cache
+// directory dir.
+func newGeocodingCache(dir string) *cache {
+	return newCache(filepath.Join(dir, "geocoding"))
+}
+
+// geocodingKey returns the key of the results for name, which may also be
+// coordinates, "lat,lon".
+func geocodingKey(name, what string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " ")) + "|" + what
+}
+
+// cachedLocations returns the locations cached for key, the results of
+// endpoint.
+func (ow *openWeather) cachedLocations(endpoint, key string) ([]Location, bool) {
+	if ow.geocoding == nil || ow.noCache || ow.raw {
+		return nil, false
+	}
+	e, ok := ow.geocoding.get(key)
+	if !ok {
+		return nil, false
+	}
+	var locations []Location
+	if err := json.Unmarshal(e.Body, &locations); err != nil || len(locations) == 0 {
+		return nil, false
+	}
+	cacheLookups.inc("hit")
+	ow.tracef(endpoint, "geocoding cache hit")
+	return locations, true
+}
+
+// storeLocations caches the locations of key. As with responses, caching
+// is best effort.
+func (ow *openWeather) storeLocations(key string, locations []Location) {
+	if ow.geocoding == nil || ow.raw || len(locations) == 0 {
+		return
+	}
+	if b, err := json.Marshal(locations); err == nil {
+		ow.geocoding.put(key, b)
+	}
+}
+
+// forget removes the entries whose keys are for name and returns the number
+// removed.
+func (c *cache) forget(name string) (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	prefix := geocodingKey(name, "")
+	n := 0
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var e cacheEntry
+		if json.Unmarshal(b, &e) != nil || !strings.HasPrefix(e.Key, prefix) {
+			continue
+		}
+		if err := os.Remove(f); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
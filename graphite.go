@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Graphite and StatsD emitters for monitoring stacks that predate
+// Prometheus. Values are in metric units like with -o influx.
+// https://graphite.readthedocs.io/en/latest/feeding-carbon.html
+// https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+
+func graphiteFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.graphiteAddr, "graphite", "", "send the data to this Graphite plaintext address, e.g. localhost:2003")
+	fs.StringVar(&opt.statsdAddr, "statsd", "", "send the data as gauges to this StatsD address, e.g. localhost:8125")
+	fs.StringVar(&opt.metricsPrefix, "metrics-prefix", "weather", "prefix of the Graphite and StatsD metric names")
+	fs.StringVar(&opt.metricsTags, "metrics-tags", "", "comma separated key=value tags added to the Graphite and StatsD metrics")
+}
+
+type metricSample struct {
+	name  string
+	value float64
+}
+
+func weatherSamples(wt *Weather, units string) []metricSample {
+	m := toMetric(wt, units)
+	return []metricSample{
+		{"temperature", m.Temperature},
+		{"pressure", m.Pressure},
+		{"humidity", m.Humidity},
+		{"wind_speed", m.WindSpeed},
+		{"wind_degrees", m.WindDegrees},
+		{"visibility", m.Visibility},
+	}
+}
+
+func airSamples(a *AirQuality) []metricSample {
+	samples := []metricSample{{"aqi", float64(a.Index)}}
+	for name, value := range a.Components {
+		samples = append(samples, metricSample{name, value})
+	}
+	sort.Slice(samples[1:], func(i, j int) bool { return samples[i+1].name < samples[j+1].name })
+	return samples
+}
+
+// metricPath returns the dotted metric name, e.g. "weather.helsinki.temperature".
+func metricPath(prefix, location, name string) string {
+	var parts []string
+	for _, part := range []string{prefix, location, name} {
+		part = strings.Map(func(r rune) rune {
+			if r == '.' || r == ' ' || r == ',' || r == ';' || r == '=' || r == ':' || r == '|' || r == '#' {
+				return '_'
+			}
+			return r
+		}, strings.ToLower(part))
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// metricTags parses the -metrics-tags list into sorted key=value pairs.
+func metricTags(value string) ([][2]string, error) {
+	var tags [][2]string
+	for _, tag := range parseKeys(value) {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", tag)
+		}
+		tags = append(tags, [2]string{k, v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+	return tags, nil
+}
+
+func graphiteLines(prefix, location string, tags [][2]string, samples []metricSample, t time.Time) string {
+	var suffix string
+	for _, tag := range tags {
+		suffix += ";" + tag[0] + "=" + tag[1]
+	}
+
+	var sb strings.Builder
+	for _, s := range samples {
+		fmt.Fprintf(&sb, "%s%s %s %d\n", metricPath(prefix, location, s.name), suffix, formatFloat(s.value), t.Unix())
+	}
+	return sb.String()
+}
+
+// statsdLines formats samples as gauges with DogStatsD style tags.
+func statsdLines(prefix, location string, tags [][2]string, samples []metricSample) string {
+	var suffix string
+	if len(tags) > 0 {
+		pairs := make([]string, len(tags))
+		for i, tag := range tags {
+			pairs[i] = tag[0] + ":" + tag[1]
+		}
+		suffix = "|#" + strings.Join(pairs, ",")
+	}
+
+	var sb strings.Builder
+	for _, s := range samples {
+		fmt.Fprintf(&sb, "%s:%s|g%s\n", metricPath(prefix, location, s.name), formatFloat(s.value), suffix)
+	}
+	return sb.String()
+}
+
+// emitMetrics sends samples to the -graphite and -statsd addresses that
+// are given. Failures are reported but do not stop the command.
+func (s *session) emitMetrics(location string, samples []metricSample, t time.Time) {
+	if s.opt.graphiteAddr == "" && s.opt.statsdAddr == "" {
+		return
+	}
+	tags, err := metricTags(s.opt.metricsTags)
+	if err != nil {
+		exitWithError("metrics-tags: " + err.Error())
+	}
+
+	if s.opt.graphiteAddr != "" {
+		if err := sendMetrics("tcp", s.opt.graphiteAddr, graphiteLines(s.opt.metricsPrefix, location, tags, samples, t)); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: graphite: %s\n", err)
+		}
+	}
+	if s.opt.statsdAddr != "" {
+		// One datagram per metric keeps each packet well below the MTU.
+		lines := strings.Split(strings.TrimSuffix(statsdLines(s.opt.metricsPrefix, location, tags, samples), "\n"), "\n")
+		if err := sendMetrics("udp", s.opt.statsdAddr, lines...); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: statsd: %s\n", err)
+		}
+	}
+}
+
+// sendMetrics writes each packet to addr in a write of its own.
+func sendMetrics(network, addr string, packets ...string) error {
+	conn, err := net.DialTimeout(network, addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	for _, packet := range packets {
+		if _, err := conn.Write([]byte(packet)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/jtlehtinen/weather/weatherpb"
+)
+
+// grpcServer implements weatherpb.WeatherService on top of the provider,
+// with the same defaults as the HTTP API.
+type grpcServer struct {
+	weatherpb.UnimplementedWeatherServiceServer
+	s *session
+}
+
+// serveGRPC serves the gRPC API on l until it is closed.
+func (s *session) serveGRPC(l net.Listener) *grpc.Server {
+	srv := grpc.NewServer()
+	weatherpb.RegisterWeatherServiceServer(srv, &grpcServer{s: s})
+	go func() {
+		if err := srv.Serve(l); err != nil {
+			exitWithError("serve: " + err.Error())
+		}
+	}()
+	return srv
+}
+
+func (g *grpcServer) Current(ctx context.Context, req *weatherpb.CurrentRequest) (*weatherpb.Weather, error) {
+	q, err := g.query(req.City, req.Units, req.Lang)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	w, err := g.s.provider().current(q)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	g.s.recordHistory(w, q.units)
+	return weatherProto(w, q.units), nil
+}
+
+func (g *grpcServer) Forecast(ctx context.Context, req *weatherpb.ForecastRequest) (*weatherpb.Forecast, error) {
+	if req.Days < 0 || req.Days > 5 {
+		return nil, status.Error(codes.InvalidArgument, "days must be between 1 and 5")
+	}
+	q, err := g.query(req.City, req.Units, req.Lang)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	f, err := g.s.provider().forecast(q)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if req.Days > 0 {
+		filterForecastDays(f, int(req.Days), time.Now())
+	}
+
+	p := &weatherpb.Forecast{City: f.CityName, Timezone: int32(f.TimeZone), Units: unitsProto(q.units)}
+	for _, e := range f.Entries {
+		p.Entries = append(p.Entries, &weatherpb.ForecastEntry{
+			Time:                     timestamppb.New(e.Time),
+			Temperature:              e.Temperature,
+			Pressure:                 e.Pressure,
+			Humidity:                 e.Humidity,
+			WindSpeed:                e.WindSpeed,
+			WindDegrees:              e.WindDegrees,
+			WindGust:                 e.WindGust,
+			Precipitation:            e.Precipitation,
+			PrecipitationProbability: e.Probability,
+			Conditions:               e.Conditions,
+			Icon:                     e.Icon,
+		})
+	}
+	return p, nil
+}
+
+// WatchUpdates checks the locations every -interval, like /v1/stream, and
+// sends the ones whose data has changed.
+func (g *grpcServer) WatchUpdates(req *weatherpb.WatchRequest, stream weatherpb.WeatherService_WatchUpdatesServer) error {
+	cities := req.Cities
+	if len(cities) == 0 {
+		cities = []string{""}
+	}
+	if len(cities) > maxSubscriptions {
+		return status.Error(codes.InvalidArgument, "too many cities")
+	}
+	queries := make([]query, len(cities))
+	for i, city := range cities {
+		q, err := g.query(city, req.Units, req.Lang)
+		if err != nil {
+			return grpcError(err)
+		}
+		queries[i] = q
+	}
+
+	last := make([]*Weather, len(queries))
+	ticker := time.NewTicker(g.s.opt.interval)
+	defer ticker.Stop()
+	for {
+		for i, q := range queries {
+			w, err := g.s.provider().current(q)
+			if err != nil {
+				// A failed check is retried on the next tick as long as
+				// there is earlier data to fall back on.
+				if last[i] == nil {
+					return grpcError(err)
+				}
+				continue
+			}
+			if last[i] != nil && *stripCachedAt(last[i]) == *stripCachedAt(w) {
+				continue
+			}
+			last[i] = w
+			g.s.recordHistory(w, q.units)
+			if err := stream.Send(weatherProto(w, q.units)); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// query builds the provider query for a request. Unspecified units and
+// lang, and an empty city, default to the server settings.
+func (g *grpcServer) query(city string, units weatherpb.Units, lang string) (query, error) {
+	opt := *g.s.opt
+	switch units {
+	case weatherpb.Units_UNITS_METRIC:
+		opt.units = "metric"
+	case weatherpb.Units_UNITS_IMPERIAL:
+		opt.units = "imperial"
+	}
+	if lang != "" {
+		if err := checkEnum("lang", lang, langValues); err != nil {
+			return query{}, &apiError{http.StatusBadRequest, err.Error()}
+		}
+		opt.lang = lang
+	}
+	return g.s.serverQuery(&opt, city)
+}
+
+func unitsProto(units string) weatherpb.Units {
+	if units == "imperial" {
+		return weatherpb.Units_UNITS_IMPERIAL
+	}
+	return weatherpb.Units_UNITS_METRIC
+}
+
+func weatherProto(w *Weather, units string) *weatherpb.Weather {
+	return &weatherpb.Weather{
+		Time:        timestamppb.New(w.Time),
+		City:        w.CityName,
+		Timezone:    int32(w.TimeZone),
+		Visibility:  w.Visibility,
+		Temperature: w.Temperature,
+		Pressure:    w.Pressure,
+		Humidity:    w.Humidity,
+		WindSpeed:   w.WindSpeed,
+		WindDegrees: w.WindDegrees,
+		Conditions:  w.Conditions,
+		Icon:        w.Icon,
+		Units:       unitsProto(units),
+	}
+}
+
+// grpcError maps err to a gRPC status the same way writeAPIError maps errors
+// to HTTP statuses.
+func grpcError(err error) error {
+	var ae *apiError
+	var nf *notFoundError
+	switch {
+	case errors.As(err, &ae):
+		code := codes.Unavailable
+		switch ae.status {
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusNotFound:
+			code = codes.NotFound
+		}
+		return status.Error(code, ae.message)
+	case errors.As(err, &nf):
+		return status.Error(codes.NotFound, redact(err.Error()))
+	}
+	return status.Error(codes.Unavailable, redact(err.Error()))
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The history database records every fetched observation. Values are
+// always stored in metric units regardless of -units. An observation is
+// recorded under the city name the provider gave, and the location it was
+// queried for, e.g. coordinates or a zip code, which may differ from it.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS observations (
+	id           INTEGER PRIMARY KEY,
+	time         INTEGER NOT NULL,
+	location     TEXT    NOT NULL COLLATE NOCASE,
+	query        TEXT    NOT NULL DEFAULT '' COLLATE NOCASE,
+	provider     TEXT    NOT NULL,
+	temperature  REAL,
+	pressure     REAL,
+	humidity     REAL,
+	wind_speed   REAL,
+	wind_degrees REAL,
+	visibility   REAL,
+	conditions   TEXT,
+	icon         TEXT,
+	UNIQUE (location, provider, time)
+);
+CREATE INDEX IF NOT EXISTS observations_location_time ON observations (location, time);
+`
+
+// historyQueryIndex is created once databases from before the query column
+// have been migrated.
+const historyQueryIndex = `CREATE INDEX IF NOT EXISTS observations_query_time ON observations (query, time)`
+
+type history struct {
+	db *sql.DB
+}
+
+// defaultHistoryPath returns $XDG_DATA_HOME/weather/history.db, falling
+// back to ~/.local/share/weather/history.db.
+func defaultHistoryPath() string {
+	return defaultDataPath("history.db")
+}
+
+// defaultDataPath returns the path of a data file under
+// $XDG_DATA_HOME/weather, falling back to ~/.local/share/weather.
+func defaultDataPath(name string) string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "weather", name)
+}
+
+func openHistory(path string) (*history, error) {
+	if errHistoryUnsupported != nil {
+		return nil, errHistoryUnsupported
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// Several weather processes may write at the same time.
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrateHistory(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &history{db: db}, nil
+}
+
+// migrateHistory adds the query column to a database created without it.
+func migrateHistory(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT count(*) FROM pragma_table_info('observations') WHERE name = 'query'").Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := db.Exec("ALTER TABLE observations ADD COLUMN query TEXT NOT NULL DEFAULT '' COLLATE NOCASE"); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec(historyQueryIndex)
+	return err
+}
+
+func (h *history) close() error {
+	return h.db.Close()
+}
+
+// record stores w fetched in the given units. Observations already in the
+// database, e.g. a cached response fetched again, are kept, only getting
+// the query when recorded without one.
+func (h *history) record(w *Weather, provider, units string) error {
+	m := toMetric(w, units)
+	_, err := h.db.Exec(`INSERT INTO observations
+		(time, location, query, provider, temperature, pressure, humidity, wind_speed, wind_degrees, visibility, conditions, icon)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (location, provider, time) DO UPDATE SET query = excluded.query WHERE query = ''`,
+		m.Time.Unix(), m.CityName, strings.TrimSpace(m.query), provider, m.Temperature, m.Pressure, m.Humidity,
+		m.WindSpeed, m.WindDegrees, m.Visibility, m.Conditions, m.Icon)
+	return err
+}
+
+// toMetric returns a copy of w converted from units to metric.
+func toMetric(w *Weather, units string) *Weather {
+	m := *w
+	if units == "imperial" {
+		m.Temperature = (w.Temperature - 32) * 5 / 9
+		m.WindSpeed = w.WindSpeed * 0.44704
+		m.WindGust = w.WindGust * 0.44704
+	}
+	return &m
+}
+
+// observation is a recorded Weather together with the provider it came
+// from.
+type observation struct {
+	Weather
+	Provider string `json:"provider"`
+}
+
+// observations returns the observations recorded between since and until,
+// oldest first. location matches both the city name and the query an
+// observation was recorded under, so that e.g. coordinates find the
+// observations fetched for them. An empty location selects all locations
+// and a zero until means no upper bound.
+func (h *history) observations(location string, since, until time.Time) ([]*observation, error) {
+	q := `SELECT time, location, provider, temperature, pressure, humidity, wind_speed, wind_degrees, visibility, conditions, icon
+		FROM observations WHERE time >= ?`
+	args := []any{since.Unix()}
+	if location != "" {
+		q += " AND (location = ? OR query = ?)"
+		args = append(args, historyLocation(location), strings.TrimSpace(location))
+	}
+	if !until.IsZero() {
+		q += " AND time < ?"
+		args = append(args, until.Unix())
+	}
+	q += " ORDER BY time, location"
+
+	rows, err := h.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*observation
+	for rows.Next() {
+		var o observation
+		var t int64
+		err := rows.Scan(&t, &o.CityName, &o.Provider, &o.Temperature, &o.Pressure, &o.Humidity, &o.WindSpeed, &o.WindDegrees, &o.Visibility, &o.Conditions, &o.Icon)
+		if err != nil {
+			return nil, err
+		}
+		o.Time = time.Unix(t, 0).UTC()
+		result = append(result, &o)
+	}
+	return result, rows.Err()
+}
+
+// historyLocation returns the name a location is recorded under: the city
+// name without a country or state suffix, e.g. "London" for "London,GB".
+// Coordinates are kept whole.
+func historyLocation(name string) string {
+	if _, _, ok := parseCoordinates(name); ok {
+		return strings.TrimSpace(name)
+	}
+	name, _, _ = strings.Cut(name, ",")
+	return strings.TrimSpace(name)
+}
+
+// fromMetric returns a copy of w converted from metric to units.
+func fromMetric(w *Weather, units string) *Weather {
+	m := *w
+	if units == "imperial" {
+		m.Temperature = w.Temperature*9/5 + 32
+		m.WindSpeed = w.WindSpeed / 0.44704
+		m.WindGust = w.WindGust / 0.44704
+	}
+	return &m
+}
+
+// parseSince parses a duration relative to now, which unlike
+// time.ParseDuration also accepts days and weeks ("7d", "2w"), or a date.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if n, ok := strings.CutSuffix(value, "d"); ok {
+		if days, err := strconv.Atoi(n); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if n, ok := strings.CutSuffix(value, "w"); ok {
+		if weeks, err := strconv.Atoi(n); err == nil {
+			return now.AddDate(0, 0, -7*weeks), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use e.g. 24h, 7d or 2024-01-02", value)
+	}
+	return now.Add(-d), nil
+}
+
+func sinceFlag(dst *time.Time) func(string) error {
+	return func(value string) error {
+		t, err := parseSince(value, time.Now())
+		if err != nil {
+			return err
+		}
+		*dst = t
+		return nil
+	}
+}
+
+func runHistory(s *session) {
+	args := s.args()
+	if len(args) == 0 {
+		exitWithUsageError("usage: weather history show|export [<city>]")
+	}
+
+	switch args[0] {
+	case "show":
+		if s.opt.since.IsZero() {
+			s.opt.since = time.Now().AddDate(0, 0, -7)
+		}
+		cities := s.citiesFrom(args[1:])
+		if s.opt.format == "csv" {
+			// One CSV for every city, in the columns of export.
+			var observations []*observation
+			for _, city := range cities {
+				observations = append(observations, s.historyObservations(city)...)
+			}
+			if err := exportHistory(os.Stdout, observations, s.opt); err != nil {
+				exitWithError("history: " + err.Error())
+			}
+			return
+		}
+		for _, city := range cities {
+			displayHistory(os.Stdout, city, s.historyObservations(city), s.opt)
+		}
+
+	case "export":
+		// Unlike show, export defaults to every location and all time.
+		city := strings.Join(args[1:], " ")
+		if alias, ok := s.cfg.string("aliases." + city); ok {
+			city = alias
+		}
+		if err := exportHistory(os.Stdout, s.historyObservations(city), s.opt); err != nil {
+			exitWithError("history: " + err.Error())
+		}
+
+	default:
+		exitWithUsageError(fmt.Sprintf("unknown history command %q", args[0]))
+	}
+}
+
+// historyObservations returns the observations of city selected by -since
+// and -until converted to -units.
+func (s *session) historyObservations(city string) []*observation {
+	observations, err := s.historyDB().observations(city, s.opt.since, s.opt.until)
+	if err != nil {
+		exitWithError("history: " + err.Error())
+	}
+	for _, o := range observations {
+		o.Weather = *fromMetric(&o.Weather, s.opt.units)
+	}
+	return observations
+}
+
+func exportHistory(w io.Writer, observations []*observation, opt *options) error {
+	switch opt.format {
+	case "json":
+		writeJSON(w, struct {
+			Units        string         `json:"units"`
+			Observations []*observation `json:"observations"`
+		}{opt.units, observations}, opt.format)
+		return nil
+
+	case "jsonl":
+		for _, o := range observations {
+			writeJSON(w, struct {
+				*observation
+				Units string `json:"units"`
+			}{o, opt.units}, opt.format)
+		}
+		return nil
+
+	default:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"time", "location", "provider", "units", "temperature", "pressure", "humidity", "wind_speed", "wind_degrees", "visibility", "conditions", "icon"})
+		for _, o := range observations {
+			cw.Write([]string{
+				o.Time.Format(time.RFC3339), o.CityName, o.Provider, opt.units,
+				formatValue("temperature", o.Temperature), formatValue("pressure", o.Pressure), formatValue("humidity", o.Humidity),
+				formatValue("wind_speed", o.WindSpeed), formatValue("wind_degrees", o.WindDegrees), formatValue("visibility", o.Visibility),
+				o.Conditions, o.Icon,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func displayHistory(w io.Writer, city string, observations []*observation, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, struct {
+			City         string         `json:"city"`
+			Units        string         `json:"units"`
+			Observations []*observation `json:"observations"`
+		}{historyLocation(city), opt.units, observations}, opt.format)
+		return
+	}
+
+	if len(observations) == 0 {
+		localFprintf(w, "no observations of %s since %s\n", historyLocation(city), opt.since.Format("2006-01-02 15:04"))
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	tw := newTable(w, 2)
+	localFprintf(tw, "%s\ttemperature\tpressure\thumidity\twind\tcondition\n", observations[0].CityName)
+	for _, o := range observations {
+		localFprintf(tw, "%s\t%.1f°%s\t%.0f hPa\t%.0f%%\t%.0f° %.1f %s\t%s\n",
+			o.Time.Local().Format("2006-01-02 15:04"), o.Temperature, temperatureSymbol, o.Pressure, o.Humidity,
+			o.WindDegrees, o.WindSpeed, windSpeedSymbol, o.Conditions)
+	}
+	tw.Flush()
+}
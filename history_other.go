@@ -0,0 +1,7 @@
+//go:build !((darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (linux && (386 || amd64 || arm || arm64 || ppc64le || riscv64 || s390x)) || (netbsd && amd64) || (openbsd && (amd64 || arm64)) || (windows && (amd64 || arm64)))
+
+package main
+
+import "errors"
+
+var errHistoryUnsupported = errors.New("history not supported on this platform")
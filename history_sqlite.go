@@ -0,0 +1,9 @@
+//go:build (darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (linux && (386 || amd64 || arm || arm64 || ppc64le || riscv64 || s390x)) || (netbsd && amd64) || (openbsd && (amd64 || arm64)) || (windows && (amd64 || arm64))
+
+package main
+
+// The history database is SQLite through a driver in pure Go, which is
+// only built for the platforms listed above, those of modernc.org/sqlite.
+import _ "modernc.org/sqlite"
+
+var errHistoryUnsupported error
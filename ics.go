@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// forecastDay summarizes the forecast entries of one local day.
+type forecastDay struct {
+	date          time.Time
+	low, high     float64
+	precipitation float64
+	probability   float64       // the highest probability of precipitation
+	wind          float64       // the highest wind speed
+	midday        ForecastEntry // the entry closest to noon
+}
+
+func forecastDays(f *Forecast) []*forecastDay {
+	var days []*forecastDay
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+		if len(days) == 0 || !days[len(days)-1].date.Equal(date) {
+			days = append(days, &forecastDay{date: date, low: e.Temperature, high: e.Temperature, midday: e})
+		}
+		d := days[len(days)-1]
+		d.low = min(d.low, e.Temperature)
+		d.high = max(d.high, e.Temperature)
+		d.precipitation += e.Precipitation
+		d.probability = max(d.probability, e.Probability)
+		d.wind = max(d.wind, e.WindSpeed)
+		if absDuration(t.Sub(d.noon(t))) < absDuration(localTime(d.midday.Time, f.TimeZone).Sub(d.noon(t))) {
+			d.midday = e
+		}
+	}
+	return days
+}
+
+func (d *forecastDay) noon(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, t.Location())
+}
+
+// summary returns e.g. "🌧️ 4–9°, 6mm rain".
+func (d *forecastDay) summary() string {
+	s := fmt.Sprintf("%s %.0f–%.0f°", weatherIconIdToEmoji(d.midday.Icon), d.low, d.high)
+	switch {
+	case d.precipitation >= 1:
+		s += fmt.Sprintf(", %.0fmm rain", d.precipitation)
+	case d.precipitation > 0:
+		s += fmt.Sprintf(", %.1fmm rain", d.precipitation)
+	}
+	return s
+}
+
+// displayForecastICS writes the forecast as an iCalendar file with an
+// all-day event for each day.
+// https://datatracker.ietf.org/doc/html/rfc5545
+func displayForecastICS(w io.Writer, f *Forecast, opt *options, now time.Time) {
+	temperatureSymbol, _ := unitSymbols(opt.units)
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//jtlehtinen//weather//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icsText(f.CityName+" forecast"),
+	}
+	for _, d := range forecastDays(f) {
+		description := fmt.Sprintf("%s in %s, %.0f–%.0f°%s", d.midday.Conditions, f.CityName, d.low, d.high, temperatureSymbol)
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%s@weather", d.date.Format("20060102"), strings.ToLower(strings.ReplaceAll(f.CityName, " ", "-"))),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+d.date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+d.date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icsText(d.summary()),
+			"DESCRIPTION:"+icsText(description),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		io.WriteString(w, icsFold(line)+"\r\n")
+	}
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icsText(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// icsFold folds a content line longer than 75 octets into continuation
+// lines starting with a space, without splitting UTF-8 sequences.
+func icsFold(line string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > 75 {
+			sb.WriteString("\r\n ")
+			n = 1
+		}
+		sb.WriteRune(r)
+		n += size
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Points are written in the InfluxDB line protocol, which VictoriaMetrics
+// and other time series databases accept as well. Values are always in
+// metric units so that series do not mix units.
+// https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+
+func influxFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.influxURL, "influx-url", "", "push the data in line protocol to this write endpoint, e.g. http://localhost:8086/api/v2/write?org=home&bucket=weather")
+	fs.StringVar(&opt.influxToken, "influx-token", "", "API token of the -influx-url endpoint")
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// influxPoint is a point with its fields in insertion order.
+type influxPoint struct {
+	measurement string
+	tags        [][2]string
+	fields      []string
+	time        time.Time
+}
+
+func (p *influxPoint) float(key string, value float64) {
+	p.fields = append(p.fields, influxTagEscaper.Replace(key)+"="+formatFloat(value))
+}
+
+func (p *influxPoint) int(key string, value int) {
+	p.fields = append(p.fields, fmt.Sprintf("%s=%di", influxTagEscaper.Replace(key), value))
+}
+
+func (p *influxPoint) string(key, value string) {
+	p.fields = append(p.fields, influxTagEscaper.Replace(key)+`="`+influxStringEscaper.Replace(value)+`"`)
+}
+
+func (p *influxPoint) String() string {
+	var sb strings.Builder
+	sb.WriteString(influxMeasurementEscaper.Replace(p.measurement))
+	for _, tag := range p.tags {
+		if tag[1] != "" {
+			sb.WriteString("," + influxTagEscaper.Replace(tag[0]) + "=" + influxTagEscaper.Replace(tag[1]))
+		}
+	}
+	sb.WriteString(" " + strings.Join(p.fields, ","))
+	fmt.Fprintf(&sb, " %d\n", p.time.UnixNano())
+	return sb.String()
+}
+
+func influxWeather(wt *Weather, opt *options) string {
+	m := toMetric(wt, opt.units)
+	p := &influxPoint{measurement: "weather", tags: [][2]string{{"location", m.CityName}, {"provider", opt.provider}}, time: m.Time}
+	p.float("temperature", m.Temperature)
+	p.float("pressure", m.Pressure)
+	p.float("humidity", m.Humidity)
+	p.float("wind_speed", m.WindSpeed)
+	p.float("wind_degrees", m.WindDegrees)
+	p.float("visibility", m.Visibility)
+	p.string("conditions", m.Conditions)
+	return p.String()
+}
+
+func influxForecast(f *Forecast, opt *options) string {
+	var sb strings.Builder
+	for _, e := range f.Entries {
+		temperature, windSpeed := e.Temperature, e.WindSpeed
+		if opt.units == "imperial" {
+			m := toMetric(&Weather{Temperature: temperature, WindSpeed: windSpeed}, opt.units)
+			temperature, windSpeed = m.Temperature, m.WindSpeed
+		}
+		p := &influxPoint{measurement: "forecast", tags: [][2]string{{"location", f.CityName}, {"provider", opt.provider}}, time: e.Time}
+		p.float("temperature", temperature)
+		p.float("pressure", e.Pressure)
+		p.float("humidity", e.Humidity)
+		p.float("wind_speed", windSpeed)
+		p.float("wind_degrees", e.WindDegrees)
+		p.float("precipitation", e.Precipitation)
+		p.float("precipitation_probability", e.Probability)
+		p.string("conditions", e.Conditions)
+		sb.WriteString(p.String())
+	}
+	return sb.String()
+}
+
+func influxAir(a *AirQuality, opt *options, now time.Time) string {
+	p := &influxPoint{measurement: "air_quality", tags: [][2]string{{"location", a.CityName}, {"provider", opt.provider}}, time: now}
+	p.int("aqi", a.Index)
+	names := make([]string, 0, len(a.Components))
+	for name := range a.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.float(name, a.Components[name])
+	}
+	return p.String()
+}
+
+// pushInflux writes lines to the -influx-url endpoint when one is given.
+// A failed push is reported but does not stop the command.
+func (s *session) pushInflux(lines string) {
+	if s.opt.influxURL == "" {
+		return
+	}
+	if err := pushInflux(s.opt.influxURL, s.opt.influxToken, lines); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: influx: %s\n", redact(err.Error()))
+	}
+}
+
+func pushInflux(endpoint, token, lines string) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(lines))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
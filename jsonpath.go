@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// -jsonpath selects values of the JSON output, so that scripts need no jq:
+// ".temperature", ".entries[0].temperature" or ".entries[].time" for
+// every element of an array. As in jq, missing values are null.
+
+func jsonPathFlag(fs *flag.FlagSet, opt *options) {
+	fs.Func("jsonpath", "print only the values at this path of the JSON output, e.g. .entries[0].temperature", func(value string) error {
+		p, err := parseJSONPath(value)
+		if err != nil {
+			return err
+		}
+		opt.jsonPath = p
+		return nil
+	})
+}
+
+// jsonStep is a step of a path: an object key, an array index, negative
+// from the end, or every element of an array.
+type jsonStep struct {
+	kind  jsonStepKind
+	key   string
+	index int
+}
+
+type jsonStepKind int
+
+const (
+	jsonKey jsonStepKind = iota
+	jsonIndex
+	jsonAll
+)
+
+// jsonPath is a parsed -jsonpath.
+type jsonPath struct {
+	steps []jsonStep
+}
+
+// outputJSONPath is the -jsonpath of the running command, applied by
+// writeJSON, or nil.
+var outputJSONPath *jsonPath
+
+// parseJSONPath parses a path such as ".entries[0].temperature". "."
+// selects the whole output.
+func parseJSONPath(path string) (*jsonPath, error) {
+	invalid := fmt.Errorf("invalid path %q, expected e.g. .temperature or .entries[0].time", path)
+	if !strings.HasPrefix(path, ".") {
+		return nil, invalid
+	}
+	p := &jsonPath{}
+	rest := path
+	if rest == "." {
+		rest = ""
+	}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 && !strings.HasPrefix(rest, "[") {
+				return nil, invalid
+			}
+			if end > 0 {
+				p.steps = append(p.steps, jsonStep{kind: jsonKey, key: rest[:end]})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, invalid
+			}
+			if inner := rest[1:end]; inner == "" {
+				p.steps = append(p.steps, jsonStep{kind: jsonAll})
+			} else {
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, invalid
+				}
+				p.steps = append(p.steps, jsonStep{kind: jsonIndex, index: i})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, invalid
+		}
+	}
+	return p, nil
+}
+
+// selectJSON returns the values at steps in v, a decoded JSON value.
+func selectJSON(v any, steps []jsonStep) []any {
+	if len(steps) == 0 {
+		return []any{v}
+	}
+	step, rest := steps[0], steps[1:]
+	switch step.kind {
+	case jsonAll:
+		list, _ := v.([]any)
+		var values []any
+		for _, e := range list {
+			values = append(values, selectJSON(e, rest)...)
+		}
+		return values
+	case jsonIndex:
+		list, _ := v.([]any)
+		i := step.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return selectJSON(nil, rest)
+		}
+		return selectJSON(list[i], rest)
+	default:
+		m, _ := v.(map[string]any)
+		return selectJSON(m[step.key], rest)
+	}
+}
+
+// selectJSONPath returns the values at p in the JSON encoding of v, keeping
+// numbers as they are encoded.
+func selectJSONPath(v any, p *jsonPath) ([]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return selectJSON(decoded, p.steps), nil
+}
@@ -0,0 +1,12 @@
+package main
+
+import "errors"
+
+// Service and account names under which the API key is stored in the OS
+// credential store.
+const (
+	keyringService = "weather"
+	keyringAccount = "openweather"
+)
+
+var errKeyringUnsupported = errors.New("no supported credential store on this platform")
@@ -0,0 +1,11 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package main
+
+func keyringGet() (string, error) {
+	return "", errKeyringUnsupported
+}
+
+func keyringSet(key string) error {
+	return errKeyringUnsupported
+}
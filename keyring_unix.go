@@ -0,0 +1,25 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Secret Service (GNOME Keyring, KWallet) is accessed through secret-tool
+// from libsecret.
+
+func keyringGet() (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func keyringSet(key string) error {
+	cmd := exec.Command("secret-tool", "store", "--label=weather OpenWeather API key", "service", keyringService, "account", keyringAccount)
+	cmd.Stdin = strings.NewReader(key)
+	return cmd.Run()
+}
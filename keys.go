@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// keyPool hands out API keys for a provider. In round-robin mode every
+// request uses the next key; otherwise the same key is used until the
+// provider rate limits it.
+type keyPool struct {
+	mu         sync.Mutex
+	keys       []string
+	next       int
+	roundRobin bool
+}
+
+func parseKeys(value string) []string {
+	var keys []string
+	for _, k := range strings.Split(value, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func newKeyPool(keys []string, roundRobin bool) *keyPool {
+	return &keyPool{keys: keys, roundRobin: roundRobin}
+}
+
+func (p *keyPool) size() int {
+	return len(p.keys)
+}
+
+// pick returns the key to use for the next request.
+func (p *keyPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := p.keys[p.next]
+	if p.roundRobin {
+		p.next = (p.next + 1) % len(p.keys)
+	}
+	return key
+}
+
+// rateLimited moves past key after the provider rejected it with 429.
+func (p *keyPool) rateLimited(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.roundRobin && p.keys[p.next] == key {
+		p.next = (p.next + 1) % len(p.keys)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// weather laundry estimates how long laundry hung outdoors now takes to
+// dry. Drying is modeled as evaporation, which grows with the vapor
+// pressure deficit of the air and with the wind.
+
+// dryingRate is the share of the laundry drying in an hour per kPa of vapor
+// pressure deficit at no wind, set so that laundry dries in about three
+// hours at 20°C, 50% humidity and a 2 m/s wind.
+const dryingRate = 0.1426
+
+// laundryOutlook is the estimate for a location. Dry is set when the
+// laundry dries by Done; Rain is set when rain is expected before that.
+type laundryOutlook struct {
+	City  string     `json:"city"`
+	Dry   bool       `json:"dry"`
+	Hours float64    `json:"hours,omitempty"`
+	Done  *time.Time `json:"done,omitempty"`
+	Rain  *time.Time `json:"rain,omitempty"`
+
+	hours    int
+	timeZone int
+}
+
+// dryingPerHour returns the share of laundry drying in an hour at a
+// temperature in °C, a relative humidity in % and a wind speed in m/s.
+func dryingPerHour(temperature, humidity, windSpeed float64) float64 {
+	saturation := 0.6108 * math.Exp(17.27*temperature/(temperature+237.3)) // kPa
+	deficit := saturation * (1 - humidity/100)
+	return dryingRate * deficit * (1 + 0.5*windSpeed)
+}
+
+// outlookLaundry steps through f an hour at a time from now until the
+// laundry is dry, rain is expected or hours have passed.
+func outlookLaundry(f *Forecast, hours int, now time.Time, units string) *laundryOutlook {
+	o := &laundryOutlook{City: f.CityName, hours: hours, timeZone: f.TimeZone}
+	dried := 0.0
+	for h := 0; h < hours; h++ {
+		t := now.Add(time.Duration(h) * time.Hour)
+		// The period covering t, or the first one when t precedes them.
+		var entry *ForecastEntry
+		for i, e := range f.Entries {
+			if t.Before(e.Time.Add(forecastPeriod)) {
+				entry = &f.Entries[i]
+				break
+			}
+		}
+		if entry == nil {
+			// Beyond the forecast.
+			return o
+		}
+		if entry.Probability >= rainProbability && entry.Precipitation > 0 {
+			rain := entry.Time
+			if rain.Before(now) {
+				rain = now
+			}
+			o.Rain = &rain
+			return o
+		}
+		m := toMetric(&Weather{Temperature: entry.Temperature, WindSpeed: entry.WindSpeed}, units)
+		rate := dryingPerHour(m.Temperature, entry.Humidity, m.WindSpeed)
+		if dried+rate >= 1 {
+			o.Hours = float64(h) + (1-dried)/rate
+			done := now.Add(time.Duration(o.Hours * float64(time.Hour))).Truncate(time.Minute)
+			o.Dry, o.Done = true, &done
+			return o
+		}
+		dried += rate
+	}
+	return o
+}
+
+// runLaundry estimates the drying time at each location within -hours.
+func runLaundry(s *session) {
+	if s.opt.hours < 1 || s.opt.hours > 120 {
+		exitWithUsageError("hours must be between 1 and 120")
+	}
+
+	cities := s.cities()
+	for _, city := range cities {
+		f, err := s.provider().forecast(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		displayLaundry(os.Stdout, outlookLaundry(f, s.opt.hours, time.Now(), s.opt.units), len(cities) > 1, s.opt)
+	}
+}
+
+// displayLaundry writes e.g. "Dries outdoors in about 3h, by 14:30",
+// prefixed with the location when withCity is set.
+func displayLaundry(w io.Writer, o *laundryOutlook, withCity bool, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, o, opt.format)
+		return
+	}
+
+	if withCity {
+		localFprintf(w, "%s: ", o.City)
+	}
+	at := func(t time.Time) string {
+		t = localTime(t, o.timeZone)
+		if t.YearDay() != localTime(time.Now(), o.timeZone).YearDay() {
+			return t.Format("Mon 15:04")
+		}
+		return t.Format("15:04")
+	}
+	switch {
+	case o.Dry && o.Hours < 1:
+		localFprintf(w, "Dries outdoors in under an hour, by %s\n", at(*o.Done))
+	case o.Dry:
+		localFprintf(w, "Dries outdoors in about %.0fh, by %s\n", math.Round(o.Hours), at(*o.Done))
+	case o.Rain != nil && !o.Rain.After(time.Now()):
+		fmt.Fprintln(w, "Rain now, dry indoors")
+	case o.Rain != nil:
+		localFprintf(w, "Rain expected at %s before it dries, dry indoors\n", at(*o.Rain))
+	default:
+		localFprintf(w, "Does not dry outdoors in the next %dh, dry indoors\n", o.hours)
+	}
+}
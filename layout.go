@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// The forecast and the table of locations fit their layout to the width of
+// the terminal: on a wide one more metrics get columns of their own, on a
+// narrow one the values are stacked on lines of their own rather than
+// wrapped. Output to files and pipes keeps the normal layout.
+
+const (
+	wideLayoutWidth   = 110
+	narrowLayoutWidth = 60
+)
+
+var layoutValues = []string{"auto", "wide", "normal", "narrow"}
+
+func layoutFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("layout", "text layout ("+strings.Join(layoutValues, "|")+") (default auto, by the width of the terminal)", enumFlag(&opt.layout, "layout", layoutValues))
+}
+
+// terminalStdout returns the stdout of the process, also while the output
+// is collected for the pager.
+func terminalStdout() *os.File {
+	if watchStdout != nil {
+		return watchStdout
+	}
+	if pendingPage != nil {
+		return pendingPage.stdout
+	}
+	return os.Stdout
+}
+
+// displayLayout returns the layout of -layout, choosing by the width of
+// the terminal with auto.
+func displayLayout(opt *options) string {
+	if opt.layout != "auto" && opt.layout != "" {
+		return opt.layout
+	}
+	width, _, err := term.GetSize(int(terminalStdout().Fd()))
+	switch {
+	case err != nil || width <= 0:
+		return "normal"
+	case width >= wideLayoutWidth:
+		return "wide"
+	case width < narrowLayoutWidth:
+		return "narrow"
+	}
+	return "normal"
+}
+
+// displayForecastWide writes the forecast of each day as a table with the
+// wind, precipitation, humidity, pressure and clouds of every period.
+func displayForecastWide(w io.Writer, f *Forecast, opt *options) {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	localFprintf(w, "%s forecast%s\n", f.CityName, staleNote(f.CachedAt))
+	localFprintf(w, "========================\n")
+
+	tw := newTable(w, 2)
+	day := ""
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		if d := localDate(t, "Mon Jan _2"); d != day {
+			if day != "" {
+				fmt.Fprintln(tw)
+			}
+			localFprintf(tw, "%s\n", d)
+			fmt.Fprintln(tw, "  TIME\tTEMP\tCONDITIONS\tWIND\tGUSTS\tPRECIPITATION\tHUMIDITY\tPRESSURE\tCLOUDS")
+			day = d
+		}
+		localFprintf(tw, "  %s\t%.0f°%s\t%s %s\t%.0f° %.1f %s\t%.1f %s\t%.1f mm (%.0f%%)\t%.0f%%\t%.0f hPa\t%.0f%%\n",
+			t.Format("15:04"), e.Temperature, temperatureSymbol, weatherIconIdToEmoji(e.Icon), e.Conditions,
+			e.WindDegrees, e.WindSpeed, windSpeedSymbol, e.WindGust, windSpeedSymbol,
+			e.Precipitation, e.Probability*100, e.Humidity, e.Pressure, e.Clouds)
+	}
+	tw.Flush()
+}
+
+// displayForecastNarrow writes each period of the forecast with the
+// conditions, and with -v the wind and precipitation, on lines below the
+// time and the temperature.
+func displayForecastNarrow(w io.Writer, f *Forecast, opt *options) {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	localFprintf(w, "%s forecast%s\n", f.CityName, staleNote(f.CachedAt))
+	localFprintf(w, "========================\n")
+
+	day := ""
+	for _, e := range f.Entries {
+		t := localTime(e.Time, f.TimeZone)
+		if d := localDate(t, "Mon Jan _2"); d != day {
+			if day != "" {
+				fmt.Fprintln(w)
+			}
+			localFprintf(w, "%s\n", d)
+			day = d
+		}
+		localFprintf(w, "  %s %.0f°%s %s\n", t.Format("15:04"), e.Temperature, temperatureSymbol, weatherIconIdToEmoji(e.Icon))
+		localFprintf(w, "    %s\n", e.Conditions)
+		if opt.verbose > 0 {
+			localFprintf(w, "    wind %.0f° %.1f %s\n", e.WindDegrees, e.WindSpeed, windSpeedSymbol)
+			localFprintf(w, "    precipitation %.1f mm (%.0f%%)\n", e.Precipitation, e.Probability*100)
+		}
+	}
+}
+
+// displayBatchWide writes the table of locations with the gusts and the
+// visibility too.
+func displayBatchWide(w io.Writer, results []batchResult, opt *options) {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "LOCATION\tTEMP\tCONDITIONS\tHUMIDITY\tWIND\tGUSTS\tPRESSURE\tVISIBILITY\tTIME")
+	for _, r := range results {
+		if r.Weather == nil {
+			continue
+		}
+		localFprintf(tw, "%s\t%.0f°%s\t%s %s\t%.0f%%\t%.0f° %.1f %s\t%.1f %s\t%.0f hPa\t%.1f km\t%s%s\n",
+			r.CityName, r.Temperature, temperatureSymbol, weatherIconIdToEmoji(r.Icon), r.Conditions,
+			r.Humidity, r.WindDegrees, r.WindSpeed, windSpeedSymbol, r.WindGust, windSpeedSymbol,
+			r.Pressure, r.Visibility/1000, localTime(r.Time, r.TimeZone).Format("15:04"), staleNote(r.CachedAt))
+	}
+	tw.Flush()
+}
+
+// displayBatchNarrow writes the locations of the table one below another,
+// each with its values stacked.
+func displayBatchNarrow(w io.Writer, results []batchResult, opt *options) {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	first := true
+	for _, r := range results {
+		if r.Weather == nil {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		localFprintf(w, "%s %s%s\n", r.CityName, localTime(r.Time, r.TimeZone).Format("15:04"), staleNote(r.CachedAt))
+		localFprintf(w, "  %.0f°%s %s %s\n", r.Temperature, temperatureSymbol, weatherIconIdToEmoji(r.Icon), r.Conditions)
+		localFprintf(w, "  humidity %.0f%%\n", r.Humidity)
+		localFprintf(w, "  wind %.1f %s\n", r.WindSpeed, windSpeedSymbol)
+		localFprintf(w, "  pressure %.0f hPa\n", r.Pressure)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// The text output writes numbers and dates in the locale of the user: 1,5
+// °C and 2.5. in Finland, 1.5 °C and May 2 in the United States. The
+// locale is -locale or, as in C programs, LC_ALL, LC_NUMERIC for numbers,
+// LC_TIME for dates and then LANG. The C and POSIX locales, or none, keep
+// the plain output. Machine-readable formats are never localized.
+
+// numberPrinter formats numbers in the locale, or is nil.
+var numberPrinter *message.Printer
+
+// dayMonthLayout is the layout of a day of the month in the locale, e.g.
+// "2.1.", or "" to keep the layouts of the output.
+var dayMonthLayout string
+
+// dayMonthLayouts are the layouts of a day of the month by language, with
+// regional English apart.
+var dayMonthLayouts = map[string]string{
+	"en-US": "Jan 2",
+	"en":    "2 Jan",
+	"fi":    "2.1.",
+	"de":    "2.1.",
+	"da":    "2.1.",
+	"nb":    "2.1.",
+	"nn":    "2.1.",
+	"no":    "2.1.",
+	"cs":    "2.1.",
+	"sk":    "2.1.",
+	"pl":    "2.1.",
+	"ru":    "2.1.",
+	"uk":    "2.1.",
+	"et":    "2.1.",
+	"tr":    "2.1.",
+	"sv":    "2/1",
+	"fr":    "2/1",
+	"es":    "2/1",
+	"it":    "2/1",
+	"pt":    "2/1",
+	"el":    "2/1",
+	"nl":    "2-1",
+	"ja":    "1/2",
+	"zh":    "1/2",
+	"ko":    "1/2",
+}
+
+// localeFromEnv returns the locale of the environment for category, e.g.
+// LC_NUMERIC.
+func localeFromEnv(category string) string {
+	for _, name := range []string{"LC_ALL", category, "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseLocale parses a POSIX locale such as fi_FI.UTF-8 or a language tag
+// such as fi-FI. ok is false for the C and POSIX locales.
+func parseLocale(name string) (tag language.Tag, ok bool) {
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name == "C" || name == "POSIX" {
+		return language.Und, false
+	}
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	return tag, err == nil
+}
+
+// setLocale sets the locale of the output, from the environment unless
+// name is given.
+func setLocale(name string) {
+	numbers, dates := name, name
+	if name == "" {
+		numbers, dates = localeFromEnv("LC_NUMERIC"), localeFromEnv("LC_TIME")
+	}
+	numberPrinter, dayMonthLayout = nil, ""
+	if tag, ok := parseLocale(numbers); ok {
+		numberPrinter = message.NewPrinter(tag)
+	}
+	if tag, ok := parseLocale(dates); ok {
+		base, _ := tag.Base()
+		region, _ := tag.Region()
+		switch {
+		case base.String() == "en" && (region.String() == "US" || region.String() == "ZZ"):
+			dayMonthLayout = dayMonthLayouts["en-US"]
+		default:
+			dayMonthLayout = dayMonthLayouts[base.String()]
+		}
+	}
+}
+
+// localFprintf is fmt.Fprintf writing the numbers in the locale.
+func localFprintf(w io.Writer, format string, a ...any) (int, error) {
+	if numberPrinter == nil {
+		return fmt.Fprintf(w, format, a...)
+	}
+	return numberPrinter.Fprintf(w, format, a...)
+}
+
+// localSprintf is fmt.Sprintf writing the numbers in the locale.
+func localSprintf(format string, a ...any) string {
+	if numberPrinter == nil {
+		return fmt.Sprintf(format, a...)
+	}
+	return numberPrinter.Sprintf(format, a...)
+}
+
+// localDate formats t with layout, writing the day of the month, e.g. the
+// "Jan 2" of "Mon Jan 2", in the locale.
+func localDate(t time.Time, layout string) string {
+	if dayMonthLayout != "" {
+		for _, dayMonth := range []string{"January 2", "Jan _2", "Jan 2", "2 January", "2 Jan"} {
+			if strings.Contains(layout, dayMonth) {
+				layout = strings.Replace(layout, dayMonth, dayMonthLayout, 1)
+				break
+			}
+		}
+	}
+	return t.Format(layout)
+}
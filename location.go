@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+func locationFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.first, "first", false, "use the best match when a city name matches several locations")
+	fs.StringVar(&opt.country, "country", "", "only consider locations in this country, e.g. FI or US")
+}
+
+var coordinatesRegexp = regexp.MustCompile(`^(-?[0-9]+(?:\.[0-9]+)?)\s*[, ]\s*(-?[0-9]+(?:\.[0-9]+)?)$`)
+
+// parseCoordinates parses a location given as "lat,lon", e.g.
+// "60.1699,24.9384".
+func parseCoordinates(s string) (lat, lon float64, ok bool) {
+	m := coordinatesRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, 0, false
+	}
+	lat, _ = strconv.ParseFloat(m[1], 64)
+	lon, _ = strconv.ParseFloat(m[2], 64)
+	return lat, lon, lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+func formatCoordinates(lat, lon float64) string {
+	return strconv.FormatFloat(lat, 'f', 4, 64) + "," + strconv.FormatFloat(lon, 'f', 4, 64)
+}
+
+// describe returns the name, state and country of a location, e.g.
+// "Springfield, Illinois, US".
+func (l *Location) describe() string {
+	parts := []string{l.Name}
+	if l.State != "" {
+		parts = append(parts, l.State)
+	}
+	return strings.Join(append(parts, l.Country), ", ")
+}
+
+// disambiguate resolves a city name given on the command line that matches
+// several locations, e.g. "Springfield". On a terminal the user picks one;
+// otherwise -first or -country must narrow it down. The chosen location is
+// returned as coordinates, unambiguous names as they are.
+func (s *session) disambiguate(city string) string {
+	if _, _, ok := parseCoordinates(city); ok || s.opt.dryRun || s.opt.raw || s.opt.fromDaemon {
+		return city
+	}
+	locations, err := s.provider().geocode(city, 5)
+	if err != nil {
+		// The request for the weather itself reports the error.
+		return city
+	}
+
+	var matches []Location
+	seen := map[string]bool{}
+	for _, l := range locations {
+		if s.opt.country != "" && !strings.EqualFold(l.Country, s.opt.country) {
+			continue
+		}
+		// Geocoding can return the same place more than once, e.g. a city
+		// and its center.
+		if !seen[l.describe()] {
+			seen[l.describe()] = true
+			matches = append(matches, l)
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		exitWithStatus(exitNotFound, fmt.Sprintf("location %q not found in %s", city, strings.ToUpper(s.opt.country)))
+	case len(matches) == 1 && s.opt.country == "":
+		return city
+	case len(matches) == 1 || s.opt.first:
+		return formatCoordinates(matches[0].Lat, matches[0].Lon)
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		var names []string
+		for _, l := range matches {
+			names = append(names, l.describe())
+		}
+		exitWithUsageError(fmt.Sprintf("%q matches several locations: %s; use -country, -first or \"lat,lon\"", city, strings.Join(names, "; ")))
+	}
+	l := pickLocation(city, matches)
+	return formatCoordinates(l.Lat, l.Lon)
+}
+
+// pickLocation asks the user to choose one of the matches of city.
+func pickLocation(city string, matches []Location) *Location {
+	fmt.Fprintf(os.Stderr, "%q matches several locations:\n", city)
+	for i, l := range matches {
+		fmt.Fprintf(os.Stderr, "  %d) %s (%s)\n", i+1, l.describe(), formatCoordinates(l.Lat, l.Lon))
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "choose 1-%d: ", len(matches))
+		if !in.Scan() {
+			fmt.Fprintln(os.Stderr)
+			exitWithError("no location chosen")
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(in.Text())); err == nil && n >= 1 && n <= len(matches) {
+			return &matches[n-1]
+		}
+	}
+}
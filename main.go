@@ -1,214 +1,1389 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const BASE_URL = "https://api.openweathermap.org/data/2.5/weather"
-
 type options struct {
-	apiKey  string
-	units   string
-	verbose bool
-	city    string
+	apiKey            string
+	keyRotation       string
+	units             string
+	lang              string
+	timeout           time.Duration
+	verbose           int // verbosity level of -v, -vv and -vvv
+	configPath        string
+	profile           string
+	provider          string
+	format            string
+	debugHTTP         bool
+	debugHTTPDump     bool
+	dryRun            bool
+	raw               bool
+	when              string
+	rankBy            string
+	rankAll           bool
+	gpxFile           string
+	speed             float64
+	depart            time.Time
+	tripFrom          time.Time
+	tripTo            time.Time
+	commuteHome       string
+	commuteWork       string
+	commuteMorning    string
+	commuteEvening    string
+	activity          string
+	frostThreshold    string
+	degreeBase        *float64
+	advice            bool
+	coldTolerance     string
+	uv                bool
+	vsNormal          bool
+	mapLayer          string
+	mapZoom           int
+	baseMap           string
+	graphics          string
+	open              bool
+	openSite          string
+	speak             bool
+	a11yMode          string
+	a11y              bool
+	noPager           bool
+	layout            string
+	locale            string
+	photoMorning      bool
+	sunEvent          string
+	sunOffset         time.Duration
+	jsonPath          *jsonPath
+	precision         precision
+	sport             string
+	sportWind         string
+	sportGustFactor   float64
+	sportDirections   string
+	scoreWeights      string
+	days              int
+	next              time.Duration
+	between           string
+	hours             int
+	cacheDir          string
+	cacheTTL          time.Duration
+	noCache           bool
+	offline           bool
+	staleFallback     bool
+	history           bool
+	historyPath       string
+	since             time.Time
+	until             time.Time
+	watch             bool
+	interval          time.Duration
+	socketPath        string
+	listen            string
+	grpcListen        string
+	metricsListen     string
+	influxURL         string
+	influxToken       string
+	graphiteAddr      string
+	statsdAddr        string
+	metricsPrefix     string
+	metricsTags       string
+	webhooks          string
+	webhookSecret     string
+	rules             []string
+	first             bool
+	batchFile         string
+	output            string
+	logFile           string
+	parallel          int
+	maxRequests       int
+	ipv4              bool
+	ipv6              bool
+	dnsTTL            time.Duration
+	http2             bool
+	maxIdleConns      int
+	idleTimeout       time.Duration
+	stats             bool
+	country           string
+	breakerFailures   int
+	breakerCooldown   time.Duration
+	notify            bool
+	slackWebhook      string
+	discordWebhook    string
+	telegramToken     string
+	telegramChats     string
+	summaryTime       string
+	subscriptionsPath string
+	smtpHost          string
+	smtpPort          int
+	smtpUser          string
+	smtpPassword      string
+	smtpFrom          string
+	smtpTo            string
+	schedule          bool
+	twilioAccount     string
+	twilioToken       string
+	twilioFrom        string
+	smsTo             string
+	fromDaemon        bool
+	docsDir           string
+	checkOnly         bool
+	force             bool
 }
 
-func exitWithError(errorMessage string) {
-	fmt.Fprintf(os.Stderr, "ERROR: %s\n", errorMessage)
-	os.Exit(1)
+var (
+	unitsValues    = []string{"metric", "imperial"}
+	providerValues = []string{"openweather"}
+	formatValues   = []string{"text", "json", "jsonl", "influx"}
+
+	keyRotationValues = []string{"on-429", "round-robin"}
+
+	nowFormatValues      = []string{"text", "json", "jsonl", "influx", "table", "csv"}
+	forecastFormatValues = []string{"text", "json", "jsonl", "influx", "chart", "ics"}
+	historyFormatValues  = []string{"text", "json", "csv", "jsonl"}
+	trendFormatValues    = []string{"text", "json", "jsonl"}
+	checkFormatValues    = []string{"text", "json", "jsonl"}
+	searchFormatValues   = []string{"text", "json", "jsonl"}
+	rainFormatValues     = []string{"text", "json", "jsonl"}
+	compareFormatValues  = []string{"text", "json"}
+	rankFormatValues     = []string{"text", "json", "jsonl"}
+	routeFormatValues    = []string{"text", "json"}
+	tripFormatValues     = []string{"text", "json"}
+	commuteFormatValues  = []string{"text", "json"}
+	scoreFormatValues    = []string{"text", "json", "jsonl"}
+	laundryFormatValues  = []string{"text", "json"}
+	frostFormatValues    = []string{"text", "json", "jsonl"}
+	degreeFormatValues   = []string{"text", "json"}
+	starsFormatValues    = []string{"text", "json", "jsonl"}
+	sunFormatValues      = []string{"text", "json", "jsonl", "unix"}
+	photoFormatValues    = []string{"text", "json"}
+	sportsFormatValues   = []string{"text", "json", "jsonl"}
+	reportFormatValues   = []string{"table", "csv", "json", "jsonl"}
+
+	// Languages supported by OpenWeather: https://openweathermap.org/current#multi
+	langValues = []string{
+		"af", "al", "ar", "az", "bg", "ca", "cz", "da", "de", "el", "en", "eu", "fa", "fi", "fr", "gl",
+		"he", "hi", "hr", "hu", "id", "it", "ja", "kr", "la", "lt", "mk", "no", "nl", "pl", "pt", "pt_br",
+		"ro", "ru", "sv", "se", "sk", "sl", "sp", "es", "sr", "th", "tr", "ua", "uk", "vi", "zh_cn", "zh_tw", "zu",
+	}
+)
+
+// checkEnum reports an error listing the allowed values when value is not
+// one of them, suggesting the closest one for a typo.
+func checkEnum(what, value string, allowed []string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	quoted := make([]string, len(allowed))
+	for i, a := range allowed {
+		quoted[i] = "'" + a + "'"
+	}
+	suggestion := ""
+	if s, ok := closestValue(value, allowed); ok {
+		suggestion = fmt.Sprintf(", did you mean '%s'?", s)
+	}
+	if len(quoted) == 1 {
+		return fmt.Errorf("%s must be %s%s", what, quoted[0], suggestion)
+	}
+	last := len(quoted) - 1
+	return fmt.Errorf("%s must be %s or %s%s", what, strings.Join(quoted[:last], ", "), quoted[last], suggestion)
+}
+
+// closestValue returns the allowed value closest to value, if it is close
+// enough to be a typo of it: differing in case, or by at most half of its
+// letters for values longer than two letters. Ties go to the first value.
+func closestValue(value string, allowed []string) (string, bool) {
+	best, bestDistance := "", -1
+	for _, a := range allowed {
+		d := levenshtein(strings.ToLower(value), strings.ToLower(a))
+		if d > 0 && (len(a) <= 2 || d > len(a)/2) {
+			continue
+		}
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = a, d
+		}
+	}
+	return best, bestDistance >= 0
 }
 
-type Weather struct {
-	CityName    string
-	TimeZone    int
-	Visibility  float64
-	Temperature float64
-	Pressure    float64
-	Humidity    float64
-	WindSpeed   float64
-	WindDegrees float64
-	Conditions  string
-	Icon        string
+func enumFlag(dst *string, what string, allowed []string) func(string) error {
+	return func(value string) error {
+		if err := checkEnum(what, value, allowed); err != nil {
+			return err
+		}
+		*dst = value
+		return nil
+	}
 }
 
-func makeRequestURL(cityName, units, apiKey string) string {
-	cityName = url.QueryEscape(cityName)
-	apiKey = url.QueryEscape(apiKey)
-	return fmt.Sprintf("%s?q=%s&units=%s&appid=%s", BASE_URL, cityName, units, apiKey)
+// verbosityFlag is a -v style boolean flag that raises the verbosity level
+// by step each time it is given. Like any boolean, 1 means true, so that
+// -vv=1 or WEATHER_VV=1 is the same as -vv; other numbers, e.g. -v=2 or
+// WEATHER_V=2, set the level directly.
+type verbosityFlag struct {
+	level *int
+	step  int
 }
 
-func fetchWeather(apiKey, cityName, units string) (*Weather, error) {
-	u := makeRequestURL(cityName, units, apiKey)
+func (v verbosityFlag) IsBoolFlag() bool { return true }
 
-	resp, err := http.Get(u)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
-	}
-
-	// API docs: https://openweathermap.org/current
-	type response struct {
-		Weather []struct {
-			Main        string `json:"main"`
-			Description string `json:"description"`
-			Icon        string `json:"icon"`
-		} `json:"weather"`
-		Main struct {
-			Temperature float64 `json:"temp"`
-			Pressure    float64 `json:"pressure"`
-			Humidity    float64 `json:"humidity"`
-		} `json:"main"`
-		Wind struct {
-			Speed   float64 `json:"speed"`
-			Degrees float64 `json:"deg"`
-		} `json:"wind"`
-		Name       string  `json:"name"`
-		TimeZone   int     `json:"timezone"`
-		Visibility float64 `json:"visibility"`
-	}
-
-	var res response
-	err = json.NewDecoder(resp.Body).Decode(&res)
+func (v verbosityFlag) String() string {
+	if v.level == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.level)
+}
+
+func (v verbosityFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
 	if err != nil {
-		return nil, err
-	}
-
-	w := &Weather{}
-	w.CityName = res.Name
-	w.TimeZone = res.TimeZone
-	w.Visibility = res.Visibility
-	w.Temperature = res.Main.Temperature
-	w.Pressure = res.Main.Pressure
-	w.Humidity = res.Main.Humidity
-	w.WindSpeed = res.Wind.Speed
-	w.WindDegrees = res.Wind.Degrees
-
-	// @NOTE: Maybe take all?
-	if len(res.Weather) > 0 {
-		w.Conditions = res.Weather[0].Description
-		w.Icon = res.Weather[0].Icon
-	}
-
-	return w, nil
-}
-
-func weatherIconIdToEmoji(id string) string {
-	// https://openweathermap.org/weather-conditions
-	switch id {
-	case "01d":
-		return "☀️" // clear sky day
-	case "02d":
-		return "⛅" // few clouds day
-	case "03d":
-		return "☁️" // scattered clouds day
-	case "04d":
-		return "☁️" // broken clouds day
-	case "09d":
-		return "🌧️" // shower rain day
-	case "10d":
-		return "🌦️" // rain day
-	case "11d":
-		return "⛈️" // thunderstorm day
-	case "13d":
-		return "❄️" // snow day
-	case "50d":
-		return "🌫️" // mist day
-	case "01n":
-		return "🌑" // clear sky night
-	case "02n":
-		return "⛅" // few clouds night
-	case "03n":
-		return "☁️" // scattered clouds night
-	case "04n":
-		return "☁️" // broken clouds night
-	case "09n":
-		return "🌧️" // shower rain night
-	case "10n":
-		return "🌦️" // rain night
-	case "11n":
-		return "⛈️" // thunderstorm night
-	case "13n":
-		return "❄️" // snow night
-	case "50n":
-		return "🌫️" // mist night
-	default:
-		return ""
-	}
-}
-
-func display(w io.Writer, wt *Weather, opt *options) {
-	temperatureSymbol, windSpeedSymbol := "C", "m/s"
-	if opt.units == "imperial" {
-		temperatureSymbol, windSpeedSymbol = "F", "mi/h"
-	}
-
-	weatherEmoji := weatherIconIdToEmoji(wt.Icon)
-
-	if opt.verbose {
-		t := time.Now().UTC().Add(time.Duration(wt.TimeZone) * time.Second)
-
-		fmt.Printf("%s %s\n", wt.CityName, t.Format(time.Stamp))
-		fmt.Printf("========================\n")
-		fmt.Printf("condition: %s %s\n", weatherEmoji, wt.Conditions)
-		fmt.Printf("temperature: %.0f°%s\n", wt.Temperature, temperatureSymbol)
-		fmt.Printf("pressure: %.0f hPa\n", wt.Pressure)
-		fmt.Printf("humidity: %.1f%%\n", wt.Humidity)
-		fmt.Printf("wind: %.0f° %.1f %s\n", wt.WindDegrees, wt.WindSpeed, windSpeedSymbol)
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return errors.New("verbosity must be true, false or a level, e.g. 2")
+		}
+		*v.level = n
+		return nil
+	}
+	if on {
+		*v.level += v.step
 	} else {
-		fmt.Printf("%s %0.f°%s %s %s\n", wt.CityName, wt.Temperature, temperatureSymbol, weatherEmoji, wt.Conditions)
+		*v.level = 0
 	}
+	return nil
 }
 
-func main() {
-	flag.Usage = func() {
-		w := flag.CommandLine.Output()
-		fmt.Fprintf(w, "weather displays the current weather of a given city.\n\n")
-		fmt.Fprintf(w, "usage:\n")
-		fmt.Fprintf(w, "\tweather [options] <city>\n\n")
-		fmt.Fprintf(w, "options:\n")
-		flag.PrintDefaults()
+// forecastFormatFlag accepts the forecast output formats or the path of a
+// PNG file to render the forecast chart into.
+func forecastFormatFlag(dst *string) func(string) error {
+	return func(value string) error {
+		if isPNGPath(value) {
+			*dst = value
+			return nil
+		}
+		return enumFlag(dst, "output format", append(forecastFormatValues, "<file>.png"))(value)
 	}
+}
+
+func exitWithError(errorMessage string) {
+	exitWithStatus(exitFailure, errorMessage)
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// raw commands run without loading the config file.
+	raw bool
+	// hidden commands are not listed in the usage.
+	hidden bool
+	flags  func(fs *flag.FlagSet, opt *options)
+	run    func(s *session)
+
+	// paged commands show long text output in the pager.
+	paged bool
+}
 
-	opt := options{units: "metric"}
+// commands lists the subcommands; the first one is the default used when
+// no command is given. It is set in init as some commands refer back to it.
+var commands []*command
 
-	flag.StringVar(&opt.apiKey, "key", os.Getenv("OPENWEATHER_API_KEY"), "OpenWeather API key")
-	flag.BoolVar(&opt.verbose, "v", false, "verbose output")
-	flag.Func("units", "units of measurement (metric|imperial)", func(value string) error {
-		if value != "metric" && value != "imperial" {
-			return errors.New("unit must be 'metric' or 'imperial'")
+func init() {
+	commands = []*command{
+		{
+			name:    "now",
+			args:    "<city>",
+			summary: "show the current weather (default command)",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				watchFlags(fs, opt)
+				influxFlags(fs, opt)
+				graphiteFlags(fs, opt)
+				displayFlags(fs, opt)
+				adviceFlags(fs, opt)
+				uvFlags(fs, opt)
+				vsNormalFlags(fs, opt)
+				openFlags(fs, opt)
+				speakFlags(fs, opt)
+				pagerFlags(fs, opt)
+				layoutFlags(fs, opt)
+				fs.Func("o", "output format ("+strings.Join(nowFormatValues, "|")+")", enumFlag(&opt.format, "output format", nowFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+				batchFlags(fs, opt)
+				socketFlags(fs, opt)
+				fs.BoolVar(&opt.fromDaemon, "daemon", false, "get the weather from the running daemon")
+			},
+			paged: true,
+			run:   runNow,
+		},
+		{
+			name:    "forecast",
+			args:    "<city>",
+			summary: "show the forecast for the next five days",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				watchFlags(fs, opt)
+				influxFlags(fs, opt)
+				displayFlags(fs, opt)
+				pagerFlags(fs, opt)
+				layoutFlags(fs, opt)
+				fs.Func("o", "output format ("+strings.Join(forecastFormatValues, "|")+"|<file>.png)", forecastFormatFlag(&opt.format))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+				fs.IntVar(&opt.days, "days", 5, "number of days to show (1-5)")
+				fs.DurationVar(&opt.next, "next", 0, "show only the next period, e.g. 6h")
+				fs.StringVar(&opt.between, "between", "", "show only the local time window of each day, e.g. 16:00-20:00")
+			},
+			paged: true,
+			run:   runForecast,
+		},
+		{
+			name:    "rain",
+			args:    "<city>",
+			summary: "tell whether rain is expected soon, exit with status 1 if not",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(rainFormatValues, "|")+")", enumFlag(&opt.format, "output format", rainFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				fs.IntVar(&opt.hours, "hours", 12, "how many hours ahead to look (1-120)")
+				outputFileFlag(fs, opt)
+			},
+			run: runRain,
+		},
+		{
+			name:    "air",
+			args:    "<city>",
+			summary: "show the current air quality",
+			flags:   allFlags,
+			run:     runAir,
+		},
+		{
+			name:    "compare",
+			args:    "<city> <city>",
+			summary: "compare the current weather of two locations side by side",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(compareFormatValues, "|")+")", enumFlag(&opt.format, "output format", compareFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runCompare,
+		},
+		{
+			name:    "rank",
+			args:    "[<city>...]",
+			summary: "rank locations by their current weather, e.g. the warmest first",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				rankFlags(fs, opt)
+				batchFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(rankFormatValues, "|")+")", enumFlag(&opt.format, "output format", rankFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runRank,
+		},
+		{
+			name:    "route",
+			args:    "<city> <city>...",
+			summary: "show the forecast along a route at the estimated arrival times",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				routeFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(routeFormatValues, "|")+")", enumFlag(&opt.format, "output format", routeFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runRoute,
+		},
+		{
+			name:    "trip",
+			args:    "<city>",
+			summary: "summarize the weather of the days of a trip and what to pack",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				tripFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(tripFormatValues, "|")+")", enumFlag(&opt.format, "output format", tripFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runTrip,
+		},
+		{
+			name:    "commute",
+			summary: "show the weather when leaving for work and heading home",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				commuteFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(commuteFormatValues, "|")+")", enumFlag(&opt.format, "output format", commuteFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runCommute,
+		},
+		{
+			name:    "score",
+			args:    "<city>",
+			summary: "score the current weather from 0 to 100 for running, biking or walking",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				activityFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(scoreFormatValues, "|")+")", enumFlag(&opt.format, "output format", scoreFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runScore,
+		},
+		{
+			name:    "laundry",
+			args:    "<city>",
+			summary: "estimate how long laundry takes to dry outdoors",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.IntVar(&opt.hours, "hours", 12, "how many hours ahead to look (1-120)")
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(laundryFormatValues, "|")+")", enumFlag(&opt.format, "output format", laundryFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runLaundry,
+		},
+		{
+			name:    "frost",
+			args:    "[<city>...]",
+			summary: "warn of frost tonight and exit with status 2 if plants need covering",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				frostFlags(fs, opt)
+				webhookFlags(fs, opt)
+				notifyFlags(fs, opt)
+				slackFlags(fs, opt)
+				discordFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("o", "output format ("+strings.Join(frostFormatValues, "|")+")", enumFlag(&opt.format, "output format", frostFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runFrost,
+		},
+		{
+			name:    "degree-days",
+			args:    "[<city>]",
+			summary: "compute heating and cooling degree days from the history and the forecast",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				degreeDaysFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("o", "output format ("+strings.Join(degreeFormatValues, "|")+")", enumFlag(&opt.format, "output format", degreeFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runDegreeDays,
+		},
+		{
+			name:    "stars",
+			args:    "<city>",
+			summary: "rate tonight for stargazing from the clouds, the moon and the darkness",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.Func("o", "output format ("+strings.Join(starsFormatValues, "|")+")", enumFlag(&opt.format, "output format", starsFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runStars,
+		},
+		{
+			name:    "photo",
+			args:    "<city>",
+			summary: "show the golden and blue hours of the coming days with their cloud cover",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				photoFlags(fs, opt)
+				fs.Func("o", "output format ("+strings.Join(photoFormatValues, "|")+")", enumFlag(&opt.format, "output format", photoFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runPhoto,
+		},
+		{
+			name:    "sun",
+			args:    "<city>",
+			summary: "print the next sunrise or sunset, shifted by -offset, e.g. as a Unix time",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				sunFlags(fs, opt)
+				fs.Func("o", "output format ("+strings.Join(sunFormatValues, "|")+")", enumFlag(&opt.format, "output format", sunFormatValues))
+				jsonPathFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runSun,
+		},
+		{
+			name:    "wind-sports",
+			args:    "<city>",
+			summary: "tell whether the wind suits kitesurfing, windsurfing or sailing",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				windSportsFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("o", "output format ("+strings.Join(sportsFormatValues, "|")+")", enumFlag(&opt.format, "output format", sportsFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runWindSports,
+		},
+		{
+			name:    "report",
+			args:    "-f <sites.csv>",
+			summary: "write the current weather of the sites listed in a CSV file as one report",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				reportFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(reportFormatValues, "|")+"|<file>.csv) (default table)", reportFormatFlag(opt))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runReport,
+		},
+		{
+			name:    "map",
+			args:    "<city>",
+			summary: "render a weather map around a location as a PNG image",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				mapFlags(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runMap,
+		},
+		{
+			name:    "feed",
+			args:    "<city>",
+			summary: "write an Atom feed of the current weather and alerts",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				displayFlags(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runFeed,
+		},
+		{
+			name:    "search",
+			args:    "<query>",
+			summary: "list the locations matching a name with their coordinates",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.Func("lang", "language of the local names shown, e.g. fi, de", enumFlag(&opt.lang, "lang", langValues))
+				fs.Func("o", "output format ("+strings.Join(searchFormatValues, "|")+")", enumFlag(&opt.format, "output format", searchFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runSearch,
+		},
+		{
+			name:    "tui",
+			args:    "[<city>]",
+			summary: "show a full-screen dashboard of the favorites",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.DurationVar(&opt.interval, "interval", 10*time.Minute, "how often the shown location is refreshed")
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+			},
+			run: runTUI,
+		},
+		{
+			name:    "daemon",
+			args:    "[<city>]",
+			summary: "poll locations in the background and answer queries over a socket",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				socketFlags(fs, opt)
+				fs.DurationVar(&opt.interval, "interval", 10*time.Minute, "how often the locations are polled")
+				fs.StringVar(&opt.metricsListen, "metrics-listen", "", "address to serve Prometheus metrics on, e.g. :9100")
+				breakerFlags(fs, opt)
+				dnsFlags(fs, opt)
+				transportFlags(fs, opt)
+				webhookFlags(fs, opt)
+				notifyFlags(fs, opt)
+				slackFlags(fs, opt)
+				discordFlags(fs, opt)
+				smsFlags(fs, opt)
+				smtpFlags(fs, opt)
+				rulesFlag(fs, &opt.rules)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+			},
+			run: runDaemon,
+		},
+		{
+			name:    "serve",
+			summary: "serve the weather as JSON over HTTP",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				fs.StringVar(&opt.listen, "listen", ":8080", "address to listen on")
+				breakerFlags(fs, opt)
+				dnsFlags(fs, opt)
+				transportFlags(fs, opt)
+				fs.StringVar(&opt.grpcListen, "grpc-listen", "", "address to serve the gRPC API on, e.g. :9090")
+				fs.DurationVar(&opt.interval, "interval", time.Minute, "how often /v1/stream checks for new data")
+				fs.Func("units", "default units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("lang", "default language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+			},
+			run: runServe,
+		},
+		{
+			name:    "history",
+			args:    "show|export [<city>]",
+			summary: "show or export observations recorded in the history database",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				configFileFlags(fs, opt)
+				historyFlags(fs, opt)
+				fs.Func("since", "select observations newer than this, e.g. 24h, 7d or 2024-01-02", sinceFlag(&opt.since))
+				fs.Func("until", "select observations older than this, e.g. 24h, 7d or 2024-01-02", sinceFlag(&opt.until))
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("o", "output format ("+strings.Join(historyFormatValues, "|")+"), export defaults to csv", enumFlag(&opt.format, "output format", historyFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runHistory,
+		},
+		{
+			name:    "trend",
+			args:    "[<city>]",
+			summary: "compare the latest recorded temperature with a day and a week ago",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				configFileFlags(fs, opt)
+				historyFlags(fs, opt)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Func("o", "output format ("+strings.Join(trendFormatValues, "|")+")", enumFlag(&opt.format, "output format", trendFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+				outputFileFlag(fs, opt)
+			},
+			run: runTrend,
+		},
+		{
+			name:    "summary",
+			args:    "[<city>...]",
+			summary: "show the weather of the day and post it to chat",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				displayFlags(fs, opt)
+				slackFlags(fs, opt)
+				discordFlags(fs, opt)
+			},
+			run: runSummary,
+		},
+		{
+			name:    "digest",
+			args:    "[<city>]",
+			summary: "email the forecast of the day for the favorites",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				displayFlags(fs, opt)
+				smtpFlags(fs, opt)
+				summaryTimeFlag(fs, opt, "local time of day when the digest is sent with -schedule")
+				fs.BoolVar(&opt.schedule, "schedule", false, "keep running and send the digest every day at -summary-time")
+			},
+			run: runDigest,
+		},
+		{
+			name:    "bot",
+			args:    "telegram",
+			summary: "run a chat bot answering weather queries and sending subscribed forecasts",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				displayFlags(fs, opt)
+				telegramFlags(fs, opt)
+				fs.DurationVar(&opt.interval, "interval", 10*time.Minute, "how often subscribed locations are checked for warnings")
+			},
+			run: runBot,
+		},
+		{
+			name:    "check",
+			args:    "[<city>...]",
+			summary: "evaluate the alert rules and exit with status 2 if any is triggered",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fetchFlags(fs, opt)
+				webhookFlags(fs, opt)
+				notifyFlags(fs, opt)
+				slackFlags(fs, opt)
+				discordFlags(fs, opt)
+				smsFlags(fs, opt)
+				rulesFlag(fs, &opt.rules)
+				fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+				fs.Var(verbosityFlag{&opt.verbose, 1}, "v", "show the rules that are not triggered too")
+				fs.StringVar(&opt.when, "when", "", "instead of the rules, exit 0 if this condition holds and 1 if not, e.g. \"rain || wind > 10\"")
+				fs.Func("o", "output format ("+strings.Join(checkFormatValues, "|")+")", enumFlag(&opt.format, "output format", checkFormatValues))
+				jsonPathFlag(fs, opt)
+				precisionFlag(fs, opt)
+			},
+			run: runCheck,
+		},
+		{
+			name:    "auth",
+			args:    "check|set-key [key]",
+			summary: "check the API key or store it in the system credential store",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				configFileFlags(fs, opt)
+				connectionFlags(fs, opt)
+			},
+			run: runAuth,
+		},
+		{
+			name:    "config",
+			args:    "get|set|unset|list|edit|path",
+			summary: "manage the config file",
+			raw:     true,
+			flags:   configFileFlags,
+			run:     runConfig,
+		},
+		{
+			name:    "cache",
+			args:    "clear|stats|forget <location>",
+			summary: "manage the response and geocoding caches",
+			flags: func(fs *flag.FlagSet, opt *options) {
+				configFileFlags(fs, opt)
+				cacheFlags(fs, opt)
+			},
+			run: runCache,
+		},
+		{
+			name:    "update",
+			summary: "update weather to the latest release (integrity checked, not signed)",
+			raw:     true,
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fs.DurationVar(&opt.timeout, "timeout", time.Minute, "timeout for each HTTP request")
+				fs.BoolVar(&opt.checkOnly, "check", false, "only check whether an update is available")
+				fs.BoolVar(&opt.force, "force", false, "reinstall the latest release even if it is not newer")
+			},
+			run: runUpdate,
+		},
+		{
+			name:    "docs",
+			args:    "man",
+			summary: "generate man pages",
+			raw:     true,
+			flags: func(fs *flag.FlagSet, opt *options) {
+				fs.StringVar(&opt.docsDir, "dir", ".", "directory to write the pages to")
+			},
+			run: runDocs,
+		},
+		{
+			name:    "completion",
+			args:    strings.Join(completionShells, "|"),
+			summary: "print a shell completion script",
+			raw:     true,
+			run:     runCompletion,
+		},
+		{
+			name:   "__complete",
+			raw:    true,
+			hidden: true,
+			run:    runComplete,
+		},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
 		}
-		opt.units = value
-		return nil
-	})
+	}
+	return nil
+}
+
+func configFileFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.configPath, "config", defaultConfigPath(), "path of the config file")
+	fs.StringVar(&opt.profile, "profile", "", "config profile to use")
+}
+
+func connectionFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.apiKey, "key", "", "OpenWeather API key, or a comma separated list of keys (default $OPENWEATHER_API_KEY)")
+	fs.Func("key-rotation", "how multiple keys are used ("+strings.Join(keyRotationValues, "|")+")", enumFlag(&opt.keyRotation, "key rotation", keyRotationValues))
+	fs.Func("provider", "weather data provider ("+strings.Join(providerValues, "|")+")", enumFlag(&opt.provider, "provider", providerValues))
+	fs.DurationVar(&opt.timeout, "timeout", 10*time.Second, "timeout for each HTTP request")
+	fs.IntVar(&opt.maxRequests, "max-requests", 8, "most requests in flight to the provider at once")
+	fs.BoolVar(&opt.ipv4, "4", false, "connect over IPv4 only")
+	fs.BoolVar(&opt.ipv6, "6", false, "connect over IPv6 only")
+	fs.BoolVar(&opt.stats, "stats", false, "print the API calls, their latency and the cache hit rate of the run to stderr")
+	fs.BoolVar(&opt.debugHTTP, "debug-http", false, "log HTTP requests and responses to stderr")
+	fs.BoolVar(&opt.debugHTTPDump, "debug-http-dump", false, "with -debug-http, also dump headers and bodies")
+	fs.BoolVar(&opt.dryRun, "dry-run", false, "print the request URLs without making any network calls")
+	fs.BoolVar(&opt.raw, "raw", false, "print the provider's responses as received instead of the weather")
+}
+
+func cacheFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.cacheDir, "cache-dir", defaultCacheDir(), "directory of the response cache, empty disables caching")
+	fs.DurationVar(&opt.cacheTTL, "cache-ttl", 10*time.Minute, "how long cached responses are used")
+}
 
-	flag.Parse()
+func offlineFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.noCache, "no-cache", false, "fetch fresh data instead of using cached responses")
+	fs.BoolVar(&opt.offline, "offline", false, "show the last cached data without using the network")
+	fs.BoolVar(&opt.staleFallback, "stale-fallback", false, "show the last cached data when the provider cannot be reached")
+}
+
+func historyFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.historyPath, "history-db", defaultHistoryPath(), "path of the history database")
+}
+
+func outputFlags(fs *flag.FlagSet, opt *options) {
+	displayFlags(fs, opt)
+	fs.Func("o", "output format ("+strings.Join(formatValues, "|")+")", enumFlag(&opt.format, "output format", formatValues))
+	jsonPathFlag(fs, opt)
+	precisionFlag(fs, opt)
+}
+
+// displayFlags registers the output flags other than -o, whose values vary
+// by command.
+func displayFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("units", "units of measurement ("+strings.Join(unitsValues, "|")+")", enumFlag(&opt.units, "unit", unitsValues))
+	fs.Func("lang", "language of condition descriptions, e.g. en, fi, de", enumFlag(&opt.lang, "lang", langValues))
+	fs.Var(verbosityFlag{&opt.verbose, 1}, "v", "verbose output")
+	fs.Var(verbosityFlag{&opt.verbose, 2}, "vv", "more verbose output, now also shows the next days of the forecast")
+	fs.Var(verbosityFlag{&opt.verbose, 3}, "vvv", "most verbose output, adds provider diagnostics and timings on stderr")
+	fs.StringVar(&opt.locale, "locale", "", "locale of the numbers and dates of text output, e.g. fi_FI (default from LC_ALL, LC_NUMERIC, LC_TIME or LANG)")
+	fs.Var(a11yFlag{&opt.a11yMode}, "a11y", "screen reader friendly text output, or auto when stdout is not a terminal")
+}
+
+// fetchFlags registers the flags of commands fetching data from a provider.
+func fetchFlags(fs *flag.FlagSet, opt *options) {
+	configFileFlags(fs, opt)
+	connectionFlags(fs, opt)
+	cacheFlags(fs, opt)
+	offlineFlags(fs, opt)
+	historyFlags(fs, opt)
+	locationFlags(fs, opt)
+	fs.BoolVar(&opt.history, "history", false, "record fetched observations in the history database")
+	fs.StringVar(&opt.logFile, "log-file", "", "append fetched observations to this file as JSON lines")
+}
+
+func allFlags(fs *flag.FlagSet, opt *options) {
+	fetchFlags(fs, opt)
+	watchFlags(fs, opt)
+	influxFlags(fs, opt)
+	graphiteFlags(fs, opt)
+	outputFlags(fs, opt)
+	outputFileFlag(fs, opt)
+}
+
+// session holds the state of a command once its flags, the environment and
+// the config file have been applied.
+type session struct {
+	opt  *options
+	cfg  *config
+	argv []string
+
+	pool *keyPool
+	ow   *openWeather
+	db   *history
 
-	opt.city = strings.Join(flag.Args(), " ")
+	// previous holds the last reading of each city in watch mode.
+	previous map[string]*Weather
 
-	if opt.apiKey == "" {
-		exitWithError("OpenWeather API key is required")
+	// opened holds the cities opened in the browser with -open.
+	opened map[string]bool
+}
+
+// args returns the positional arguments of the command.
+func (s *session) args() []string {
+	return s.argv
+}
+
+// keys resolves the API keys on first use: -key, then OPENWEATHER_API_KEY,
+// then the config file and finally the system credential store.
+func (s *session) keys() *keyPool {
+	if s.pool != nil {
+		return s.pool
 	}
 
-	if strings.TrimSpace(opt.city) == "" {
-		exitWithError("city name is required")
+	if s.opt.apiKey == "" {
+		s.opt.apiKey = os.Getenv("OPENWEATHER_API_KEY")
 	}
 
-	w, err := fetchWeather(opt.apiKey, opt.city, opt.units)
-	if err != nil {
+	if s.opt.apiKey == "" {
+		var err error
+		s.opt.apiKey, err = configAPIKey(s.cfg)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+	}
+
+	if s.opt.apiKey == "" {
+		// Errors are ignored: a missing keyring entry just means no key.
+		s.opt.apiKey, _ = keyringGet()
+	}
+
+	apiKeys := parseKeys(s.opt.apiKey)
+	if len(apiKeys) == 0 {
+		exitWithStatus(exitAuth, "OpenWeather API key is required")
+	}
+
+	for _, k := range apiKeys {
+		registerSecret(k)
+	}
+	s.pool = newKeyPool(apiKeys, s.opt.keyRotation == "round-robin")
+	return s.pool
+}
+
+func (s *session) provider() *openWeather {
+	if s.ow == nil {
+		s.ow = newOpenWeather(s.keys(), s.opt)
+	}
+	return s.ow
+}
+
+func (s *session) historyDB() *history {
+	if s.db == nil {
+		db, err := openHistory(s.opt.historyPath)
+		if err != nil {
+			exitWithError("history: " + err.Error())
+		}
+		s.db = db
+	}
+	return s.db
+}
+
+// recordHistory stores w, given in units, in the history database when
+// -history is on and in the -log-file. Stale data served from the cache is
+// not a new observation and is skipped.
+func (s *session) recordHistory(w *Weather, units string) {
+	if w.CachedAt != nil {
+		return
+	}
+	if s.opt.logFile != "" {
+		if err := appendObservation(s.opt.logFile, w, s.opt.provider, units); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: log file: %s\n", err)
+		}
+	}
+	if !s.opt.history {
+		return
+	}
+	if err := s.historyDB().record(w, s.opt.provider, units); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: history: %s\n", err)
+	}
+}
+
+func (s *session) query(city string) query {
+	return query{city: city, units: s.opt.units, lang: s.opt.lang}
+}
+
+// cities returns the locations given as arguments, joined into one name.
+// Without arguments the default city and then the favorites from the
+// config are used. Names defined in the [aliases] table are expanded, and
+// names given as arguments that match several locations are disambiguated.
+func (s *session) cities() []string {
+	cities := s.citiesFrom(s.args())
+	if len(s.args()) > 0 && cities[0] == strings.Join(s.args(), " ") {
+		cities[0] = s.disambiguate(cities[0])
+	}
+	return cities
+}
+
+func (s *session) citiesFrom(args []string) []string {
+	if city := strings.Join(args, " "); strings.TrimSpace(city) != "" {
+		if alias, ok := s.cfg.string("aliases." + city); ok {
+			city = alias
+		}
+		return []string{city}
+	}
+	if cities := s.cfg.list("city"); len(cities) > 0 {
+		return cities
+	}
+	if cities := s.cfg.list("favorites"); len(cities) > 0 {
+		return cities
+	}
+	exitWithUsageError("city name is required")
+	return nil
+}
+
+// parseInterspersed parses flags given anywhere among the arguments, e.g.
+// `weather history show helsinki -since 7d`, and returns the positional
+// arguments. Arguments after "--" are never treated as flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err == flag.ErrHelp {
+			os.Exit(0)
+		} else if err != nil {
+			// The flag package has printed the error and the usage.
+			os.Exit(exitUsage)
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func setup(cmd *command, args []string) *session {
+	opt := &options{units: "metric", lang: "en", keyRotation: "on-429", provider: "openweather", format: "text", activity: "run", coldTolerance: "normal", sport: "kitesurf", sunEvent: "sunrise", mapLayer: "precipitation", graphics: "auto", openSite: "openweather", layout: "auto"}
+
+	fs := flag.NewFlagSet("weather "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() { commandUsage(fs, cmd) }
+	// Commands without flags get their arguments as is.
+	if cmd.flags != nil {
+		cmd.flags(fs, opt)
+		args = parseInterspersed(fs, args)
+	}
+
+	if err := applyEnv(fs); err != nil {
 		exitWithError(err.Error())
 	}
 
-	display(os.Stdout, w, &opt)
+	s := &session{opt: opt, cfg: &config{values: map[string]any{}}, argv: args}
+	if !cmd.raw {
+		cfg, err := loadConfig(opt.configPath)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		if opt.profile != "" {
+			if cfg, err = cfg.withProfile(opt.profile); err != nil {
+				exitWithError(err.Error())
+			}
+		}
+		if err := applyConfig(cfg, fs); err != nil {
+			exitWithError(err.Error())
+		}
+		s.cfg = cfg
+	}
+
+	opt.a11y = screenReaderOutput(opt.a11yMode)
+	if opt.format == "text" || opt.format == "table" || opt.format == "chart" {
+		setLocale(opt.locale)
+	}
+
+	if opt.jsonPath != nil {
+		// A path selects from the JSON output, whatever -o says.
+		outputJSONPath = opt.jsonPath
+		if !isJSON(opt.format) {
+			opt.format = "json"
+		}
+	}
+	outputPrecision = opt.precision
+
+	registerSecret(opt.influxToken)
+	registerSecret(opt.webhookSecret)
+	registerSecret(opt.slackWebhook)
+	registerSecret(opt.discordWebhook)
+	registerSecret(opt.telegramToken)
+	registerSecret(opt.smtpPassword)
+	registerSecret(opt.twilioToken)
+	if fs.Lookup("http2") != nil {
+		tuneTransport(opt)
+	}
+	if opt.dnsTTL > 0 {
+		useDNSCache(opt.dnsTTL)
+	}
+	switch {
+	case opt.ipv4 && opt.ipv6:
+		exitWithUsageError("give either -4 or -6")
+	case opt.ipv4:
+		useAddressFamily("tcp4")
+	case opt.ipv6:
+		useAddressFamily("tcp6")
+	}
+	if opt.debugHTTP {
+		httpClient = newDebugClient(os.Stderr, opt.debugHTTPDump)
+	}
+	httpClient.Timeout = opt.timeout
+	if opt.stats {
+		statsOutput = os.Stderr
+	}
+	if fs.Lookup("max-requests") != nil && opt.maxRequests < 1 {
+		exitWithUsageError("max-requests must be at least 1")
+	}
+
+	return s
+}
+
+func usage() {
+	w := os.Stderr
+	fmt.Fprintf(w, "weather displays the current weather of a given city.\n\n")
+	fmt.Fprintf(w, "usage:\n")
+	fmt.Fprintf(w, "\tweather [options] <city>\n")
+	fmt.Fprintf(w, "\tweather <command> [options] [arguments]\n")
+	fmt.Fprintf(w, "\tweather -version\n\n")
+	fmt.Fprintf(w, "commands:\n")
+	for _, c := range commands {
+		if !c.hidden {
+			fmt.Fprintf(w, "\t%-10s %s\n", c.name, c.summary)
+		}
+	}
+	fmt.Fprintf(w, "\nRun 'weather <command> -h' for the options of a command.\n")
+}
+
+func commandUsage(fs *flag.FlagSet, cmd *command) {
+	w := fs.Output()
+	fmt.Fprintf(w, "usage:\n")
+	fmt.Fprintf(w, "\t%s\n\n", strings.TrimSpace("weather "+cmd.name+" [options] "+cmd.args))
+	fmt.Fprintf(w, "%s\n", cmd.summary)
+	if cmd.flags != nil {
+		fmt.Fprintf(w, "\noptions:\n")
+		fs.PrintDefaults()
+	}
+}
+
+// exitOnError exits on errors other than errDryRun and errRaw, which only
+// signal that a request or a response was printed instead of the data. The
+// exit status tells the kind of error.
+func exitOnError(err error) bool {
+	if errors.Is(err, errDryRun) || errors.Is(err, errRaw) {
+		return true
+	}
+	if err != nil {
+		exitWithStatus(exitStatus(err), err.Error())
+	}
+	return false
+}
+
+func runNow(s *session) {
+	if s.opt.batchFile != "" {
+		if len(s.args()) > 0 {
+			exitWithUsageError("give the locations either with -f or as arguments")
+		}
+		runBatch(s, s.readLocations(s.opt.batchFile))
+		return
+	}
+	if s.opt.format == "table" || s.opt.format == "csv" {
+		runBatch(s, s.cities())
+		return
+	}
+
+	// Several locations, e.g. the favorites, are fetched at once and then
+	// shown in order.
+	cities := s.cities()
+	var fetched []batchResult
+	if len(cities) > 1 && !s.opt.fromDaemon && !s.opt.dryRun && !s.opt.raw {
+		fetched = s.fetchBatch(cities)
+	}
+
+	for i, city := range cities {
+		if s.opt.fromDaemon {
+			w, units, err := queryDaemon(s.opt.socketPath, city)
+			if err != nil {
+				exitWithError(err.Error())
+			}
+			s.opt.units = units
+			display(os.Stdout, w, nil, nil, s.opt)
+			s.displayVsNormal(city, w)
+			s.displayAdvice(w)
+			s.displayUV(city)
+			s.speakWeather(w)
+			continue
+		}
+
+		var w *Weather
+		var err error
+		if fetched != nil {
+			w, err = fetched[i].Weather, fetched[i].err
+		} else {
+			w, err = s.provider().current(s.query(city))
+		}
+		if exitOnError(err) {
+			continue
+		}
+		s.recordHistory(w, s.opt.units)
+
+		var recent []float64
+		if s.opt.history {
+			recent = s.recentTemperatures(w)
+		}
+
+		var prev *Weather
+		if s.opt.watch {
+			if s.previous == nil {
+				s.previous = map[string]*Weather{}
+			}
+			prev, s.previous[city] = s.previous[city], w
+		}
+		display(os.Stdout, w, prev, recent, s.opt)
+		s.displayVsNormal(city, w)
+		s.displayAdvice(w)
+		s.displayUV(city)
+		s.speakWeather(w)
+		s.openLocation(city)
+		s.pushInflux(influxWeather(w, s.opt))
+		s.emitMetrics(w.CityName, weatherSamples(w, s.opt.units), w.Time)
+
+		// With history on, verbose output also compares against earlier
+		// observations.
+		if s.opt.verbose > 0 && s.opt.history && s.opt.format == "text" {
+			for _, d := range s.trend(w).Deltas {
+				fmt.Printf("trend: %s\n", d.describe())
+			}
+		}
+		if s.opt.verbose >= 2 && s.opt.format == "text" {
+			s.displayOutlook(city)
+		}
+	}
+}
+
+// displayAdvice prints what to wear in w with -advice.
+func (s *session) displayAdvice(w *Weather) {
+	if s.opt.advice && s.opt.format == "text" {
+		fmt.Printf("advice: %s\n", clothingAdvice(toMetric(w, s.opt.units), s.opt.coldTolerance))
+	}
+}
+
+// displayOutlook prints a line for today and the next two days of the
+// forecast of city, shown by now -vv.
+func (s *session) displayOutlook(city string) {
+	f, err := s.provider().forecast(s.query(city))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: forecast: %s\n", redact(err.Error()))
+		return
+	}
+	days := forecastDays(f)
+	for _, d := range days[:min(len(days), 3)] {
+		fmt.Printf("%s: %s\n", localDate(d.date, "Mon 2 Jan"), d.summary())
+	}
+}
+
+func runForecast(s *session) {
+	if s.opt.days < 1 || s.opt.days > 5 {
+		exitWithUsageError("days must be between 1 and 5")
+	}
+	if s.opt.next < 0 {
+		exitWithUsageError("next must not be negative")
+	}
+	var windowStart, windowLength time.Duration
+	if s.opt.between != "" {
+		var err error
+		if windowStart, windowLength, err = parseBetween(s.opt.between); err != nil {
+			exitWithUsageError(err.Error())
+		}
+	}
+
+	cities := s.cities()
+	if (isPNGPath(s.opt.format) || s.opt.format == "ics") && len(cities) > 1 {
+		exitWithError(fmt.Sprintf("-o %s supports one city at a time", s.opt.format))
+	}
+
+	for _, city := range cities {
+		f, err := s.provider().forecast(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		filterForecastDays(f, s.opt.days, time.Now())
+		if s.opt.next > 0 {
+			filterForecastNext(f, s.opt.next, time.Now())
+		}
+		if s.opt.between != "" {
+			filterForecastBetween(f, windowStart, windowLength)
+		}
+		switch {
+		case s.opt.format == "chart":
+			displayForecastChart(os.Stdout, f, s.opt, terminalWidth())
+		case s.opt.format == "ics":
+			displayForecastICS(os.Stdout, f, s.opt, time.Now())
+		case isPNGPath(s.opt.format):
+			if err := writeForecastPNG(s.opt.format, f, s.opt); err != nil {
+				exitWithError("chart: " + err.Error())
+			}
+		default:
+			displayForecast(os.Stdout, f, s.opt)
+		}
+		s.pushInflux(influxForecast(f, s.opt))
+	}
+}
+
+func runAir(s *session) {
+	for _, city := range s.cities() {
+		a, err := s.provider().air(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		displayAir(os.Stdout, a, s.opt)
+		s.pushInflux(influxAir(a, s.opt, time.Now()))
+		s.emitMetrics(a.CityName, airSamples(a), time.Now())
+	}
+}
+
+func main() {
+	args := os.Args[1:]
+	cmd := commands[0]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "-help", "--help", "help":
+			if len(args) > 1 {
+				if c := findCommand(args[1]); c != nil {
+					setup(c, []string{"-h"})
+				}
+			}
+			usage()
+			return
+		case "-version", "--version":
+			printVersion(os.Stdout)
+			return
+		}
+
+		if c := findCommand(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	}
+
+	s := setup(cmd, args)
+	run := cmd.run
+	if s.opt.output != "" {
+		run = writeOutput(s.opt.output, run)
+	}
+	if cmd.paged && pagedOutput(s.opt) {
+		run = pageOutput(run)
+	}
+	if s.opt.watch {
+		watch(s, run)
+	} else {
+		run(s)
+	}
+	writeStats()
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func runDocs(s *session) {
+	args := s.args()
+	if len(args) != 1 || args[0] != "man" {
+		exitWithUsageError("usage: weather docs man [-dir <directory>]")
+	}
+
+	if err := os.MkdirAll(s.opt.docsDir, 0o755); err != nil {
+		exitWithError(err.Error())
+	}
+
+	write := func(name string, page []byte) {
+		path := filepath.Join(s.opt.docsDir, name)
+		if err := os.WriteFile(path, page, 0o644); err != nil {
+			exitWithError(err.Error())
+		}
+		fmt.Println(path)
+	}
+
+	write("weather.1", manPage())
+	for _, c := range commands {
+		if !c.hidden {
+			write("weather-"+c.name+".1", commandManPage(c))
+		}
+	}
+}
+
+// roff escapes text for use in a man page.
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+func manHeader(buf *bytes.Buffer, title string) {
+	v, _, built := buildInfo()
+	t := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, built); err == nil {
+		t = parsed
+	}
+	fmt.Fprintf(buf, ".TH %s 1 \"%s\" \"weather %s\" \"User Commands\"\n", strings.ToUpper(roff(title)), t.Format("January 2006"), roff(orUnknown(v)))
+}
+
+func manPage() []byte {
+	var buf bytes.Buffer
+	manHeader(&buf, "weather")
+
+	fmt.Fprintf(&buf, ".SH NAME\nweather \\- fetch and display the weather using the OpenWeather API\n")
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n.B weather\n[\\fIoptions\\fR] \\fIcity\\fR\n.br\n.B weather\n\\fIcommand\\fR [\\fIoptions\\fR] [\\fIarguments\\fR]\n")
+	fmt.Fprintf(&buf, ".SH DESCRIPTION\n")
+	fmt.Fprintf(&buf, "weather displays the current weather of a given city. Without a command the \\fBnow\\fR command is run.\n")
+
+	fmt.Fprintf(&buf, ".SH COMMANDS\n")
+	for _, c := range commands {
+		if !c.hidden {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n%s. See \\fBweather\\-%s\\fR(1).\n", roff(c.name), roff(c.summary), roff(c.name))
+		}
+	}
+
+	fmt.Fprintf(&buf, ".SH ENVIRONMENT\n")
+	fmt.Fprintf(&buf, ".TP\n.B OPENWEATHER_API_KEY\nThe OpenWeather API key, used when \\fB\\-key\\fR is not given.\n")
+	fmt.Fprintf(&buf, ".TP\n.B WEATHER_*\nEvery flag can be set with an environment variable; the variable of each flag is listed in the page of the command.\n")
+
+	fmt.Fprintf(&buf, ".SH EXIT STATUS\n")
+	for _, e := range exitStatuses {
+		fmt.Fprintf(&buf, ".TP\n.B %d\n%s\n", e.status, roff(e.meaning))
+	}
+
+	fmt.Fprintf(&buf, ".SH FILES\n.TP\n.I %s\nThe config file. Flags and environment variables take precedence over it.\n", roff(withTilde(defaultConfigPath())))
+
+	fmt.Fprintf(&buf, ".SH SEE ALSO\n")
+	var refs []string
+	for _, c := range commands {
+		if !c.hidden {
+			refs = append(refs, fmt.Sprintf("\\fBweather\\-%s\\fR(1)", roff(c.name)))
+		}
+	}
+	fmt.Fprintf(&buf, "%s\n", strings.Join(refs, ", "))
+
+	return buf.Bytes()
+}
+
+func commandManPage(cmd *command) []byte {
+	var buf bytes.Buffer
+	manHeader(&buf, "weather-"+cmd.name)
+
+	fmt.Fprintf(&buf, ".SH NAME\nweather\\-%s \\- %s\n", roff(cmd.name), roff(cmd.summary))
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n.B weather %s\n", roff(cmd.name))
+	if cmd.flags != nil {
+		fmt.Fprintf(&buf, "[\\fIoptions\\fR] ")
+	}
+	fmt.Fprintf(&buf, "%s\n", roff(cmd.args))
+
+	if cmd.flags != nil {
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.flags(fs, &options{})
+
+		fmt.Fprintf(&buf, ".SH OPTIONS\n")
+		fs.VisitAll(func(f *flag.Flag) {
+			name, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(&buf, ".TP\n\\fB\\-%s\\fR", roff(f.Name))
+			if name != "" {
+				fmt.Fprintf(&buf, " \\fI%s\\fR", roff(name))
+			}
+			fmt.Fprintf(&buf, "\n%s", roff(usage))
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+				fmt.Fprintf(&buf, " (default %s)", roff(withTilde(f.DefValue)))
+			}
+			fmt.Fprintf(&buf, ".\nEnvironment: \\fB%s\\fR.\n", roff(envName(f.Name)))
+		})
+	}
+
+	fmt.Fprintf(&buf, ".SH SEE ALSO\n\\fBweather\\fR(1)\n")
+	return buf.Bytes()
+}
+
+// withTilde abbreviates the home directory of the user generating the
+// pages so that they are not specific to them.
+func withTilde(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	return strings.Replace(path, home, "~", 1)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// weather map renders a weather map layer of OpenWeather around a location
+// over a base map, OpenStreetMap by default, as a PNG image. Both are made
+// of 256 pixel square tiles in the Web Mercator projection:
+// https://wiki.openstreetmap.org/wiki/Slippy_map_tilenames
+
+const (
+	mapTileSize = 256
+	mapSize     = 3 * mapTileSize
+	maxMapZoom  = 18
+)
+
+// defaultBaseMap is the URL template of the base map tiles.
+const defaultBaseMap = "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+
+var mapLayerValues = []string{"precipitation", "clouds", "temperature", "wind", "pressure"}
+
+// mapLayers maps the layers to their OpenWeather names.
+var mapLayers = map[string]string{
+	"precipitation": "precipitation_new",
+	"clouds":        "clouds_new",
+	"temperature":   "temp_new",
+	"wind":          "wind_new",
+	"pressure":      "pressure_new",
+}
+
+var (
+	mapMarker     = color.RGBA{0xd6, 0x45, 0x2b, 0xff}
+	mapBackground = color.RGBA{0xee, 0xee, 0xee, 0xff}
+)
+
+func mapFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("layer", "the weather layer ("+strings.Join(mapLayerValues, "|")+") (default precipitation)", enumFlag(&opt.mapLayer, "layer", mapLayerValues))
+	fs.IntVar(&opt.mapZoom, "zoom", 7, "zoom level, from 0 for the whole world to 18")
+	fs.StringVar(&opt.baseMap, "basemap", defaultBaseMap, "URL template of the base map tiles, or none")
+	fs.Func("graphics", "how to show the map on a terminal ("+strings.Join(graphicsValues, "|")+") (default auto)", enumFlag(&opt.graphics, "graphics", graphicsValues))
+}
+
+// mapTile is the position of a tile in the grid of a zoom level and where
+// it goes in the map image.
+type mapTile struct {
+	x, y int
+	at   image.Point
+}
+
+// mapTiles returns the tiles covering a map of mapSize pixels centered on
+// lat, lon at zoom. Tiles wrap around the antimeridian and end at the poles.
+func mapTiles(lat, lon float64, zoom int) []mapTile {
+	n := float64(int(1) << zoom)
+	rad := lat * math.Pi / 180
+	cx := (lon + 180) / 360 * n * mapTileSize
+	cy := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * n * mapTileSize
+	left, top := int(math.Floor(cx))-mapSize/2, int(math.Floor(cy))-mapSize/2
+
+	var tiles []mapTile
+	for ty := floorDiv(top, mapTileSize); ty*mapTileSize < top+mapSize; ty++ {
+		if ty < 0 || ty >= int(n) {
+			continue
+		}
+		for tx := floorDiv(left, mapTileSize); tx*mapTileSize < left+mapSize; tx++ {
+			x := (tx%int(n) + int(n)) % int(n)
+			tiles = append(tiles, mapTile{x: x, y: ty, at: image.Pt(tx*mapTileSize-left, ty*mapTileSize-top)})
+		}
+	}
+	return tiles
+}
+
+func floorDiv(a, b int) int {
+	return int(math.Floor(float64(a) / float64(b)))
+}
+
+// tileURL fills in a URL template such as defaultBaseMap.
+func tileURL(template string, zoom, x, y int) string {
+	return strings.NewReplacer("{z}", strconv.Itoa(zoom), "{x}", strconv.Itoa(x), "{y}", strconv.Itoa(y)).Replace(template)
+}
+
+// getBaseTile downloads a base map tile. The OpenStreetMap tile usage
+// policy asks for a User-Agent identifying the application.
+func getBaseTile(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	v, _, _ := buildInfo()
+	req.Header.Set("User-Agent", "weather/"+orUnknown(v))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchMap draws the tiles of the base map and of the weather layer around
+// lat, lon into images of their own. Without a base map its image is
+// plain.
+func (s *session) fetchMap(lat, lon float64) (base, weather *image.RGBA, err error) {
+	zoom, layer := s.opt.mapZoom, mapLayers[s.opt.mapLayer]
+	tiles := mapTiles(lat, lon, zoom)
+
+	images := make([][2]image.Image, len(tiles))
+	errs := make([]error, len(tiles))
+	var wg sync.WaitGroup
+	var exits workerExits
+	for i, t := range tiles {
+		wg.Add(1)
+		go func(i int, t mapTile) {
+			defer wg.Done()
+			defer exits.catch()
+			decode := func(b []byte, err error) image.Image {
+				if err == nil {
+					var img image.Image
+					if img, err = png.Decode(bytes.NewReader(b)); err == nil {
+						return img
+					}
+				}
+				if errs[i] == nil {
+					errs[i] = err
+				}
+				return nil
+			}
+			if s.opt.baseMap != "none" && !s.opt.dryRun {
+				images[i][0] = decode(getBaseTile(tileURL(s.opt.baseMap, zoom, t.x, t.y)))
+				if errs[i] != nil {
+					errs[i] = fmt.Errorf("base map: %w", errs[i])
+					return
+				}
+			}
+			images[i][1] = decode(s.provider().tile(layer, zoom, t.x, t.y))
+		}(i, t)
+	}
+	wg.Wait()
+	exits.rethrow()
+	// The errors of the tiles are all alike, so the first one is enough.
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	base = image.NewRGBA(image.Rect(0, 0, mapSize, mapSize))
+	weather = image.NewRGBA(base.Bounds())
+	draw.Draw(base, base.Bounds(), image.NewUniform(mapBackground), image.Point{}, draw.Src)
+	for i, t := range tiles {
+		r := image.Rectangle{Min: t.at, Max: t.at.Add(image.Pt(mapTileSize, mapTileSize))}
+		for j, dst := range []*image.RGBA{base, weather} {
+			if tile := images[i][j]; tile != nil {
+				draw.Draw(dst, r, tile, tile.Bounds().Min, draw.Over)
+			}
+		}
+	}
+	return base, weather, nil
+}
+
+// composeMap draws the weather layer over the base map and marks the
+// location at the center.
+func composeMap(base, weather *image.RGBA) *image.RGBA {
+	img := image.NewRGBA(base.Bounds())
+	draw.Draw(img, img.Bounds(), base, image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), weather, image.Point{}, draw.Over)
+
+	c := mapSize / 2
+	drawLine(img, c-8, c, c+8, c, mapMarker)
+	drawLine(img, c, c-8, c, c+8, mapMarker)
+	return img
+}
+
+// mapAttribution returns the credits the map tiles require.
+func mapAttribution(opt *options) string {
+	if opt.baseMap == defaultBaseMap {
+		return "(c) OpenStreetMap contributors, weather data (c) OpenWeather"
+	}
+	return "Weather data (c) OpenWeather"
+}
+
+// drawAttribution writes the credits in the bottom right corner of img.
+func drawAttribution(img *image.RGBA, opt *options) {
+	attribution := mapAttribution(opt)
+	box := image.Rect(mapSize-7*len(attribution)-8, mapSize-18, mapSize, mapSize)
+	draw.Draw(img, box, image.NewUniform(color.NRGBA{0xff, 0xff, 0xff, 0xcc}), image.Point{}, draw.Over)
+	drawText(img, box.Min.X+4, mapSize-5, attribution, pngText)
+}
+
+// runMap shows the map of the location in the terminal, or writes it as a
+// PNG image to stdout, which is usually redirected to a file with -output.
+func runMap(s *session) {
+	if s.opt.mapZoom < 0 || s.opt.mapZoom > maxMapZoom {
+		exitWithUsageError(fmt.Sprintf("zoom must be between 0 and %d", maxMapZoom))
+	}
+	if s.opt.baseMap != "none" && !strings.Contains(s.opt.baseMap, "{z}") {
+		exitWithUsageError("basemap must be a URL template with {z}, {x} and {y}, or none")
+	}
+	// On a terminal the map is shown in it rather than written as a PNG
+	// file, unless -graphics asks for that anyway.
+	graphics := s.opt.graphics
+	if graphics == "auto" {
+		graphics = ""
+		if s.opt.output == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+			graphics = terminalGraphics()
+		}
+	}
+
+	cities := s.cities()
+	if len(cities) != 1 {
+		exitWithUsageError("usage: weather map [options] <city>")
+	}
+	l, err := s.provider().locate(cities[0])
+	if exitOnError(err) {
+		return
+	}
+	base, weather, err := s.fetchMap(l.Lat, l.Lon)
+	if exitOnError(err) {
+		return
+	}
+	if graphics != "" {
+		if err := displayMap(os.Stdout, base, weather, graphics, s.opt); err != nil {
+			exitWithError(err.Error())
+		}
+		return
+	}
+	img := composeMap(base, weather)
+	drawAttribution(img, s.opt)
+	if err := png.Encode(os.Stdout, img); err != nil {
+		exitWithError(err.Error())
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metrics are written in the Prometheus text exposition format:
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+
+var (
+	apiRequests  = newCounterVec("weather_api_requests_total", "Requests made to the weather provider API.", "endpoint", "status")
+	cacheLookups = newCounterVec("weather_cache_lookups_total", "Response cache lookups by result (hit, miss, stale).", "result")
+	fetchErrors  = newCounterVec("weather_fetch_errors_total", "Fetches that failed, by provider API endpoint.", "endpoint")
+	dnsLookups   = newCounterVec("weather_dns_lookups_total", "Host name lookups of long-running modes by result (hit, miss, stale).", "result")
+	apiRetries   = newCounterVec("weather_api_retries_total", "Requests retried with another API key after a rate limit.", "endpoint")
+
+	requestDuration = newHistogramVec("weather_api_request_duration_seconds", "Latency of the requests to the weather provider API.", latencyBuckets, "endpoint")
+
+	circuitState       = newGaugeVec("weather_provider_circuit_state", "State of the provider circuit breaker (0 closed, 1 open, 2 half-open).", "provider")
+	circuitTransitions = newCounterVec("weather_provider_circuit_transitions_total", "Provider circuit breaker state changes, by new state.", "provider", "state")
+)
+
+// counterVec is a counter with a value for each combination of labels. It
+// doubles as a gauge vector, see newGaugeVec.
+type counterVec struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	mu          sync.Mutex
+	values      map[string]float64  // keyed by the formatted label set
+	labelValues map[string][]string // the label values of each key
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, typ: "counter", labels: labels, values: map[string]float64{}, labelValues: map[string][]string{}}
+}
+
+func newGaugeVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, typ: "gauge", labels: labels, values: map[string]float64{}, labelValues: map[string][]string{}}
+}
+
+// inc increments the counter of the given label values, which are in the
+// order of the label names.
+func (c *counterVec) inc(values ...string) {
+	key := formatLabels(c.labels, values)
+	c.mu.Lock()
+	c.values[key]++
+	c.labelValues[key] = values
+	c.mu.Unlock()
+}
+
+// set sets the value of a gauge.
+func (c *counterVec) set(value float64, values ...string) {
+	key := formatLabels(c.labels, values)
+	c.mu.Lock()
+	c.values[key] = value
+	c.labelValues[key] = values
+	c.mu.Unlock()
+}
+
+// each calls fn with the label values and the value of each counter.
+func (c *counterVec) each(fn func(values []string, value float64)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, v := range c.values {
+		fn(c.labelValues[key], v)
+	}
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.typ)
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+// latencyBuckets are the upper bounds of the request latency histogram, in
+// seconds.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogramVec is a histogram with a series for each combination of labels.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram // keyed by the formatted label set
+}
+
+type histogram struct {
+	values []string
+	counts []uint64 // by bucket, not cumulative
+	count  uint64
+	sum    float64
+	max    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogram{}}
+}
+
+// observe records v in the series of the given label values.
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := formatLabels(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogram{values: values, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+	s.max = max(s.max, v)
+}
+
+// each calls fn with the label values and the series of each combination.
+func (h *histogramVec) each(fn func(values []string, s histogram)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.series {
+		fn(s.values, *s)
+	}
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels := append(h.labels[:len(h.labels):len(h.labels)], "le")
+	for _, key := range keys {
+		s := h.series[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, append(s.values[:len(s.values):len(s.values)], formatFloat(le))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, append(s.values[:len(s.values):len(s.values)], "+Inf")), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// weatherGauges lists the gauges exported for each location, in metric
+// units as Prometheus recommends base units.
+var weatherGauges = []struct {
+	name  string
+	help  string
+	value func(w *Weather) float64
+}{
+	{"weather_temperature_celsius", "Current temperature.", func(w *Weather) float64 { return w.Temperature }},
+	{"weather_pressure_hpa", "Current atmospheric pressure.", func(w *Weather) float64 { return w.Pressure }},
+	{"weather_humidity_percent", "Current relative humidity.", func(w *Weather) float64 { return w.Humidity }},
+	{"weather_wind_speed_meters_per_second", "Current wind speed.", func(w *Weather) float64 { return w.WindSpeed }},
+	{"weather_wind_direction_degrees", "Current wind direction.", func(w *Weather) float64 { return w.WindDegrees }},
+	{"weather_visibility_meters", "Current visibility.", func(w *Weather) float64 { return w.Visibility }},
+	{"weather_observation_timestamp_seconds", "Time of the current observation.", func(w *Weather) float64 { return float64(w.Time.Unix()) }},
+}
+
+// writeMetrics writes the weather gauges of observations, given in units,
+// followed by the internal counters.
+func writeMetrics(w io.Writer, observations []*Weather, units string) {
+	for _, g := range weatherGauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, o := range observations {
+			fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels([]string{"location"}, []string{o.CityName}), formatFloat(g.value(toMetric(o, units))))
+		}
+	}
+	for _, c := range []*counterVec{apiRequests, apiRetries, cacheLookups, dnsLookups, fetchErrors, circuitState, circuitTransitions} {
+		c.write(w)
+	}
+	requestDuration.write(w)
+}
+
+// metricsHandler serves the metrics of the weather returned by observations.
+func metricsHandler(observations func() []*Weather, units string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, observations(), units)
+	}
+}
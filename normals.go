@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// -vs-normal follows the current weather with how far the temperature is
+// from the long-term average of the day, from the climate normals of the
+// OpenWeather statistics API.
+
+func vsNormalFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.vsNormal, "vs-normal", false, "compare the temperature with the normal of the day, needs the OpenWeather statistics API")
+}
+
+// displayVsNormal prints the deviation of the temperature in w from the
+// normal at city with -vs-normal. Without a subscription to the statistics
+// API there are no normals and a warning is printed instead.
+func (s *session) displayVsNormal(city string, w *Weather) {
+	if !s.opt.vsNormal || s.opt.format != "text" {
+		return
+	}
+	date := localTime(w.Time, w.TimeZone)
+	c, err := s.provider().climate(s.query(city), date.Month(), date.Day())
+	var se *statusError
+	if errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden) {
+		fmt.Fprintln(os.Stderr, "WARNING: vs-normal: climate normals need an OpenWeather subscription with the statistics API")
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: vs-normal: %s\n", redact(err.Error()))
+		return
+	}
+	displayNormalDeviation(os.Stdout, w, c, s.opt.units)
+}
+
+// displayNormalDeviation writes e.g. "+4.2° vs normal (mean 3.1°C on 4
+// Dec)".
+func displayNormalDeviation(w io.Writer, m *Weather, c *ClimateNormal, units string) {
+	temperatureSymbol, _ := unitSymbols(units)
+	date := time.Date(2000, c.Month, c.Day, 0, 0, 0, 0, time.UTC)
+	localFprintf(w, "%+.1f° vs normal (mean %.1f°%s on %s)\n", m.Temperature-c.Mean, c.Mean, temperatureSymbol, localDate(date, "2 Jan"))
+}
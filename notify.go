@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func notifyFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.notify, "notify", false, "raise a desktop notification when an alert rule fires or a weather warning is active")
+}
+
+var errNotifyUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// notificationBodyLength limits the length of notification bodies; alert
+// descriptions can run to several paragraphs.
+const notificationBodyLength = 240
+
+// notify raises a desktop notification when -notify is given. Failures are
+// reported but do not stop the command.
+func (s *session) notify(title, body string) {
+	if !s.opt.notify {
+		return
+	}
+	if err := desktopNotify(title, truncate(body, notificationBodyLength)); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: notify: %s\n", err)
+	}
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return strings.TrimSpace(string(runes[:n-1])) + "…"
+	}
+	return s
+}
+
+// notifying reports whether triggered rules and weather warnings are
+// reported anywhere besides the output.
+func (s *session) notifying() bool {
+	return s.opt.notify || s.opt.slackWebhook != "" || s.hasDiscord() || s.opt.smsTo != ""
+}
+
+// notifyRule reports a triggered rule on the desktop and in chat.
+func (s *session) notifyRule(w *Weather, r ruleResult) {
+	s.notify(fmt.Sprintf("%s: %s", w.CityName, r.Rule), fmt.Sprintf("%s %s, %s%s", weatherIconIdToEmoji(w.Icon), w.Conditions, formatFloat(r.Value), ruleUnitSymbol(r.quantity, s.opt.units)))
+	s.postSlack(slackRule(w, r, s.opt))
+	s.postDiscord(w.CityName, discordRule(w, r, s.opt))
+}
+
+// notifyAlert reports a weather warning on the desktop, in chat and by SMS.
+func (s *session) notifyAlert(location string, a *Alert) {
+	s.notify(fmt.Sprintf("⚠️ %s: %s", location, a.Event), a.Description)
+	s.postSlack(slackAlert(location, a))
+	s.postDiscord(location, discordAlert(location, a))
+	s.sendSMS(smsAlert(location, a))
+}
+
+// notifyActiveAlerts raises a notification for each weather warning of city
+// that is in effect now. Without access to alerts there are none.
+func (s *session) notifyActiveAlerts(city, location string) {
+	alerts, err := s.provider().alerts(s.query(city))
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s: alerts: %s\n", city, redact(err.Error()))
+		return
+	}
+
+	now := time.Now()
+	for i := range alerts {
+		if a := &alerts[i]; !now.Before(a.Start) && now.Before(a.End) {
+			s.notifyAlert(location, a)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Notifications are posted through osascript, which shows them with the
+// user notification settings of Script Editor.
+
+var appleScriptEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func desktopNotify(title, body string) error {
+	script := `display notification "` + appleScriptEscaper.Replace(body) + `" with title "` + appleScriptEscaper.Replace(title) + `"`
+	out, err := exec.Command("osascript", "-e", script).CombinedOutput()
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
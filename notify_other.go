@@ -0,0 +1,7 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package main
+
+func desktopNotify(title, body string) error {
+	return errNotifyUnsupported
+}
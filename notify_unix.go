@@ -0,0 +1,23 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Notifications are sent with notify-send from libnotify.
+
+func desktopNotify(title, body string) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return errors.New("notify-send not found, install libnotify")
+	}
+	out, err := exec.Command(path, "--app-name=weather", title, body).CombinedOutput()
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
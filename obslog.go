@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The -log-file is a flat file alternative to the history database: every
+// fetched observation is appended to it as one JSON object per line, e.g.
+// for jq, a spreadsheet or a log shipper.
+
+// observationLine is a line of the -log-file.
+type observationLine struct {
+	Logged   time.Time `json:"logged"`
+	Provider string    `json:"provider"`
+	Units    string    `json:"units"`
+	*Weather
+}
+
+// logMu serializes the lines written by the goroutines of a process. The
+// file is opened for each line in append mode, so lines written by other
+// processes are not interleaved either and the file can be rotated at any
+// time.
+var logMu sync.Mutex
+
+// appendObservation appends w, given in units, to the log file at path.
+func appendObservation(path string, w *Weather, provider, units string) error {
+	line, err := json.Marshal(observationLine{Logged: time.Now().UTC(), Provider: provider, Units: units, Weather: w})
+	if err != nil {
+		return err
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,924 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// API docs: https://openweathermap.org/api
+const (
+	BASE_URL     = "https://api.openweathermap.org/data/2.5/weather"
+	FORECAST_URL = "https://api.openweathermap.org/data/2.5/forecast"
+	AIR_URL      = "https://api.openweathermap.org/data/2.5/air_pollution"
+	GEOCODE_URL  = "https://api.openweathermap.org/geo/1.0/direct"
+	REVERSE_URL  = "https://api.openweathermap.org/geo/1.0/reverse"
+	ONECALL_URL  = "https://api.openweathermap.org/data/3.0/onecall"
+	CLIMATE_URL  = "https://history.openweathermap.org/data/2.5/aggregated/day"
+	ICON_URL     = "https://openweathermap.org/img/wn/%s@2x.png"
+	TILE_URL     = "https://tile.openweathermap.org/map/%s/%d/%d/%d.png"
+)
+
+// errDryRun is returned instead of making a request in -dry-run mode.
+var errDryRun = errors.New("dry run")
+
+// errRaw is returned instead of the parsed data in -raw mode, once the
+// response has been printed.
+var errRaw = errors.New("raw response printed")
+
+// statusError is returned when the provider answers with a non-200 status.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("request status %d %s", e.code, http.StatusText(e.code))
+}
+
+// notFoundError is returned when a location cannot be resolved, with the
+// descriptions of the locations the name may be a typo of.
+type notFoundError struct {
+	name        string
+	suggestions []string
+}
+
+func (e *notFoundError) Error() string {
+	msg := fmt.Sprintf("location %q not found", e.name)
+	switch n := len(e.suggestions); {
+	case n == 1:
+		msg += fmt.Sprintf(", did you mean %s?", e.suggestions[0])
+	case n > 1:
+		msg += fmt.Sprintf(", did you mean %s or %s?", strings.Join(e.suggestions[:n-1], ", "), e.suggestions[n-1])
+	}
+	return msg
+}
+
+// query identifies the location and presentation of a request.
+type query struct {
+	city  string
+	units string
+	lang  string
+}
+
+// params returns the query parameters of the location. Locations given as
+// coordinates are looked up by them rather than by name.
+func (q query) params() url.Values {
+	v := url.Values{}
+	if lat, lon, ok := parseCoordinates(q.city); ok {
+		v.Set("lat", fmt.Sprint(lat))
+		v.Set("lon", fmt.Sprint(lon))
+	} else {
+		v.Set("q", q.city)
+	}
+	v.Set("units", q.units)
+	v.Set("lang", q.lang)
+	return v
+}
+
+type Weather struct {
+	Time        time.Time `json:"time"`
+	CityName    string    `json:"city"`
+	TimeZone    int       `json:"timezone"`
+	Visibility  float64   `json:"visibility"`
+	Temperature float64   `json:"temperature"`
+	Pressure    float64   `json:"pressure"`
+	Humidity    float64   `json:"humidity"`
+	WindSpeed   float64   `json:"wind_speed"`
+	WindDegrees float64   `json:"wind_degrees"`
+	WindGust    float64   `json:"wind_gust,omitempty"`
+	Conditions  string    `json:"conditions"`
+	Icon        string    `json:"icon"`
+
+	// CachedAt is set when stale cached data is shown because the provider
+	// could not be reached.
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+
+	// query is the location the weather was fetched for, recorded in the
+	// history next to the city name the provider gave.
+	query string
+}
+
+type Forecast struct {
+	CityName string          `json:"city"`
+	TimeZone int             `json:"timezone"`
+	Entries  []ForecastEntry `json:"entries"`
+	CachedAt *time.Time      `json:"cached_at,omitempty"`
+}
+
+// ForecastEntry covers a three hour period starting at Time.
+type ForecastEntry struct {
+	Time          time.Time `json:"time"`
+	Temperature   float64   `json:"temperature"`
+	Pressure      float64   `json:"pressure"`
+	Humidity      float64   `json:"humidity"`
+	WindSpeed     float64   `json:"wind_speed"`
+	WindDegrees   float64   `json:"wind_degrees"`
+	WindGust      float64   `json:"wind_gust"`
+	Precipitation float64   `json:"precipitation"`
+	Probability   float64   `json:"precipitation_probability"`
+	Clouds        float64   `json:"clouds"`
+	Conditions    string    `json:"conditions"`
+	Icon          string    `json:"icon"`
+}
+
+// AirQuality holds the air quality index (1 = good ... 5 = very poor) and
+// pollutant concentrations in μg/m³.
+type AirQuality struct {
+	CityName   string             `json:"city"`
+	Index      int                `json:"aqi"`
+	Components map[string]float64 `json:"components"`
+	CachedAt   *time.Time         `json:"cached_at,omitempty"`
+}
+
+// Alert is a weather warning issued by a national weather service.
+type Alert struct {
+	Sender      string    `json:"sender"`
+	Event       string    `json:"event"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description"`
+}
+
+// UVIndex holds the current UV index and its hourly forecast.
+type UVIndex struct {
+	Current  float64      `json:"current"`
+	Hourly   []UVForecast `json:"hourly"`
+	TimeZone int          `json:"timezone"`
+}
+
+// UVForecast is the UV index of the hour starting at Time.
+type UVForecast struct {
+	Time  time.Time `json:"time"`
+	Index float64   `json:"uvi"`
+}
+
+type Location struct {
+	Name       string            `json:"name"`
+	LocalNames map[string]string `json:"local_names,omitempty"`
+	State      string            `json:"state,omitempty"`
+	Country    string            `json:"country"`
+	Lat        float64           `json:"lat"`
+	Lon        float64           `json:"lon"`
+}
+
+// openWeather is the OpenWeather provider.
+type openWeather struct {
+	keys   *keyPool
+	dryRun bool
+	raw    bool
+
+	// Responses younger than cacheTTL are served from cache, which is nil
+	// when caching is disabled. With noCache fresh responses are still
+	// stored but never read.
+	cache    *cache
+	cacheTTL time.Duration
+	noCache  bool
+
+	// geocoding keeps the results of geocoding for good, see geocache.go.
+	geocoding *cache
+
+	// offline serves cached responses of any age without touching the
+	// network. staleFallback does the same only when the provider cannot
+	// be reached.
+	offline       bool
+	staleFallback bool
+
+	// flights coalesces concurrent identical requests so that long-running
+	// modes issue a single upstream call for them.
+	flights flightGroup[[]byte]
+
+	// slots bounds the requests in flight, whichever command or worker
+	// makes them.
+	slots requestSlots
+
+	// breaker is nil unless a long-running mode enables it.
+	breaker *breaker
+
+	// With reuse, set by the daemon, the current weather decoded from a
+	// response is kept, see currentReused.
+	reuse     bool
+	decodedMu sync.Mutex
+	decoded   map[string]decodedWeather
+	scratch   currentResponse
+
+	// trace receives a line about each request with -vvv, or is nil.
+	trace io.Writer
+}
+
+func newOpenWeather(keys *keyPool, opt *options) *openWeather {
+	ow := &openWeather{
+		keys:          keys,
+		dryRun:        opt.dryRun,
+		raw:           opt.raw,
+		cacheTTL:      opt.cacheTTL,
+		noCache:       opt.noCache,
+		offline:       opt.offline,
+		staleFallback: opt.staleFallback,
+		slots:         newRequestSlots(opt.maxRequests),
+	}
+	if opt.cacheDir != "" {
+		ow.cache = newCache(opt.cacheDir)
+		ow.geocoding = newGeocodingCache(opt.cacheDir)
+	}
+	if opt.breakerFailures > 0 {
+		ow.breaker = newBreaker("openweather", opt.breakerFailures, opt.breakerCooldown)
+	}
+	if opt.verbose >= 3 {
+		ow.trace = os.Stderr
+	}
+	return ow
+}
+
+// tracef writes a line about a request to endpoint to the trace.
+func (ow *openWeather) tracef(endpoint, format string, args ...any) {
+	if ow.trace != nil {
+		fmt.Fprintf(ow.trace, "openweather %s: %s\n", endpointPath(endpoint), redact(fmt.Sprintf(format, args...)))
+	}
+}
+
+func requestURL(endpoint string, params url.Values, apiKey string) string {
+	v := url.Values{}
+	for k, values := range params {
+		v[k] = values
+	}
+	v.Set("appid", apiKey)
+	return endpoint + "?" + v.Encode()
+}
+
+// fetch requests endpoint and returns the raw response body. When a stale
+// cached response is served instead, the time it was fetched is returned
+// too.
+func (ow *openWeather) fetch(endpoint string, params url.Values) ([]byte, *time.Time, error) {
+	if ow.dryRun {
+		fmt.Fprintln(os.Stdout, redact(requestURL(endpoint, params, ow.keys.pick())))
+		return nil, nil, errDryRun
+	}
+
+	key := endpoint + "?" + params.Encode()
+
+	var cached *cacheEntry
+	if ow.cache != nil {
+		cached, _ = ow.cache.get(key)
+	}
+
+	if ow.offline {
+		if cached == nil {
+			cached = ow.cachedInEnglish(endpoint, params)
+		}
+		if cached == nil {
+			fetchErrors.inc(endpointPath(endpoint))
+			return nil, nil, errors.New("no cached data available while offline")
+		}
+		cacheLookups.inc("stale")
+		ow.tracef(endpoint, "offline, cached %s ago", time.Since(cached.Time).Round(time.Second))
+		return cached.Body, &cached.Time, nil
+	}
+
+	if cached != nil && !ow.noCache && !ow.raw && time.Since(cached.Time) < ow.cacheTTL {
+		cacheLookups.inc("hit")
+		ow.tracef(endpoint, "cache hit, cached %s ago", time.Since(cached.Time).Round(time.Second))
+		return cached.Body, nil, nil
+	}
+	if ow.cache != nil {
+		cacheLookups.inc("miss")
+	}
+
+	start := time.Now()
+	body, err := ow.flights.do(key, func() ([]byte, error) {
+		ow.slots.acquire()
+		defer ow.slots.release()
+		if ow.breaker == nil {
+			return ow.fetchWithKeys(endpoint, params)
+		}
+		if err := ow.breaker.allow(); err != nil {
+			return nil, err
+		}
+		body, err := ow.fetchWithKeys(endpoint, params)
+		ow.breaker.record(err)
+		return body, err
+	})
+	if err != nil {
+		// While the circuit is open cached data of any age is better than
+		// none.
+		var open *circuitOpenError
+		if (ow.staleFallback || errors.As(err, &open)) && cached != nil && isUnavailable(err) {
+			cacheLookups.inc("stale")
+			ow.tracef(endpoint, "%s after %s, serving data cached %s ago", err, time.Since(start).Round(time.Microsecond), time.Since(cached.Time).Round(time.Second))
+			return cached.Body, &cached.Time, nil
+		}
+		fetchErrors.inc(endpointPath(endpoint))
+		ow.tracef(endpoint, "%s after %s", err, time.Since(start).Round(time.Microsecond))
+		return nil, nil, err
+	}
+	ow.tracef(endpoint, "%d bytes in %s", len(body), time.Since(start).Round(time.Microsecond))
+
+	if ow.cache != nil {
+		// Caching is best effort, a failed write only costs a request later.
+		ow.cache.put(key, body)
+	}
+	return body, nil, nil
+}
+
+// cachedInEnglish returns the cached response to the request in English,
+// whose condition descriptions are translated locally, when there is none
+// in the language of params.
+func (ow *openWeather) cachedInEnglish(endpoint string, params url.Values) *cacheEntry {
+	if ow.cache == nil || params.Get("lang") == "" || params.Get("lang") == "en" {
+		return nil
+	}
+	en := maps.Clone(params)
+	en.Set("lang", "en")
+	cached, _ := ow.cache.get(endpoint + "?" + en.Encode())
+	return cached
+}
+
+// isUnavailable reports whether err means the provider could not be
+// reached or failed on its side, as opposed to rejecting the request.
+func isUnavailable(err error) bool {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return true
+	}
+	var se *statusError
+	var open *circuitOpenError
+	return errors.As(err, &se) && se.code >= 500 || errors.As(err, &open)
+}
+
+// fetchWithKeys retries with the next key from the pool when the provider
+// rate limits the current one.
+func (ow *openWeather) fetchWithKeys(endpoint string, params url.Values) ([]byte, error) {
+	var err error
+	for i := 0; i < ow.keys.size(); i++ {
+		if i > 0 {
+			apiRetries.inc(endpointPath(endpoint))
+		}
+		apiKey := ow.keys.pick()
+
+		var body []byte
+		start := time.Now()
+		body, err = get(requestURL(endpoint, params, apiKey))
+		requestDuration.observe(time.Since(start).Seconds(), endpointPath(endpoint))
+		apiRequests.inc(endpointPath(endpoint), requestStatus(err))
+
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusTooManyRequests {
+			ow.keys.rateLimited(apiKey)
+			continue
+		}
+		return body, err
+	}
+	return nil, err
+}
+
+// endpointPath returns the path of an endpoint URL, which identifies the
+// endpoint in metrics, e.g. "/data/2.5/weather".
+func endpointPath(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Path
+	}
+	return endpoint
+}
+
+// requestStatus returns the HTTP status of a request as a string, or "error"
+// when the request failed without a response.
+func requestStatus(err error) string {
+	var se *statusError
+	switch {
+	case err == nil:
+		return "200"
+	case errors.As(err, &se):
+		return fmt.Sprint(se.code)
+	default:
+		return "error"
+	}
+}
+
+// bodyBuffers are the buffers responses are read into, reused so that
+// long-running modes do not grow a new buffer for every response.
+var bodyBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func get(u string) ([]byte, error) {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode}
+	}
+
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer bodyBuffers.Put(buf)
+	buf.Reset()
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	// The body outlives the buffer in the cache and the coalesced calls.
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+func (ow *openWeather) fetchJSON(endpoint string, params url.Values, v any) (*time.Time, error) {
+	body, stale, err := ow.fetch(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	if ow.raw {
+		ow.printRaw(endpoint, params, body)
+		// Locations are looked up on the way to the data asked for.
+		if endpoint != GEOCODE_URL && endpoint != REVERSE_URL {
+			return nil, errRaw
+		}
+	}
+	return stale, json.Unmarshal(body, v)
+}
+
+// printRaw prints the response body of a request as it was received, after
+// its URL on stderr so that stdout can be piped to e.g. jq.
+func (ow *openWeather) printRaw(endpoint string, params url.Values, body []byte) {
+	fmt.Fprintf(os.Stderr, "GET %s\n", redact(requestURL(endpoint, params, ow.keys.pick())))
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		body = append(body[:len(body):len(body)], '\n')
+	}
+	os.Stdout.Write(body)
+}
+
+// currentResponse is the response of the current weather API.
+// API docs: https://openweathermap.org/current
+type currentResponse struct {
+	Weather []struct {
+		ID          int    `json:"id"`
+		Main        string `json:"main"`
+		Description string `json:"description"`
+		Icon        string `json:"icon"`
+	} `json:"weather"`
+	Main struct {
+		Temperature float64 `json:"temp"`
+		Pressure    float64 `json:"pressure"`
+		Humidity    float64 `json:"humidity"`
+	} `json:"main"`
+	Wind struct {
+		Speed   float64 `json:"speed"`
+		Degrees float64 `json:"deg"`
+		Gust    float64 `json:"gust"`
+	} `json:"wind"`
+	Time       int64   `json:"dt"`
+	Name       string  `json:"name"`
+	TimeZone   int     `json:"timezone"`
+	Visibility float64 `json:"visibility"`
+}
+
+func (ow *openWeather) current(q query) (*Weather, error) {
+	if ow.reuse && !ow.raw {
+		return ow.currentReused(q)
+	}
+
+	var res currentResponse
+	stale, err := ow.fetchJSON(BASE_URL, q.params(), &res)
+	if err != nil {
+		return nil, ow.locationError(err, q.city)
+	}
+	w := res.weather(q.lang, stale)
+	w.query = q.city
+	return w, nil
+}
+
+// weather returns the weather of the response, cached at stale or fresh
+// when it is nil.
+func (res *currentResponse) weather(lang string, stale *time.Time) *Weather {
+	w := &Weather{CachedAt: stale}
+	w.Time = time.Unix(res.Time, 0).UTC()
+	w.CityName = res.Name
+	w.TimeZone = res.TimeZone
+	w.Visibility = res.Visibility
+	w.Temperature = res.Main.Temperature
+	w.Pressure = res.Main.Pressure
+	w.Humidity = res.Main.Humidity
+	w.WindSpeed = res.Wind.Speed
+	w.WindDegrees = res.Wind.Degrees
+	w.WindGust = res.Wind.Gust
+
+	// @NOTE: Maybe take all?
+	if len(res.Weather) > 0 {
+		w.Conditions = localizeConditions(res.Weather[0].ID, res.Weather[0].Description, lang)
+		w.Icon = res.Weather[0].Icon
+	}
+
+	return w
+}
+
+// decodedWeather is the weather decoded from a response body.
+type decodedWeather struct {
+	body []byte
+	w    *Weather
+}
+
+// currentReused is current for the periodic refreshes of the daemon. The
+// response is decoded into the same scratch value each time, and a
+// response the same as the last one to the request, as between two
+// updates of the provider's data, returns the weather decoded from it
+// before rather than decoding it again. Each caller gets a copy of its
+// own, which it may modify.
+func (ow *openWeather) currentReused(q query) (*Weather, error) {
+	params := q.params()
+	body, stale, err := ow.fetch(BASE_URL, params)
+	if err != nil {
+		return nil, ow.locationError(err, q.city)
+	}
+
+	key := BASE_URL + "?" + params.Encode()
+	ow.decodedMu.Lock()
+	defer ow.decodedMu.Unlock()
+	if d, ok := ow.decoded[key]; ok && stale == nil && bytes.Equal(d.body, body) {
+		w := *d.w
+		return &w, nil
+	}
+
+	// Unmarshal leaves the fields missing from the response as they are
+	// but reuses the backing array of the slice, also the fields of its
+	// elements, so they are cleared of the last response first.
+	clear(ow.scratch.Weather[:cap(ow.scratch.Weather)])
+	ow.scratch = currentResponse{Weather: ow.scratch.Weather[:0]}
+	if err := json.Unmarshal(body, &ow.scratch); err != nil {
+		return nil, ow.locationError(err, q.city)
+	}
+	w := ow.scratch.weather(q.lang, stale)
+	w.query = q.city
+	if stale == nil {
+		if ow.decoded == nil {
+			ow.decoded = map[string]decodedWeather{}
+		}
+		kept := *w
+		ow.decoded[key] = decodedWeather{body, &kept}
+	}
+	return w, nil
+}
+
+func (ow *openWeather) forecast(q query) (*Forecast, error) {
+	// API docs: https://openweathermap.org/forecast5
+	type response struct {
+		List []struct {
+			Time int64 `json:"dt"`
+			Main struct {
+				Temperature float64 `json:"temp"`
+				Pressure    float64 `json:"pressure"`
+				Humidity    float64 `json:"humidity"`
+			} `json:"main"`
+			Weather []struct {
+				ID          int    `json:"id"`
+				Description string `json:"description"`
+				Icon        string `json:"icon"`
+			} `json:"weather"`
+			Wind struct {
+				Speed   float64 `json:"speed"`
+				Degrees float64 `json:"deg"`
+				Gust    float64 `json:"gust"`
+			} `json:"wind"`
+			Probability float64 `json:"pop"`
+			Clouds      struct {
+				All float64 `json:"all"`
+			} `json:"clouds"`
+			Rain struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"rain"`
+			Snow struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"snow"`
+		} `json:"list"`
+		City struct {
+			Name     string `json:"name"`
+			TimeZone int    `json:"timezone"`
+		} `json:"city"`
+	}
+
+	var res response
+	stale, err := ow.fetchJSON(FORECAST_URL, q.params(), &res)
+	if err != nil {
+		return nil, ow.locationError(err, q.city)
+	}
+
+	f := &Forecast{CityName: res.City.Name, TimeZone: res.City.TimeZone, CachedAt: stale}
+	for _, item := range res.List {
+		e := ForecastEntry{
+			Time:          time.Unix(item.Time, 0).UTC(),
+			Temperature:   item.Main.Temperature,
+			Pressure:      item.Main.Pressure,
+			Humidity:      item.Main.Humidity,
+			WindSpeed:     item.Wind.Speed,
+			WindDegrees:   item.Wind.Degrees,
+			WindGust:      item.Wind.Gust,
+			Precipitation: item.Rain.ThreeHours + item.Snow.ThreeHours,
+			Probability:   item.Probability,
+			Clouds:        item.Clouds.All,
+		}
+		if len(item.Weather) > 0 {
+			e.Conditions = localizeConditions(item.Weather[0].ID, item.Weather[0].Description, q.lang)
+			e.Icon = item.Weather[0].Icon
+		}
+		f.Entries = append(f.Entries, e)
+	}
+
+	return f, nil
+}
+
+func (ow *openWeather) air(q query) (*AirQuality, error) {
+	loc, err := ow.locate(q.city)
+	if err != nil {
+		return nil, err
+	}
+
+	// API docs: https://openweathermap.org/api/air-pollution
+	type response struct {
+		List []struct {
+			Main struct {
+				Index int `json:"aqi"`
+			} `json:"main"`
+			Components map[string]float64 `json:"components"`
+		} `json:"list"`
+	}
+
+	params := url.Values{}
+	params.Set("lat", fmt.Sprint(loc.Lat))
+	params.Set("lon", fmt.Sprint(loc.Lon))
+
+	var res response
+	stale, err := ow.fetchJSON(AIR_URL, params, &res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.List) == 0 {
+		return nil, fmt.Errorf("no air quality data for %s", loc.Name)
+	}
+
+	return &AirQuality{
+		CityName:   loc.Name,
+		Index:      res.List[0].Main.Index,
+		Components: res.List[0].Components,
+		CachedAt:   stale,
+	}, nil
+}
+
+// ClimateNormal holds the long-term averages of a day of the year, in the
+// units of the query.
+type ClimateNormal struct {
+	Month         time.Month `json:"month"`
+	Day           int        `json:"day"`
+	Low           float64    `json:"low"`
+	High          float64    `json:"high"`
+	Mean          float64    `json:"mean"`
+	Precipitation float64    `json:"precipitation"`
+	WindSpeed     float64    `json:"wind_speed"`
+	Humidity      float64    `json:"humidity"`
+}
+
+// climate returns the climate normals of a day of the year at the location
+// of q. The statistics API needs a paid OpenWeather subscription.
+func (ow *openWeather) climate(q query, month time.Month, day int) (*ClimateNormal, error) {
+	loc, err := ow.locate(q.city)
+	if err != nil {
+		return nil, err
+	}
+
+	// API docs: https://openweathermap.org/api/statistics-api
+	type response struct {
+		Result struct {
+			Temp struct {
+				Mean       float64 `json:"mean"`
+				AverageMin float64 `json:"average_min"`
+				AverageMax float64 `json:"average_max"`
+			} `json:"temp"`
+			Humidity struct {
+				Mean float64 `json:"mean"`
+			} `json:"humidity"`
+			Wind struct {
+				Mean float64 `json:"mean"`
+			} `json:"wind"`
+			Precipitation struct {
+				Mean float64 `json:"mean"`
+			} `json:"precipitation"`
+		} `json:"result"`
+	}
+
+	params := url.Values{}
+	params.Set("lat", fmt.Sprint(loc.Lat))
+	params.Set("lon", fmt.Sprint(loc.Lon))
+	params.Set("month", fmt.Sprint(int(month)))
+	params.Set("day", fmt.Sprint(day))
+
+	var res response
+	if _, err := ow.fetchJSON(CLIMATE_URL, params, &res); err != nil {
+		return nil, err
+	}
+
+	// The statistics are in Kelvin and m/s whatever the units.
+	r := res.Result
+	celsius := func(k float64) float64 { return k - 273.15 }
+	m := &Weather{Temperature: celsius(r.Temp.Mean), WindSpeed: r.Wind.Mean}
+	low, high := &Weather{Temperature: celsius(r.Temp.AverageMin)}, &Weather{Temperature: celsius(r.Temp.AverageMax)}
+	m, low, high = fromMetric(m, q.units), fromMetric(low, q.units), fromMetric(high, q.units)
+	return &ClimateNormal{
+		Month:         month,
+		Day:           day,
+		Low:           low.Temperature,
+		High:          high.Temperature,
+		Mean:          m.Temperature,
+		Precipitation: r.Precipitation.Mean,
+		WindSpeed:     m.WindSpeed,
+		Humidity:      r.Humidity.Mean,
+	}, nil
+}
+
+// alerts returns the weather alerts in effect for the location. Alerts are
+// only available with a One Call API 3.0 subscription.
+func (ow *openWeather) alerts(q query) ([]Alert, error) {
+	loc, err := ow.locate(q.city)
+	if err != nil {
+		return nil, err
+	}
+
+	// API docs: https://openweathermap.org/api/one-call-3#listsource
+	type response struct {
+		Alerts []struct {
+			Sender      string `json:"sender_name"`
+			Event       string `json:"event"`
+			Start       int64  `json:"start"`
+			End         int64  `json:"end"`
+			Description string `json:"description"`
+		} `json:"alerts"`
+	}
+
+	params := url.Values{}
+	params.Set("lat", fmt.Sprint(loc.Lat))
+	params.Set("lon", fmt.Sprint(loc.Lon))
+	params.Set("exclude", "current,minutely,hourly,daily")
+	params.Set("lang", q.lang)
+
+	var res response
+	if _, err := ow.fetchJSON(ONECALL_URL, params, &res); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]Alert, len(res.Alerts))
+	for i, a := range res.Alerts {
+		alerts[i] = Alert{
+			Sender:      a.Sender,
+			Event:       a.Event,
+			Start:       time.Unix(a.Start, 0).UTC(),
+			End:         time.Unix(a.End, 0).UTC(),
+			Description: a.Description,
+		}
+	}
+	return alerts, nil
+}
+
+// uv returns the UV index at the location of q. Like alerts, it is only
+// available with a One Call API 3.0 subscription.
+func (ow *openWeather) uv(q query) (*UVIndex, error) {
+	loc, err := ow.locate(q.city)
+	if err != nil {
+		return nil, err
+	}
+
+	// API docs: https://openweathermap.org/api/one-call-3#parameter
+	type response struct {
+		TimeZoneOffset int `json:"timezone_offset"`
+		Current        struct {
+			UVI float64 `json:"uvi"`
+		} `json:"current"`
+		Hourly []struct {
+			Time int64   `json:"dt"`
+			UVI  float64 `json:"uvi"`
+		} `json:"hourly"`
+	}
+
+	params := url.Values{}
+	params.Set("lat", fmt.Sprint(loc.Lat))
+	params.Set("lon", fmt.Sprint(loc.Lon))
+	params.Set("exclude", "minutely,daily,alerts")
+
+	var res response
+	if _, err := ow.fetchJSON(ONECALL_URL, params, &res); err != nil {
+		return nil, err
+	}
+
+	uv := &UVIndex{Current: res.Current.UVI, TimeZone: res.TimeZoneOffset}
+	for _, h := range res.Hourly {
+		uv.Hourly = append(uv.Hourly, UVForecast{Time: time.Unix(h.Time, 0).UTC(), Index: h.UVI})
+	}
+	return uv, nil
+}
+
+// tile returns the PNG image of a weather map tile of layer, e.g.
+// "precipitation_new".
+func (ow *openWeather) tile(layer string, zoom, x, y int) ([]byte, error) {
+	// API docs: https://openweathermap.org/api/weathermaps
+	body, _, err := ow.fetch(fmt.Sprintf(TILE_URL, layer, zoom, x, y), url.Values{})
+	return body, err
+}
+
+// geocode returns up to limit locations matching name.
+func (ow *openWeather) geocode(name string, limit int) ([]Location, error) {
+	key := geocodingKey(name, fmt.Sprint("direct ", limit))
+	if locations, ok := ow.cachedLocations(GEOCODE_URL, key); ok {
+		return locations, nil
+	}
+
+	// API docs: https://openweathermap.org/api/geocoding-api
+	params := url.Values{}
+	params.Set("q", name)
+	params.Set("limit", fmt.Sprint(limit))
+
+	var locations []Location
+	if _, err := ow.fetchJSON(GEOCODE_URL, params, &locations); err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, &notFoundError{name: name}
+	}
+	ow.storeLocations(key, locations)
+	return locations, nil
+}
+
+// locate returns the coordinates and name of a location given by name or
+// as coordinates.
+func (ow *openWeather) locate(city string) (*Location, error) {
+	lat, lon, ok := parseCoordinates(city)
+	if !ok {
+		locations, err := ow.geocode(city, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &locations[0], nil
+	}
+
+	key := geocodingKey(formatCoordinates(lat, lon), "reverse")
+	if locations, ok := ow.cachedLocations(REVERSE_URL, key); ok {
+		return &locations[0], nil
+	}
+
+	// API docs: https://openweathermap.org/api/geocoding-api#reverse
+	params := url.Values{}
+	params.Set("lat", fmt.Sprint(lat))
+	params.Set("lon", fmt.Sprint(lon))
+	params.Set("limit", "1")
+
+	var locations []Location
+	if _, err := ow.fetchJSON(REVERSE_URL, params, &locations); err != nil {
+		return nil, err
+	}
+	loc := &Location{Name: formatCoordinates(lat, lon), Lat: lat, Lon: lon}
+	if len(locations) > 0 {
+		loc.Name, loc.State, loc.Country = locations[0].Name, locations[0].State, locations[0].Country
+		ow.storeLocations(key, []Location{*loc})
+	}
+	return loc, nil
+}
+
+// locationError turns the 404 OpenWeather answers for unknown cities into a
+// notFoundError.
+func (ow *openWeather) locationError(err error, name string) error {
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusNotFound {
+		return &notFoundError{name: name, suggestions: ow.suggestLocations(name)}
+	}
+	return err
+}
+
+// suggestLocations returns the descriptions of up to three locations a
+// name not found may be a typo of. The geocoding API matches names more
+// loosely than the weather APIs, e.g. "helsnki" finds Helsinki, so its
+// results with a name close to the one given are taken.
+func (ow *openWeather) suggestLocations(name string) []string {
+	if _, _, ok := parseCoordinates(name); ok || ow.raw {
+		return nil
+	}
+	locations, err := ow.geocode(name, 5)
+	if err != nil {
+		return nil
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return nameDistance(name, &locations[i]) < nameDistance(name, &locations[j])
+	})
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for _, l := range locations {
+		if nameDistance(name, &l) > max(2, utf8.RuneCountInString(name)/3) || seen[l.describe()] {
+			continue
+		}
+		seen[l.describe()] = true
+		suggestions = append(suggestions, l.describe())
+	}
+	return suggestions[:min(len(suggestions), 3)]
+}
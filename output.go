@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func outputFileFlag(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.output, "output", "", "write the output to this file instead of stdout, replacing it atomically")
+}
+
+// pendingOutput is the temporary file the output of the running command goes
+// to with -output, or nil.
+var pendingOutput *os.File
+
+// writeOutput returns run with its standard output redirected to path. The
+// output is written to a temporary file next to path, which replaces path
+// once run returns, so readers such as status bars or web servers never see
+// a partially written file, not even in the middle of a -watch refresh.
+func writeOutput(path string, run func(*session)) func(*session) {
+	return func(s *session) {
+		tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		stdout := os.Stdout
+		os.Stdout, pendingOutput = tmp, tmp
+		defer func() { os.Stdout = stdout }()
+
+		run(s)
+		if err := commitOutput(path); err != nil {
+			exitWithError(fmt.Sprintf("output: %s", err))
+		}
+	}
+}
+
+// commitOutput moves the pending output in place of path.
+func commitOutput(path string) error {
+	tmp := pendingOutput
+	if tmp == nil {
+		return nil
+	}
+	pendingOutput = nil
+	// CreateTemp makes the file readable by its owner only.
+	err := tmp.Chmod(0o644)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// discardOutput removes the pending output of a command that failed, leaving
+// the previous contents of the -output file as they were.
+func discardOutput() {
+	if tmp := pendingOutput; tmp != nil {
+		pendingOutput = nil
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+}
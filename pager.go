@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Like git, the forecast and the weather of many locations are shown in
+// $PAGER, less by default, when they do not fit in the terminal. The output
+// is collected first, since only then is its height known.
+
+func pagerFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.noPager, "no-pager", false, "do not show long output in $PAGER")
+}
+
+// pagedOutput reports whether the output of a paged command goes to the
+// pager: text on a terminal, outside watch mode.
+func pagedOutput(opt *options) bool {
+	if opt.noPager || opt.watch || opt.output != "" || (opt.format != "text" && opt.format != "table") {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+var errNoPager = errors.New("paging is disabled")
+
+// pendingPage collects the output of the running command for the pager, or
+// is nil.
+var pendingPage *page
+
+type page struct {
+	stdout *os.File
+	w      *os.File
+	done   chan struct{}
+	buf    bytes.Buffer
+}
+
+// pageOutput returns run with its standard output collected and shown in
+// the pager if it is taller than the terminal.
+func pageOutput(run func(*session)) func(*session) {
+	return func(s *session) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			run(s)
+			return
+		}
+		p := &page{stdout: os.Stdout, w: w, done: make(chan struct{})}
+		go func() {
+			io.Copy(&p.buf, r)
+			r.Close()
+			close(p.done)
+		}()
+		os.Stdout, pendingPage = w, p
+
+		run(s)
+		showPage(true)
+	}
+}
+
+// showPage writes the collected output to the terminal, through the pager
+// if paging is true and the output does not fit.
+func showPage(paging bool) {
+	p := pendingPage
+	if p == nil {
+		return
+	}
+	pendingPage = nil
+	p.w.Close()
+	<-p.done
+	os.Stdout = p.stdout
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if paging && err == nil && width > 0 && outputRows(p.buf.String(), width) >= height {
+		if runPager(&p.buf) == nil {
+			return
+		}
+	}
+	os.Stdout.Write(p.buf.Bytes())
+}
+
+// outputRows returns how many rows of a terminal width columns wide the
+// output takes, wrapping long lines.
+func outputRows(output string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		rows += max(1, (displayWidth(line)+width-1)/width)
+	}
+	return rows
+}
+
+// runPager shows output in $PAGER, failing only if the pager cannot be
+// started. As with git, an empty PAGER or cat disables paging, and less is
+// told to pass colors through and to quit when the output fits after all.
+func runPager(output io.Reader) error {
+	pager, ok := os.LookupEnv("PAGER")
+	if !ok {
+		pager = "less"
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		return errNoPager
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = output, os.Stdout, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	cmd.Wait()
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// weather photo plans the light for photography: the golden and blue hours
+// of each day of the forecast with the cloud cover expected during them.
+
+// Sun altitudes delimiting the golden hour, from 6° above the horizon to 4°
+// below it, and the blue hour, from 4° to 6° below it.
+const (
+	goldenHourTop  = 6
+	blueHourTop    = -4
+	blueHourBottom = -6
+)
+
+// photoScanStep is the precision of the windows, and photoScanWindow the
+// half day searched for them.
+const (
+	photoScanStep   = time.Minute
+	photoScanWindow = 12 * time.Hour
+)
+
+func photoFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.photoMorning, "sunrise", false, "plan the mornings instead of the evenings")
+}
+
+// lightWindow is a span of time, zero when the sun does not reach the
+// altitudes of the window that day.
+type lightWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// photoDay is the light of a morning or an evening.
+type photoDay struct {
+	Date          string      `json:"date"`
+	Golden        lightWindow `json:"golden_hour"`
+	Blue          lightWindow `json:"blue_hour"`
+	Clouds        *float64    `json:"clouds,omitempty"` // during the golden hour
+	Precipitation float64     `json:"precipitation"`
+	Light         string      `json:"light"`
+	Best          bool        `json:"best,omitempty"`
+
+	score int
+}
+
+// crossing returns the first minute from start within photoScanWindow when
+// the sun passes altitude, going down when falling is set and up otherwise.
+func crossing(start time.Time, lat, lon, altitude float64, falling bool) (time.Time, bool) {
+	prev := sunAltitude(start, lat, lon)
+	for t := start.Add(photoScanStep); t.Before(start.Add(photoScanWindow)); t = t.Add(photoScanStep) {
+		alt := sunAltitude(t, lat, lon)
+		if falling && prev >= altitude && alt < altitude || !falling && prev < altitude && alt >= altitude {
+			return t, true
+		}
+		prev = alt
+	}
+	return time.Time{}, false
+}
+
+// lightWindows returns the golden and blue hours of the morning or the
+// evening starting at start, which is local midnight or noon.
+func lightWindows(start time.Time, lat, lon float64, morning bool) (golden, blue lightWindow) {
+	window := func(from, to float64) lightWindow {
+		// In the evening the sun passes from, then to, going down; in the
+		// morning it passes to, then from, going up.
+		a, b := from, to
+		if morning {
+			a, b = to, from
+		}
+		s, ok1 := crossing(start, lat, lon, a, !morning)
+		e, ok2 := crossing(start, lat, lon, b, !morning)
+		if !ok1 || !ok2 || !e.After(s) {
+			return lightWindow{}
+		}
+		return lightWindow{Start: s, End: e}
+	}
+	return window(goldenHourTop, blueHourTop), window(blueHourTop, blueHourBottom)
+}
+
+// rateLight rates the cloud cover for photography. Some cloud catches the
+// color of the low sun; a clear sky is plain and an overcast one dull.
+func rateLight(clouds, precipitation float64) (string, int) {
+	switch {
+	case precipitation >= 1:
+		return "rain", 0
+	case clouds > 85:
+		return "overcast", 10
+	case clouds > 60:
+		return "mostly cloudy", 50
+	case clouds >= 20:
+		return "dramatic", 100
+	default:
+		return "clear", 70
+	}
+}
+
+// planPhoto returns the mornings or evenings of the days of f at lat, lon,
+// marking the one with the best light.
+func planPhoto(f *Forecast, lat, lon float64, morning bool) []photoDay {
+	var days []photoDay
+	best := -1
+	for _, d := range forecastDays(f) {
+		// The dates of forecastDay are local dates at midnight UTC.
+		start := d.date.Add(-time.Duration(f.TimeZone) * time.Second)
+		if !morning {
+			start = start.Add(12 * time.Hour)
+		}
+		p := photoDay{Date: d.date.Format("2006-01-02")}
+		p.Golden, p.Blue = lightWindows(start, lat, lon, morning)
+		if p.Golden.Start.IsZero() {
+			continue
+		}
+
+		clouds, n := 0.0, 0.0
+		for _, e := range f.Entries {
+			if e.Time.Add(forecastPeriod).After(p.Golden.Start) && e.Time.Before(p.Golden.End) {
+				clouds += e.Clouds
+				p.Precipitation += e.Precipitation
+				n++
+			}
+		}
+		if n == 0 {
+			// The window is beyond the forecast.
+			p.Light = "no forecast"
+		} else {
+			clouds /= n
+			p.Clouds = &clouds
+			p.Light, p.score = rateLight(clouds, p.Precipitation)
+			if best < 0 || p.score > days[best].score {
+				best = len(days)
+			}
+		}
+		days = append(days, p)
+	}
+	if best >= 0 {
+		days[best].Best = true
+	}
+	return days
+}
+
+// runPhoto plans the light at each location for the days of the forecast.
+func runPhoto(s *session) {
+	for _, city := range s.cities() {
+		l, err := s.provider().locate(city)
+		if exitOnError(err) {
+			continue
+		}
+		f, err := s.provider().forecast(s.query(formatCoordinates(l.Lat, l.Lon)))
+		if exitOnError(err) {
+			continue
+		}
+		if f.CityName == "" {
+			f.CityName = l.Name
+		}
+		displayPhoto(os.Stdout, f, planPhoto(f, l.Lat, l.Lon, s.opt.photoMorning), s.opt)
+	}
+}
+
+func displayPhoto(w io.Writer, f *Forecast, days []photoDay, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, struct {
+			City string     `json:"city"`
+			Days []photoDay `json:"days"`
+		}{f.CityName, days}, opt.format)
+		return
+	}
+
+	span := func(l lightWindow) string {
+		if l.Start.IsZero() {
+			return "—"
+		}
+		return localTime(l.Start, f.TimeZone).Format("15:04") + "–" + localTime(l.End, f.TimeZone).Format("15:04")
+	}
+	when := "evenings"
+	if opt.photoMorning {
+		when = "mornings"
+	}
+	localFprintf(w, "%s %s\n\n", f.CityName, when)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "DATE\tGOLDEN HOUR\tBLUE HOUR\tCLOUDS\tLIGHT")
+	for _, d := range days {
+		date, _ := time.Parse("2006-01-02", d.Date)
+		clouds := "—"
+		if d.Clouds != nil {
+			clouds = localSprintf("%.0f%%", *d.Clouds)
+		}
+		light := d.Light
+		if d.Best {
+			light += " ★"
+		}
+		localFprintf(tw, "%s\t%s\t%s\t%s\t%s\n", localDate(date, "Mon Jan 2"), span(d.Golden), span(d.Blue), clouds, light)
+	}
+	tw.Flush()
+}
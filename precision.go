@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSON and CSV carry the values as the provider gave them or as converted,
+// e.g. 15.799999999999997°F, while the text output rounds them for display.
+// -precision rounds the measurements of the machine-readable output too:
+// "1" to one decimal, "1,pressure=0" the pressure apart, and "full", the
+// default, keeps every digit.
+
+func precisionFlag(fs *flag.FlagSet, opt *options) {
+	fs.Func("precision", "decimals of the values in JSON and CSV, e.g. 1 or \"1,pressure=0\" (default full)", func(value string) error {
+		p, err := parsePrecision(value)
+		if err != nil {
+			return err
+		}
+		opt.precision = p
+		return nil
+	})
+}
+
+// precisionFields are the measurements -precision rounds, by their names
+// in the JSON and CSV output.
+var precisionFields = []string{
+	"temperature", "pressure", "humidity", "wind_speed", "wind_gust", "wind_degrees",
+	"visibility", "precipitation", "clouds",
+}
+
+// precision is the number of decimals of each field, or -1 for full
+// precision. The "" entry applies to the fields not given.
+type precision map[string]int
+
+// outputPrecision is the -precision of the running command, applied by
+// writeJSON and formatValue, or nil.
+var outputPrecision precision
+
+// parsePrecision parses a precision such as "1" or "1,pressure=0".
+func parsePrecision(value string) (precision, error) {
+	p := precision{}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, number, ok := strings.Cut(item, "=")
+		if !ok {
+			name, number = "", item
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			if err := checkEnum("field", name, precisionFields); err != nil {
+				return nil, fmt.Errorf("invalid precision %q: %w", value, err)
+			}
+		}
+		n := -1
+		if number = strings.TrimSpace(number); number != "full" {
+			var err error
+			if n, err = strconv.Atoi(number); err != nil || n < 0 || n > 15 {
+				return nil, fmt.Errorf("invalid precision %q, expected decimals or full, e.g. \"1,pressure=0\"", value)
+			}
+		}
+		p[name] = n
+	}
+	return p, nil
+}
+
+func precisionSetting(args []string) (any, error) {
+	value := strings.Join(args, ",")
+	_, err := parsePrecision(value)
+	return value, err
+}
+
+// round returns v rounded to the decimals of field.
+func (p precision) round(field string, v float64) float64 {
+	n, ok := p[field]
+	if !ok {
+		n, ok = p[""]
+	}
+	if !ok || n < 0 {
+		return v
+	}
+	scale := math.Pow10(n)
+	return math.Round(v*scale) / scale
+}
+
+// formatValue formats the value of field for CSV.
+func formatValue(field string, v float64) string {
+	return formatFloat(outputPrecision.round(field, v))
+}
+
+// apply returns v to encode as JSON with its measurements rounded to p,
+// or v itself with full precision.
+func (p precision) apply(v any) any {
+	if len(p) == 0 {
+		return v
+	}
+	b, err := p.roundedJSON(v)
+	if err != nil {
+		// Encoding v reports the error.
+		return v
+	}
+	return b
+}
+
+// precisionPattern matches the measurements in compact JSON. Quotes within
+// strings are escaped, so keys are never matched in them.
+var precisionPattern = regexp.MustCompile(`"(` + strings.Join(precisionFields, "|") + `)":(-?[0-9][0-9.eE+-]*)`)
+
+// roundedJSON returns the JSON encoding of v with its measurements rounded
+// to p. Rounding the encoding keeps the fields in order and leaves apart
+// the numbers, e.g. coordinates, that are not measurements.
+func (p precision) roundedJSON(v any) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	b := precisionPattern.ReplaceAllFunc(bytes.TrimSpace(buf.Bytes()), func(m []byte) []byte {
+		sub := precisionPattern.FindSubmatch(m)
+		f, err := strconv.ParseFloat(string(sub[2]), 64)
+		if err != nil {
+			return m
+		}
+		return fmt.Appendf(nil, `"%s":%s`, sub[1], formatFloat(p.round(string(sub[1]), f)))
+	})
+	return b, nil
+}
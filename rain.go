@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// rainProbability is the probability of precipitation from which a
+// forecast period counts as rainy.
+const rainProbability = 0.5
+
+// forecastPeriod is the length of a forecast entry.
+const forecastPeriod = 3 * time.Hour
+
+// rainOutlook answers whether it will rain at a location within the next
+// hours. Start and End delimit the first rainy spell, in which Precipitation
+// mm is expected.
+type rainOutlook struct {
+	City          string     `json:"city"`
+	Rain          bool       `json:"rain"`
+	Start         *time.Time `json:"start,omitempty"`
+	End           *time.Time `json:"end,omitempty"`
+	Precipitation float64    `json:"precipitation"`
+
+	hours    int
+	timeZone int
+}
+
+// outlookRain finds the first spell of rainy periods of f that starts
+// within hours from now.
+func outlookRain(f *Forecast, hours int, now time.Time) *rainOutlook {
+	o := &rainOutlook{City: f.CityName, hours: hours, timeZone: f.TimeZone}
+	until := now.Add(time.Duration(hours) * time.Hour)
+	for _, e := range f.Entries {
+		start, end := e.Time, e.Time.Add(forecastPeriod)
+		if !end.After(now) || !e.Time.Before(until) {
+			continue
+		}
+		rainy := e.Probability >= rainProbability && e.Precipitation > 0
+		switch {
+		case rainy && o.Start == nil:
+			o.Rain, o.Start, o.End = true, &start, &end
+			o.Precipitation = e.Precipitation
+		case rainy && o.End.Equal(e.Time):
+			o.End = &end
+			o.Precipitation += e.Precipitation
+		case o.Start != nil:
+			// The first spell is over.
+			return o
+		}
+	}
+	return o
+}
+
+// runRain answers "will it rain?" with a line per location. The exit
+// status is 0 when rain is expected at any of them and 1 otherwise.
+func runRain(s *session) {
+	if s.opt.hours < 1 || s.opt.hours > 120 {
+		exitWithUsageError("hours must be between 1 and 120")
+	}
+
+	cities := s.cities()
+	rain := false
+	for _, city := range cities {
+		f, err := s.provider().forecast(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		o := outlookRain(f, s.opt.hours, time.Now())
+		rain = rain || o.Rain
+		displayRain(os.Stdout, o, len(cities) > 1, s.opt)
+	}
+	if !rain && !s.opt.dryRun && !s.opt.raw {
+		exit(1)
+	}
+}
+
+// displayRain writes e.g. "Rain likely between 14:00–17:00, 4mm expected",
+// prefixed with the location when withCity is set.
+func displayRain(w io.Writer, o *rainOutlook, withCity bool, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, o, opt.format)
+		return
+	}
+
+	if withCity {
+		localFprintf(w, "%s: ", o.City)
+	}
+	if !o.Rain {
+		localFprintf(w, "No rain expected in the next %dh\n", o.hours)
+		return
+	}
+	amount := localSprintf("%.0fmm", o.Precipitation)
+	if o.Precipitation < 1 {
+		amount = localSprintf("%.1fmm", o.Precipitation)
+	}
+	start, end := localTime(*o.Start, o.timeZone), localTime(*o.End, o.timeZone)
+	day := ""
+	if start.YearDay() != localTime(time.Now(), o.timeZone).YearDay() {
+		day = start.Format("Mon ")
+	}
+	localFprintf(w, "Rain likely between %s%s–%s, %s expected\n", day, start.Format("15:04"), end.Format("15:04"), amount)
+}
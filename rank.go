@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// weather rank orders locations by a score computed from their current
+// weather, highest first: a quantity such as "temp", or an expression of
+// them such as "temp - 2*wind". A leading minus ranks the lowest first.
+
+func rankFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.rankBy, "by", "temp", "the quantity or expression to rank by, e.g. wind or \"temp - 2*wind\"")
+	fs.BoolVar(&opt.rankAll, "all", false, "rank the favorites too when locations are given")
+}
+
+// scoreQuantities maps the names usable in scores to the value in w, in the
+// units of the weather.
+var scoreQuantities = map[string]func(w *Weather) float64{
+	"temp":        func(w *Weather) float64 { return w.Temperature },
+	"temperature": func(w *Weather) float64 { return w.Temperature },
+	"wind":        func(w *Weather) float64 { return w.WindSpeed },
+	"wind_speed":  func(w *Weather) float64 { return w.WindSpeed },
+	"humidity":    func(w *Weather) float64 { return w.Humidity },
+	"pressure":    func(w *Weather) float64 { return w.Pressure },
+	"visibility":  func(w *Weather) float64 { return w.Visibility },
+}
+
+// score is a parsed -by expression.
+type score func(w *Weather) float64
+
+// scoreParser parses a -by expression by recursive descent:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = "-" unary | "(" sum ")" | number | quantity
+type scoreParser struct {
+	text   string
+	tokens []string
+}
+
+func parseScore(text string) (score, error) {
+	p := &scoreParser{text: text, tokens: tokenizeScore(text)}
+	sc, err := p.sum()
+	if err == nil && len(p.tokens) > 0 {
+		err = fmt.Errorf("invalid score %q, unexpected %q", text, p.tokens[0])
+	}
+	return sc, err
+}
+
+// tokenizeScore splits text into numbers, names and operators.
+func tokenizeScore(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		r := rune(text[i])
+		j := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsDigit(r) || r == '.':
+			for j < len(text) && (unicode.IsDigit(rune(text[j])) || text[j] == '.') {
+				j++
+			}
+		case unicode.IsLetter(r) || r == '_':
+			for j < len(text) && (unicode.IsLetter(rune(text[j])) || text[j] == '_') {
+				j++
+			}
+		}
+		tokens = append(tokens, text[i:j])
+		i = j
+	}
+	return tokens
+}
+
+func (p *scoreParser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+func (p *scoreParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *scoreParser) sum() (score, error) {
+	sc, err := p.product()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.next()
+		var right score
+		if right, err = p.product(); err != nil {
+			break
+		}
+		left := sc
+		if op == "+" {
+			sc = func(w *Weather) float64 { return left(w) + right(w) }
+		} else {
+			sc = func(w *Weather) float64 { return left(w) - right(w) }
+		}
+	}
+	return sc, err
+}
+
+func (p *scoreParser) product() (score, error) {
+	sc, err := p.unary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.next()
+		var right score
+		if right, err = p.unary(); err != nil {
+			break
+		}
+		left := sc
+		if op == "*" {
+			sc = func(w *Weather) float64 { return left(w) * right(w) }
+		} else {
+			sc = func(w *Weather) float64 { return left(w) / right(w) }
+		}
+	}
+	return sc, err
+}
+
+func (p *scoreParser) unary() (score, error) {
+	t := p.next()
+	switch {
+	case t == "-":
+		sc, err := p.unary()
+		return func(w *Weather) float64 { return -sc(w) }, err
+	case t == "(":
+		sc, err := p.sum()
+		if err == nil && p.next() != ")" {
+			err = fmt.Errorf("invalid score %q, missing )", p.text)
+		}
+		return sc, err
+	case t != "" && (unicode.IsDigit(rune(t[0])) || t[0] == '.'):
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score %q, bad number %q", p.text, t)
+		}
+		return func(*Weather) float64 { return v }, nil
+	}
+	if q, ok := scoreQuantities[strings.ToLower(t)]; ok {
+		return q, nil
+	}
+	if t == "" {
+		return nil, fmt.Errorf("invalid score %q, expected e.g. \"temp - 2*wind\"", p.text)
+	}
+	return nil, fmt.Errorf("invalid score %q, unknown quantity %q", p.text, t)
+}
+
+// rankedResult is a location of the ranking.
+type rankedResult struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+	batchResult
+}
+
+// rankLocations returns the locations to rank: the arguments, each one a
+// location, and the locations of -f, or the favorites when there are none
+// or with -all.
+func (s *session) rankLocations() []string {
+	var cities []string
+	for _, arg := range s.args() {
+		cities = append(cities, s.citiesFrom([]string{arg})[0])
+	}
+	if s.opt.batchFile != "" {
+		cities = append(cities, s.readLocations(s.opt.batchFile)...)
+	}
+	if len(cities) == 0 || s.opt.rankAll {
+		cities = append(cities, s.cfg.list("favorites")...)
+	}
+	if len(cities) == 0 {
+		exitWithUsageError("no locations to rank, give them as arguments, with -f or as favorites")
+	}
+	return cities
+}
+
+// runRank fetches the current weather of the locations and lists them by
+// -by, highest score first. Failed locations are reported and left out, and
+// make the exit status 1.
+func runRank(s *session) {
+	sc, err := parseScore(s.opt.rankBy)
+	if err != nil {
+		exitWithUsageError(err.Error())
+	}
+
+	var ranked []rankedResult
+	failed := false
+	for _, r := range s.fetchBatch(s.rankLocations()) {
+		if errors.Is(r.err, errDryRun) || errors.Is(r.err, errRaw) {
+			return
+		}
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", r.Query, r.Error)
+			failed = true
+			continue
+		}
+		s.recordHistory(r.Weather, s.opt.units)
+		ranked = append(ranked, rankedResult{Score: sc(r.Weather), batchResult: r})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+
+	displayRanking(os.Stdout, ranked, s.opt)
+	if failed {
+		exit(exitFailure)
+	}
+}
+
+func displayRanking(w io.Writer, ranked []rankedResult, opt *options) {
+	if isJSON(opt.format) {
+		if opt.format == "jsonl" {
+			for _, r := range ranked {
+				writeJSON(w, r, opt.format)
+			}
+			return
+		}
+		writeJSON(w, ranked, opt.format)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "#\tLOCATION\tSCORE\tTEMP\tCONDITIONS\tWIND\tHUMIDITY")
+	for _, r := range ranked {
+		localFprintf(tw, "%d\t%s\t%s\t%.0f°%s\t%s %s\t%.1f %s\t%.0f%%\n",
+			r.Rank, r.CityName, strconv.FormatFloat(r.Score, 'f', 1, 64), r.Temperature, temperatureSymbol,
+			weatherIconIdToEmoji(r.Icon), r.Conditions, r.WindSpeed, windSpeedSymbol, r.Humidity)
+	}
+	tw.Flush()
+}
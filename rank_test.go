@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenizeScore(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"temp", []string{"temp"}},
+		{"temp - 2*wind", []string{"temp", "-", "2", "*", "wind"}},
+		{"-(wind_speed/2.5)", []string{"-", "(", "wind_speed", "/", "2.5", ")"}},
+		{"  .5+Humidity ", []string{".5", "+", "Humidity"}},
+		{"temp^2", []string{"temp", "^", "2"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenizeScore(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenizeScore(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	w := &Weather{Temperature: 20, WindSpeed: 4, Humidity: 50, Pressure: 1010, Visibility: 10000}
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"temp", 20},
+		{"TEMPERATURE", 20},
+		{"wind_speed", 4},
+		{"-temp", -20},
+		{"--temp", 20},
+		{"temp - -wind", 24},
+		{"-wind * 2", -8},
+		{"temp - 2*wind", 12},
+		{"2*wind + temp", 28},
+		{"(temp - wind) / 2", 8},
+		{"temp - wind - humidity", -34},
+		{"temp / wind / 5", 1},
+		{"-(temp + wind)", -24},
+		{"humidity/100 + pressure - visibility/1000", 1000.5},
+		{"1.5", 1.5},
+		{".5 * temp", 10},
+	}
+	for _, tt := range tests {
+		sc, err := parseScore(tt.text)
+		if err != nil {
+			t.Errorf("parseScore(%q): %s", tt.text, err)
+			continue
+		}
+		if got := sc(w); got != tt.want {
+			t.Errorf("parseScore(%q) scores %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseScoreErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"", `invalid score "", expected e.g. "temp - 2*wind"`},
+		{"-", `invalid score "-", expected e.g. "temp - 2*wind"`},
+		{"temp +", `invalid score "temp +", expected e.g. "temp - 2*wind"`},
+		{"temp * ", `invalid score "temp * ", expected e.g. "temp - 2*wind"`},
+		{"snow", `invalid score "snow", unknown quantity "snow"`},
+		{"temp - aqi", `invalid score "temp - aqi", unknown quantity "aqi"`},
+		{"1..2", `invalid score "1..2", bad number "1..2"`},
+		{"(temp - wind", `invalid score "(temp - wind", missing )`},
+		{"temp)", `invalid score "temp)", unexpected ")"`},
+		{"temp wind", `invalid score "temp wind", unexpected "wind"`},
+		{"temp^2", `invalid score "temp^2", unexpected "^"`},
+	}
+	for _, tt := range tests {
+		_, err := parseScore(tt.text)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseScore(%q): got error %v, want %s", tt.text, err, tt.err)
+		}
+	}
+}
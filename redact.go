@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// secrets holds the values that must never appear in user-visible output.
+var secrets struct {
+	mu     sync.Mutex
+	values []string
+}
+
+var apiKeyQueryPattern = regexp.MustCompile(`(appid=)[^&\s"]+`)
+
+// registerSecret marks value, and its URL-escaped form, for redaction. Very
+// short values are ignored as masking them would mangle unrelated output.
+func registerSecret(value string) {
+	if len(value) < 4 {
+		return
+	}
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	secrets.values = append(secrets.values, value)
+	if escaped := url.QueryEscape(value); escaped != value {
+		secrets.values = append(secrets.values, escaped)
+	}
+}
+
+// redact masks registered secrets and any appid query parameter in s.
+func redact(s string) string {
+	secrets.mu.Lock()
+	for _, v := range secrets.values {
+		s = strings.ReplaceAll(s, v, "REDACTED")
+	}
+	secrets.mu.Unlock()
+	return apiKeyQueryPattern.ReplaceAllString(s, "${1}REDACTED")
+}
+
+func redactURL(u *url.URL) string {
+	return redact(u.String())
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// weather report writes the current weather of a list of sites, e.g. the
+// facilities of a company, read from a CSV file, as one combined report.
+// The file has a header naming its columns: a location, or lat and lon,
+// and optionally an id to tell the sites apart in the report.
+
+func reportFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.batchFile, "f", "", "read the sites from this CSV file, - for stdin")
+	fs.IntVar(&opt.parallel, "parallel", 4, "how many sites are fetched at once")
+}
+
+// reportFormatFlag accepts the report output formats or the path of a CSV
+// file to write the report into.
+func reportFormatFlag(opt *options) func(string) error {
+	return func(value string) error {
+		if strings.HasSuffix(strings.ToLower(value), ".csv") {
+			opt.format, opt.output = "csv", value
+			return nil
+		}
+		return enumFlag(&opt.format, "output format", append(reportFormatValues, "<file>.csv"))(value)
+	}
+}
+
+// reportColumns maps the accepted header names to the columns they name.
+var reportColumns = map[string]string{
+	"id":        "id",
+	"site":      "location",
+	"name":      "location",
+	"city":      "location",
+	"location":  "location",
+	"lat":       "lat",
+	"latitude":  "lat",
+	"lon":       "lon",
+	"lng":       "lon",
+	"longitude": "lon",
+}
+
+// reportSite is a row of the sites file. Query is what is fetched: the
+// coordinates when given, the location otherwise.
+type reportSite struct {
+	ID       string
+	Location string
+	Lat, Lon *float64
+	Query    string
+}
+
+// readSites reads the sites file at path. Lines starting with # are
+// skipped, and aliases in the location column are expanded.
+func (s *session) readSites(path string) []reportSite {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			exitWithError(err.Error())
+		}
+		defer f.Close()
+	}
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		exitWithError(err.Error())
+	}
+	if len(rows) == 0 {
+		exitWithError("no sites in " + path)
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		if column, ok := reportColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[column] = i
+		}
+	}
+	_, hasLocation := columns["location"]
+	_, hasLat := columns["lat"]
+	_, hasLon := columns["lon"]
+	if !hasLocation && !(hasLat && hasLon) {
+		exitWithError(path + ": the header must name a location column, or lat and lon columns")
+	}
+	field := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var sites []reportSite
+	for n, row := range rows[1:] {
+		site := reportSite{ID: field(row, "id"), Location: field(row, "location")}
+		lat, lon := field(row, "lat"), field(row, "lon")
+		switch {
+		case lat != "" || lon != "":
+			latitude, longitude, ok := parseCoordinates(lat + "," + lon)
+			if !ok {
+				exitWithError(fmt.Sprintf("%s:%d: invalid coordinates %q, %q", path, n+2, lat, lon))
+			}
+			site.Lat, site.Lon = &latitude, &longitude
+			site.Query = formatCoordinates(latitude, longitude)
+		case site.Location != "":
+			site.Query = site.Location
+			if alias, ok := s.cfg.string("aliases." + site.Location); ok {
+				site.Query = alias
+			}
+		default:
+			if strings.Join(row, "") == "" {
+				continue
+			}
+			exitWithError(fmt.Sprintf("%s:%d: no location or coordinates", path, n+2))
+		}
+		sites = append(sites, site)
+	}
+	if len(sites) == 0 {
+		exitWithError("no sites in " + path)
+	}
+	return sites
+}
+
+// reportRow is the outcome for a site of the report.
+type reportRow struct {
+	ID       string   `json:"id,omitempty"`
+	Site     string   `json:"site,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	*Weather `json:"weather,omitempty"`
+	Units    string `json:"units,omitempty"`
+	unitValues
+	Error string `json:"error,omitempty"`
+}
+
+// runReport fetches the current weather of the sites of -f, -parallel at a
+// time, and writes the report in the order of the file. Failed sites do not
+// stop the others, but make the exit status 1.
+func runReport(s *session) {
+	if s.opt.batchFile == "" {
+		exitWithUsageError("usage: weather report -f <sites.csv>")
+	}
+	sites := s.readSites(s.opt.batchFile)
+	queries := make([]string, len(sites))
+	for i, site := range sites {
+		queries[i] = site.Query
+	}
+
+	rows := make([]reportRow, len(sites))
+	failed := false
+	for i, r := range s.fetchBatch(queries) {
+		if errors.Is(r.err, errDryRun) || errors.Is(r.err, errRaw) {
+			return
+		}
+		site := sites[i]
+		rows[i] = reportRow{ID: site.ID, Site: site.Location, Lat: site.Lat, Lon: site.Lon, Weather: r.Weather, Units: r.Units, unitValues: r.unitValues, Error: r.Error}
+		if r.err != nil {
+			failed = true
+			continue
+		}
+		s.recordHistory(r.Weather, s.opt.units)
+	}
+
+	displayReport(os.Stdout, rows, s.opt)
+	if failed {
+		if err := commitOutput(s.opt.output); err != nil {
+			exitWithError(fmt.Sprintf("output: %s", err))
+		}
+		exit(1)
+	}
+}
+
+// displayReport writes the rows of the report. CSV and JSON carry the
+// errors of failed sites in an error field; the table reports them on
+// stderr.
+func displayReport(w io.Writer, rows []reportRow, opt *options) {
+	switch opt.format {
+	case "json":
+		writeJSON(w, rows, opt.format)
+		return
+	case "jsonl":
+		for _, r := range rows {
+			writeJSON(w, r, opt.format)
+		}
+		return
+	case "csv":
+		coordinate := func(v *float64) string {
+			if v == nil {
+				return ""
+			}
+			return formatFloat(*v)
+		}
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"id", "site", "lat", "lon", "location", "time", "units", "temperature", "humidity", "wind_speed", "wind_gust", "wind_degrees", "pressure", "visibility", "conditions", "error"}, unitColumns...))
+		for _, r := range rows {
+			row := []string{r.ID, r.Site, coordinate(r.Lat), coordinate(r.Lon)}
+			if r.Weather == nil {
+				row = append(row, "", "", "", "", "", "", "", "", "", "", "", r.Error)
+				cw.Write(append(row, r.csv()...))
+				continue
+			}
+			row = append(row,
+				r.CityName, r.Time.Format(time.RFC3339), r.Units,
+				formatValue("temperature", r.Temperature), formatValue("humidity", r.Humidity),
+				formatValue("wind_speed", r.WindSpeed), formatValue("wind_gust", r.WindGust), formatValue("wind_degrees", r.WindDegrees),
+				formatValue("pressure", r.Pressure), formatValue("visibility", r.Visibility), r.Conditions, "",
+			)
+			cw.Write(append(row, r.csv()...))
+		}
+		cw.Flush()
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "ID\tSITE\tTEMP\tCONDITIONS\tHUMIDITY\tWIND\tTIME")
+	for _, r := range rows {
+		site := r.Site
+		if site == "" && r.Lat != nil {
+			site = formatCoordinates(*r.Lat, *r.Lon)
+		}
+		if r.Weather == nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %s\n", site, r.Error)
+			continue
+		}
+		if site == "" {
+			site = r.CityName
+		}
+		localFprintf(tw, "%s\t%s\t%.0f°%s\t%s %s\t%.0f%%\t%.1f %s\t%s%s\n",
+			r.ID, site, r.Temperature, temperatureSymbol, weatherIconIdToEmoji(r.Icon), r.Conditions,
+			r.Humidity, r.WindSpeed, windSpeedSymbol,
+			localTime(r.Time, r.TimeZone).Format("15:04"), staleNote(r.CachedAt))
+	}
+	tw.Flush()
+}
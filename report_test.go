@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSites writes content to a sites file and returns its path.
+func testSites(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "sites.csv")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// exitMessage runs f, which is expected to exit with an error, and returns
+// the error printed. Exits panic as during a refresh of -watch.
+func exitMessage(t *testing.T, f func()) (message string) {
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	os.Stderr, stderr = stderr, os.Stderr
+	watching = true
+	defer func() {
+		watching = false
+		os.Stderr, stderr = stderr, os.Stderr
+		if _, ok := recover().(watchExit); !ok {
+			message = ""
+			return
+		}
+		b, _ := os.ReadFile(stderr.Name())
+		message = strings.TrimSpace(strings.TrimPrefix(string(b), "ERROR: "))
+	}()
+	f()
+	return ""
+}
+
+func TestReadSites(t *testing.T) {
+	lat, lon := 60.17, 24.94
+	tests := []struct {
+		name    string
+		content string
+		want    []reportSite
+	}{
+		{
+			name:    "locations",
+			content: "id,city\n1,Helsinki\n2, Oulu \n",
+			want: []reportSite{
+				{ID: "1", Location: "Helsinki", Query: "Helsinki"},
+				{ID: "2", Location: "Oulu", Query: "Oulu"},
+			},
+		},
+		{
+			name:    "coordinates",
+			content: "Latitude,Longitude\n60.17,24.94\n",
+			want:    []reportSite{{Lat: &lat, Lon: &lon, Query: "60.1700,24.9400"}},
+		},
+		{
+			name:    "rows without coordinates",
+			content: "site,lat,lon\nHQ,60.17,24.94\nOulu,,\nTampere\n",
+			want: []reportSite{
+				{Location: "HQ", Lat: &lat, Lon: &lon, Query: "60.1700,24.9400"},
+				{Location: "Oulu", Query: "Oulu"},
+				{Location: "Tampere", Query: "Tampere"},
+			},
+		},
+		{
+			name:    "aliases",
+			content: "name,notes\nhome,our office\nwork\n",
+			want: []reportSite{
+				{Location: "home", Query: "Helsinki,FI"},
+				{Location: "work", Query: "work"},
+			},
+		},
+		{
+			name:    "comments and blank rows",
+			content: "# sites\nid,location\n# closed\n,\n3,Espoo\n",
+			want:    []reportSite{{ID: "3", Location: "Espoo", Query: "Espoo"}},
+		},
+	}
+	s := &session{opt: &options{}, cfg: &config{values: map[string]any{"aliases.home": "Helsinki,FI"}}}
+	for _, tt := range tests {
+		got := s.readSites(testSites(t, tt.content))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadSitesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"empty", "", "no sites in %s"},
+		{"only a header", "id,location\n", "no sites in %s"},
+		{"no location column", "id,lat\n1,60.17\n", "%s: the header must name a location column, or lat and lon columns"},
+		{"no location or coordinates", "id,location,lat,lon\n1,,,\n", "%s:2: no location or coordinates"},
+		{"latitude without longitude", "location,lat,lon\nHQ,60.17,\n", `%s:2: invalid coordinates "60.17", ""`},
+		{"invalid coordinates", "lat,lon\n60.17,24.94\n91,24.94\n", `%s:3: invalid coordinates "91", "24.94"`},
+		{"bad quoting", "location\n\"Helsinki\n", `parse error on line 2, column 11: extraneous or missing " in quoted-field`},
+	}
+	s := &session{opt: &options{}, cfg: &config{values: map[string]any{}}}
+	for _, tt := range tests {
+		path := testSites(t, tt.content)
+		want := tt.err
+		if strings.Contains(want, "%s") {
+			want = fmt.Sprintf(want, path)
+		}
+		if got := exitMessage(t, func() { s.readSites(path) }); got != want {
+			t.Errorf("%s: got error %q, want %q", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// weather route shows the forecast along a road trip: the weather expected
+// at each waypoint at the time of arrival there, estimated from the
+// departure time and an average speed.
+
+// routeSampleDistance is how far apart the points of a GPX track are
+// sampled, in km.
+const routeSampleDistance = 50
+
+func routeFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.gpxFile, "gpx", "", "read the route from a GPX file instead of the arguments")
+	fs.Float64Var(&opt.speed, "speed", 70, "average speed in km/h, or mph with -units imperial, over the straight line between waypoints")
+	fs.Func("depart", "departure time, e.g. 14:30 or 2024-07-10T08:00 (default now)", departFlag(&opt.depart))
+}
+
+// departFlag parses a departure time given as a local time of day, today or
+// tomorrow if it has passed, or as a local date and time.
+func departFlag(dst *time.Time) func(string) error {
+	return func(value string) error {
+		now := time.Now()
+		if t, err := time.ParseInLocation("15:04", value, time.Local); err == nil {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+			if t.Before(now.Add(-time.Hour)) {
+				t = t.AddDate(0, 0, 1)
+			}
+			*dst = t
+			return nil
+		}
+		for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", time.RFC3339} {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				*dst = t
+				return nil
+			}
+		}
+		return errors.New("expected e.g. 14:30 or 2024-07-10T08:00")
+	}
+}
+
+// waypoint is a point of the route. Name is empty for points of a GPX
+// track, which are named after the nearest place in the forecast.
+type waypoint struct {
+	Name     string  `json:"name"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Distance float64 `json:"distance"` // from the start, in km or mi
+}
+
+// routeLeg is the forecast at a waypoint at the estimated time of arrival.
+// Forecast is nil beyond the forecast range.
+type routeLeg struct {
+	waypoint
+	Arrival  time.Time      `json:"arrival"`
+	Forecast *ForecastEntry `json:"forecast,omitempty"`
+
+	timeZone int
+}
+
+// distance is the great-circle distance between two points in km.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371
+	rad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Name string  `xml:"name"`
+}
+
+// readGPX reads the route of a GPX file: its route points, or its track
+// sampled every routeSampleDistance km, or else its waypoints.
+// https://www.topografix.com/GPX/1/1/
+func readGPX(path string) ([]waypoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Waypoints []gpxPoint `xml:"wpt"`
+		Routes    []struct {
+			Points []gpxPoint `xml:"rtept"`
+		} `xml:"rte"`
+		Tracks []struct {
+			Segments []struct {
+				Points []gpxPoint `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+	}
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var route, track []gpxPoint
+	for _, r := range doc.Routes {
+		route = append(route, r.Points...)
+	}
+	for _, t := range doc.Tracks {
+		for _, seg := range t.Segments {
+			track = append(track, seg.Points...)
+		}
+	}
+	switch {
+	case len(route) > 0:
+	case len(track) > 0:
+		route = sampleTrack(track)
+	default:
+		route = doc.Waypoints
+	}
+	if len(route) < 2 {
+		return nil, fmt.Errorf("%s: a route needs at least two points", path)
+	}
+
+	points := make([]waypoint, len(route))
+	for i, p := range route {
+		points[i] = waypoint{Name: p.Name, Lat: p.Lat, Lon: p.Lon}
+	}
+	return points, nil
+}
+
+// sampleTrack keeps the first and the last point of a track and the points
+// at least routeSampleDistance km along the track from the previous kept one.
+func sampleTrack(track []gpxPoint) []gpxPoint {
+	kept := []gpxPoint{track[0]}
+	along := 0.0
+	for i := 1; i < len(track); i++ {
+		along += distance(track[i-1].Lat, track[i-1].Lon, track[i].Lat, track[i].Lon)
+		if along >= routeSampleDistance || i == len(track)-1 {
+			kept = append(kept, track[i])
+			along = 0
+		}
+	}
+	return kept
+}
+
+// routeWaypoints returns the waypoints of -gpx or of the arguments, each
+// one a location.
+func (s *session) routeWaypoints() []waypoint {
+	if s.opt.gpxFile != "" {
+		if len(s.args()) > 0 {
+			exitWithUsageError("give the route either with -gpx or as arguments")
+		}
+		points, err := readGPX(s.opt.gpxFile)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		return points
+	}
+
+	if len(s.args()) < 2 {
+		exitWithUsageError("usage: weather route <city> <city>... or weather route -gpx <file>")
+	}
+	var points []waypoint
+	for _, arg := range s.args() {
+		city := s.citiesFrom([]string{arg})[0]
+		if city == arg {
+			city = s.disambiguate(city)
+		}
+		l, err := s.provider().locate(city)
+		if exitOnError(err) {
+			// A dry run prints the requests of the waypoints only.
+			continue
+		}
+		points = append(points, waypoint{Name: l.Name, Lat: l.Lat, Lon: l.Lon})
+	}
+	return points
+}
+
+// runRoute estimates the arrival time at each waypoint and shows the
+// forecast entry closest to it.
+func runRoute(s *session) {
+	if s.opt.speed <= 0 {
+		exitWithUsageError("speed must be positive")
+	}
+	depart := s.opt.depart
+	if depart.IsZero() {
+		depart = time.Now().Truncate(time.Minute)
+	}
+
+	points := s.routeWaypoints()
+	if s.opt.dryRun || s.opt.raw {
+		return
+	}
+	perUnit := 1.0 // km per distance unit
+	if s.opt.units == "imperial" {
+		perUnit = 1.609344
+	}
+
+	var legs []routeLeg
+	for i, p := range points {
+		if i > 0 {
+			prev := points[i-1]
+			p.Distance = prev.Distance + distance(prev.Lat, prev.Lon, p.Lat, p.Lon)/perUnit
+			points[i] = p
+		}
+		leg := routeLeg{waypoint: p, Arrival: depart.Add(time.Duration(p.Distance / s.opt.speed * float64(time.Hour)))}
+
+		f, err := s.provider().forecast(s.query(formatCoordinates(p.Lat, p.Lon)))
+		if exitOnError(err) {
+			return
+		}
+		if leg.Name == "" {
+			leg.Name = f.CityName
+		}
+		leg.timeZone = f.TimeZone
+		// An entry covers the period from its time, so the one whose middle
+		// is closest to the arrival is used, within a period.
+		best := forecastPeriod
+		for j, e := range f.Entries {
+			if d := absDuration(e.Time.Add(forecastPeriod / 2).Sub(leg.Arrival)); d <= best {
+				leg.Forecast, best = &f.Entries[j], d
+			}
+		}
+		legs = append(legs, leg)
+	}
+	displayRoute(os.Stdout, legs, s.opt)
+}
+
+func displayRoute(w io.Writer, legs []routeLeg, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, legs, opt.format)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	distanceSymbol := "km"
+	if opt.units == "imperial" {
+		distanceSymbol = "mi"
+	}
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "WAYPOINT\tDISTANCE\tARRIVAL\tTEMP\tCONDITIONS\tWIND\tPRECIPITATION")
+	for _, l := range legs {
+		arrival := localTime(l.Arrival, l.timeZone).Format("Mon 15:04")
+		if l.Forecast == nil {
+			localFprintf(tw, "%s\t%.0f %s\t%s\tbeyond the forecast\n", l.Name, l.Distance, distanceSymbol, arrival)
+			continue
+		}
+		e := l.Forecast
+		localFprintf(tw, "%s\t%.0f %s\t%s\t%.0f°%s\t%s %s\t%.1f %s\t%.1f mm (%.0f%%)\n",
+			l.Name, l.Distance, distanceSymbol, arrival, e.Temperature, temperatureSymbol,
+			weatherIconIdToEmoji(e.Icon), e.Conditions, e.WindSpeed, windSpeedSymbol, e.Precipitation, e.Probability*100)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Alert rules are comparisons such as "temp < 0", "wind > 15 m/s" or
+// "aqi >= 4", listed under rules in the config file. Values without a unit
+// are in the units of -units.
+
+// ruleQuantities maps the names usable in rules to the quantity they test.
+var ruleQuantities = map[string]string{
+	"temp":        "temperature",
+	"temperature": "temperature",
+	"wind":        "wind_speed",
+	"wind_speed":  "wind_speed",
+	"humidity":    "humidity",
+	"pressure":    "pressure",
+	"visibility":  "visibility",
+	"dew_point":   "dew_point",
+	"dewpoint":    "dew_point",
+	"aqi":         "aqi",
+}
+
+// ruleUnits convert a value given in a unit into the metric unit of the
+// quantity.
+var ruleUnits = map[string]map[string]func(float64) float64{
+	"temperature": {
+		"c": func(v float64) float64 { return v },
+		"f": func(v float64) float64 { return (v - 32) * 5 / 9 },
+	},
+	"wind_speed": {
+		"m/s":  func(v float64) float64 { return v },
+		"km/h": func(v float64) float64 { return v / 3.6 },
+		"mph":  func(v float64) float64 { return v * 0.44704 },
+		"mi/h": func(v float64) float64 { return v * 0.44704 },
+		"kn":   func(v float64) float64 { return v * 0.514444 },
+	},
+	"dew_point": {
+		"c": func(v float64) float64 { return v },
+		"f": func(v float64) float64 { return (v - 32) * 5 / 9 },
+	},
+	"pressure":   {"hpa": func(v float64) float64 { return v }},
+	"humidity":   {"%": func(v float64) float64 { return v }},
+	"visibility": {"m": func(v float64) float64 { return v }, "km": func(v float64) float64 { return v * 1000 }},
+}
+
+var ruleRegexp = regexp.MustCompile(`^([a-z_]+)\s*(<=|>=|==|!=|<|>)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*(\S*)$`)
+
+type rule struct {
+	text     string
+	quantity string
+	op       string
+	value    float64 // in metric units
+}
+
+func parseRule(text, units string) (*rule, error) {
+	m := ruleRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(text)))
+	if m == nil {
+		return nil, fmt.Errorf("invalid rule %q, expected e.g. \"temp < 0\"", text)
+	}
+	quantity, ok := ruleQuantities[m[1]]
+	if !ok {
+		return nil, fmt.Errorf("invalid rule %q, unknown quantity %q", text, m[1])
+	}
+	value, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule %q: %w", text, err)
+	}
+
+	unit := strings.TrimPrefix(m[4], "°")
+	if unit == "" {
+		m := toMetric(&Weather{Temperature: value, WindSpeed: value}, units)
+		switch quantity {
+		case "temperature", "dew_point":
+			value = m.Temperature
+		case "wind_speed":
+			value = m.WindSpeed
+		}
+	} else {
+		convert, ok := ruleUnits[quantity][unit]
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q, unknown unit %q", text, m[4])
+		}
+		value = convert(value)
+	}
+	return &rule{text: strings.TrimSpace(text), quantity: quantity, op: m[2], value: value}, nil
+}
+
+// observed returns the value of the rule quantity in w, or in a for aqi
+// rules.
+func (r *rule) observed(m *Weather, a *AirQuality) float64 {
+	switch r.quantity {
+	case "temperature":
+		return m.Temperature
+	case "wind_speed":
+		return m.WindSpeed
+	case "humidity":
+		return m.Humidity
+	case "pressure":
+		return m.Pressure
+	case "visibility":
+		return m.Visibility
+	case "dew_point":
+		return dewPoint(m.Temperature, m.Humidity)
+	case "aqi":
+		return float64(a.Index)
+	}
+	return 0
+}
+
+func (r *rule) matches(value float64) bool {
+	switch r.op {
+	case "<":
+		return value < r.value
+	case "<=":
+		return value <= r.value
+	case ">":
+		return value > r.value
+	case ">=":
+		return value >= r.value
+	case "==":
+		return value == r.value
+	case "!=":
+		return value != r.value
+	}
+	return false
+}
+
+// ruleResult is the outcome of a rule for a location. Value is in the
+// units of the weather it was evaluated against.
+type ruleResult struct {
+	City      string  `json:"city"`
+	Rule      string  `json:"rule"`
+	Value     float64 `json:"value"`
+	Triggered bool    `json:"triggered"`
+
+	quantity string
+}
+
+func ruleUnitSymbol(quantity, units string) string {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(units)
+	switch quantity {
+	case "temperature", "dew_point":
+		return "°" + temperatureSymbol
+	case "wind_speed":
+		return " " + windSpeedSymbol
+	case "pressure":
+		return " hPa"
+	case "humidity":
+		return "%"
+	case "visibility":
+		return " m"
+	}
+	return ""
+}
+
+// evaluateRules evaluates rules against w, given in units. a may be nil when
+// no rule tests air quality.
+func evaluateRules(rules []*rule, w *Weather, a *AirQuality, units string) []ruleResult {
+	m := toMetric(w, units)
+	results := make([]ruleResult, len(rules))
+	for i, r := range rules {
+		value := r.observed(m, a)
+		triggered := r.matches(value)
+		switch r.quantity {
+		case "temperature", "wind_speed":
+			value = r.observed(w, a)
+		case "dew_point":
+			// The dew point is computed, so round it like the measured values.
+			value = math.Round(fromMetric(&Weather{Temperature: value}, units).Temperature*100) / 100
+		}
+		results[i] = ruleResult{City: w.CityName, Rule: r.text, Value: value, Triggered: triggered, quantity: r.quantity}
+	}
+	return results
+}
+
+func needsAir(rules []*rule) bool {
+	for _, r := range rules {
+		if r.quantity == "aqi" {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesFlag appends each -rule to dst.
+func rulesFlag(fs *flag.FlagSet, dst *[]string) {
+	fs.Func("rule", "alert rule, e.g. \"temp < 0\" (can be repeated)", func(value string) error {
+		*dst = append(*dst, value)
+		return nil
+	})
+}
+
+// alertRules parses the rules of the config file followed by the -rule
+// flags.
+func (s *session) alertRules() []*rule {
+	var rules []*rule
+	for _, text := range append(s.cfg.list("rules"), s.opt.rules...) {
+		r, err := parseRule(text, s.opt.units)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// evaluate fetches the weather of city, and the air quality when a rule
+// needs it, and evaluates rules against them.
+func (s *session) evaluate(city string, rules []*rule) (*Weather, []ruleResult, error) {
+	w, err := s.provider().current(s.query(city))
+	if err != nil {
+		return nil, nil, err
+	}
+	var a *AirQuality
+	if needsAir(rules) {
+		if a, err = s.provider().air(s.query(city)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return w, evaluateRules(rules, w, a, s.opt.units), nil
+}
+
+// runCheck evaluates the alert rules once and exits with status 2 when any
+// of them is triggered, so that cron jobs and scripts can act on it. With
+// -when it tests the condition instead.
+func runCheck(s *session) {
+	if s.opt.when != "" {
+		runWhen(s)
+		return
+	}
+
+	rules := s.alertRules()
+	if len(rules) == 0 {
+		exitWithError("no alert rules, add them with \"weather config set rules 'temp < 0'\" or -rule")
+	}
+
+	triggered := false
+	for _, city := range s.cities() {
+		w, results, err := s.evaluate(city, rules)
+		if exitOnError(err) {
+			continue
+		}
+		s.recordHistory(w, s.opt.units)
+		displayRuleResults(os.Stdout, results, s.opt)
+
+		for _, r := range results {
+			if r.Triggered {
+				triggered = true
+				sendWebhooks(parseKeys(s.opt.webhooks), s.opt.webhookSecret, &webhookEvent{Type: "threshold", Location: w.CityName, Time: w.Time, Units: s.opt.units, Weather: w, Rule: r.Rule})
+				s.notifyRule(w, r)
+			}
+		}
+		if s.notifying() {
+			s.notifyActiveAlerts(city, w.CityName)
+		}
+	}
+
+	webhookDeliveries.Wait()
+	if triggered {
+		exit(exitTriggered)
+	}
+}
+
+// displayRuleResults shows the triggered rules, or all of them with -v.
+func displayRuleResults(w io.Writer, results []ruleResult, opt *options) {
+	if isJSON(opt.format) {
+		if opt.format == "jsonl" {
+			for _, r := range results {
+				writeJSON(w, r, opt.format)
+			}
+			return
+		}
+		writeJSON(w, results, opt.format)
+		return
+	}
+
+	for _, r := range results {
+		if r.Triggered || opt.verbose > 0 {
+			state := "ok"
+			if r.Triggered {
+				state = "TRIGGERED"
+			}
+			fmt.Fprintf(w, "%s: %s: %s (%s%s)\n", r.City, state, r.Rule, formatFloat(r.Value), ruleUnitSymbol(r.quantity, opt.units))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		text     string
+		units    string
+		quantity string
+		op       string
+		value    float64
+	}{
+		{"temp < 0", "metric", "temperature", "<", 0},
+		{"  TEMP<=-5  ", "metric", "temperature", "<=", -5},
+		{"temperature != -0.5", "metric", "temperature", "!=", -0.5},
+		{"temp < 32", "imperial", "temperature", "<", 0},
+		{"temp < 0 c", "imperial", "temperature", "<", 0},
+		{"temp >= 50°F", "metric", "temperature", ">=", 10},
+		{"dewpoint > 68 °f", "metric", "dew_point", ">", 20},
+		{"wind > 15", "metric", "wind_speed", ">", 15},
+		{"wind > 10", "imperial", "wind_speed", ">", 4.4704},
+		{"wind_speed > 36 km/h", "metric", "wind_speed", ">", 10},
+		{"wind > 10 kn", "imperial", "wind_speed", ">", 5.14444},
+		{"pressure < 1000 hPa", "imperial", "pressure", "<", 1000},
+		{"humidity == 90%", "metric", "humidity", "==", 90},
+		{"visibility < 2 km", "metric", "visibility", "<", 2000},
+		{"aqi >= 4", "imperial", "aqi", ">=", 4},
+	}
+	for _, tt := range tests {
+		r, err := parseRule(tt.text, tt.units)
+		if err != nil {
+			t.Errorf("parseRule(%q, %s): %s", tt.text, tt.units, err)
+			continue
+		}
+		if r.quantity != tt.quantity || r.op != tt.op || math.Abs(r.value-tt.value) > 1e-9 {
+			t.Errorf("parseRule(%q, %s) = %s %s %v, want %s %s %v", tt.text, tt.units, r.quantity, r.op, r.value, tt.quantity, tt.op, tt.value)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"temp", `invalid rule "temp", expected e.g. "temp < 0"`},
+		{"temp = 0", `invalid rule "temp = 0", expected e.g. "temp < 0"`},
+		{"temp < 0 c extra", `invalid rule "temp < 0 c extra", expected e.g. "temp < 0"`},
+		{"temp < - 5", `invalid rule "temp < - 5", expected e.g. "temp < 0"`},
+		{"snow > 1", `invalid rule "snow > 1", unknown quantity "snow"`},
+		{"wind > 10 furlongs", `invalid rule "wind > 10 furlongs", unknown unit "furlongs"`},
+		{"temp < 0 k", `invalid rule "temp < 0 k", unknown unit "k"`},
+		{"aqi > 3 c", `invalid rule "aqi > 3 c", unknown unit "c"`},
+	}
+	for _, tt := range tests {
+		_, err := parseRule(tt.text, "metric")
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseRule(%q): got error %v, want %s", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		text  string
+		value float64
+		want  bool
+	}{
+		{"temp < 0", -0.1, true},
+		{"temp < 0", 0, false},
+		{"temp <= 0", 0, true},
+		{"temp > 0", 0, false},
+		{"temp >= 0", 0, true},
+		{"aqi == 4", 4, true},
+		{"aqi != 4", 4, false},
+		{"aqi != 4", 3, true},
+	}
+	for _, tt := range tests {
+		r, err := parseRule(tt.text, "metric")
+		if err != nil {
+			t.Fatalf("parseRule(%q): %s", tt.text, err)
+		}
+		if got := r.matches(tt.value); got != tt.want {
+			t.Errorf("%q matches %v: got %t, want %t", tt.text, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scheduled jobs are listed under schedule in the config file and run by
+// the daemon:
+//
+//	fetch helsinki every 10m
+//	digest at 07:00
+//	summary tokyo at 06:30
+//
+// fetch polls a location at its own interval instead of -interval. digest
+// emails the forecast of the day and summary posts it to Slack and Discord,
+// for the given location or else the favorites.
+
+var (
+	fetchJobRegexp = regexp.MustCompile(`^fetch\s+(.+?)\s+every\s+(\S+)$`)
+	dailyJobRegexp = regexp.MustCompile(`^(digest|summary)(?:\s+(.+?))?\s+at\s+(\d{1,2}:\d{2})$`)
+)
+
+type job struct {
+	text  string
+	kind  string // fetch, digest or summary
+	city  string // empty for the favorites
+	every time.Duration
+	at    string // HH:MM
+
+	cities []string // locations of digest and summary jobs
+}
+
+func parseJob(text string) (*job, error) {
+	text = strings.TrimSpace(text)
+	if m := fetchJobRegexp.FindStringSubmatch(text); m != nil {
+		every, err := time.ParseDuration(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid job %q: %w", text, err)
+		}
+		if every < minWatchInterval {
+			return nil, fmt.Errorf("invalid job %q, interval must be at least %s", text, minWatchInterval)
+		}
+		return &job{text: text, kind: "fetch", city: m[1], every: every}, nil
+	}
+	if m := dailyJobRegexp.FindStringSubmatch(text); m != nil {
+		at, err := time.Parse("15:04", m[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid job %q, expected a time like 07:00", text)
+		}
+		return &job{text: text, kind: m[1], city: m[2], at: at.Format("15:04")}, nil
+	}
+	return nil, fmt.Errorf("invalid job %q, expected e.g. \"fetch helsinki every 10m\" or \"digest at 07:00\"", text)
+}
+
+// scheduleSetting validates the jobs of the schedule setting, one per
+// argument. Unlike other lists they are not split on commas, which may
+// appear in locations such as "Helsinki,FI".
+func scheduleSetting(args []string) (any, error) {
+	for _, text := range args {
+		if _, err := parseJob(text); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// scheduledJobs parses the jobs of the config file.
+func (s *session) scheduledJobs() []*job {
+	var jobs []*job
+	for _, text := range s.cfg.list("schedule") {
+		j, err := parseJob(text)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		if j.city != "" {
+			j.city = s.citiesFrom([]string{j.city})[0]
+		}
+		switch j.kind {
+		case "digest":
+			checkSMTP(s.opt)
+		case "summary":
+			if s.opt.slackWebhook == "" && !s.hasDiscord() {
+				exitWithError(fmt.Sprintf("job %q: no Slack or Discord webhook to post the summary to", j.text))
+			}
+		}
+		if j.kind != "fetch" {
+			j.cities = []string{j.city}
+			if j.city == "" {
+				j.cities = s.digestCities()
+			}
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// runDaily runs a digest or summary job every day at its time.
+func (d *daemon) runDaily(ctx context.Context, j *job) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(nextSummaryTime(time.Now(), j.at))):
+		}
+
+		d.refreshMu.Lock()
+		switch j.kind {
+		case "digest":
+			if err := d.s.sendDigest(j.cities, time.Now()); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: digest: %s\n", redact(err.Error()))
+			}
+		case "summary":
+			for _, city := range j.cities {
+				sum, err := d.s.dailySummary(city)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", city, redact(err.Error()))
+					continue
+				}
+				d.s.postSlack(slackSummary(sum, d.s.opt))
+				d.s.postDiscord(sum.weather.CityName, discordSummary(sum, d.s.opt))
+			}
+		}
+		d.refreshMu.Unlock()
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJob(t *testing.T) {
+	tests := []struct {
+		text string
+		want job
+	}{
+		{"fetch helsinki every 10m", job{text: "fetch helsinki every 10m", kind: "fetch", city: "helsinki", every: 10 * time.Minute}},
+		{"  fetch New York, US  every 1h30m ", job{text: "fetch New York, US  every 1h30m", kind: "fetch", city: "New York, US", every: 90 * time.Minute}},
+		{"fetch 60.17,24.94 every 10s", job{text: "fetch 60.17,24.94 every 10s", kind: "fetch", city: "60.17,24.94", every: 10 * time.Second}},
+		{"digest at 07:00", job{text: "digest at 07:00", kind: "digest", at: "07:00"}},
+		{"summary tokyo at 6:30", job{text: "summary tokyo at 6:30", kind: "summary", city: "tokyo", at: "06:30"}},
+		{"summary Salt Lake City at 23:59", job{text: "summary Salt Lake City at 23:59", kind: "summary", city: "Salt Lake City", at: "23:59"}},
+	}
+	for _, tt := range tests {
+		j, err := parseJob(tt.text)
+		if err != nil {
+			t.Errorf("parseJob(%q): %s", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(*j, tt.want) {
+			t.Errorf("parseJob(%q) = %+v, want %+v", tt.text, *j, tt.want)
+		}
+	}
+}
+
+func TestParseJobErrors(t *testing.T) {
+	const expected = `, expected e.g. "fetch helsinki every 10m" or "digest at 07:00"`
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"", `invalid job ""` + expected},
+		{"fetch helsinki", `invalid job "fetch helsinki"` + expected},
+		{"fetch every 10m", `invalid job "fetch every 10m"` + expected},
+		{"Fetch helsinki every 10m", `invalid job "Fetch helsinki every 10m"` + expected},
+		{"fetch helsinki every often", `invalid job "fetch helsinki every often": time: invalid duration "often"`},
+		{"fetch helsinki every 5s", `invalid job "fetch helsinki every 5s", interval must be at least 10s`},
+		{"digest at 7", `invalid job "digest at 7"` + expected},
+		{"digest at 24:00", `invalid job "digest at 24:00", expected a time like 07:00`},
+		{"summary tokyo at 12:60", `invalid job "summary tokyo at 12:60", expected a time like 07:00`},
+		{"report at 07:00", `invalid job "report at 07:00"` + expected},
+	}
+	for _, tt := range tests {
+		_, err := parseJob(tt.text)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseJob(%q): got error %v, want %s", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestScheduleSetting(t *testing.T) {
+	jobs := []string{"fetch Helsinki,FI every 10m", "digest at 07:00"}
+	got, err := scheduleSetting(jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, jobs) {
+		t.Errorf("got %q, want the jobs as they are, %q", got, jobs)
+	}
+	if _, err := scheduleSetting([]string{"digest at 07:00", "digest"}); err == nil {
+		t.Error("an invalid job was accepted")
+	}
+}
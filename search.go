@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// runSearch lists the locations matching a name, closest match first, with
+// the coordinates to use in scripts and favorites.
+func runSearch(s *session) {
+	name := strings.TrimSpace(strings.Join(s.args(), " "))
+	if name == "" {
+		exitWithUsageError("usage: weather search <query>")
+	}
+
+	// 5 is the most the geocoding API returns.
+	locations, err := s.provider().geocode(name, 5)
+	if exitOnError(err) || s.opt.raw {
+		return
+	}
+	var matches []Location
+	for _, l := range locations {
+		if s.opt.country == "" || strings.EqualFold(l.Country, s.opt.country) {
+			matches = append(matches, l)
+		}
+	}
+	if len(matches) == 0 {
+		exitWithStatus(exitNotFound, fmt.Sprintf("location %q not found in %s", name, strings.ToUpper(s.opt.country)))
+	}
+
+	// The API matches names loosely, e.g. "helsinky" finds Helsinki, so the
+	// results are ordered by how close their names are to the query.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return nameDistance(name, &matches[i]) < nameDistance(name, &matches[j])
+	})
+	displayLocations(os.Stdout, matches, s.opt)
+}
+
+// nameDistance is the edit distance between query and the closest of the
+// names of l.
+func nameDistance(query string, l *Location) int {
+	query = strings.ToLower(query)
+	best := levenshtein(query, strings.ToLower(l.Name))
+	for _, name := range l.LocalNames {
+		best = min(best, levenshtein(query, strings.ToLower(name)))
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, utf8.RuneCountInString(b)+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	for _, ra := range a {
+		cur := []int{prev[0] + 1}
+		j := 0
+		for _, rb := range b {
+			cost := 1
+			if ra == rb {
+				cost = 0
+			}
+			cur = append(cur, min(prev[j+1]+1, cur[j]+1, prev[j]+cost))
+			j++
+		}
+		prev = cur
+	}
+	return prev[len(prev)-1]
+}
+
+func displayLocations(w io.Writer, locations []Location, opt *options) {
+	if isJSON(opt.format) {
+		if opt.format == "jsonl" {
+			for _, l := range locations {
+				writeJSON(w, l, opt.format)
+			}
+			return
+		}
+		writeJSON(w, locations, opt.format)
+		return
+	}
+
+	tw := newTable(w, 2)
+	for _, l := range locations {
+		place := l.describe()
+		if local := l.LocalNames[opt.lang]; local != "" && local != l.Name {
+			place += " (" + local + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", place, formatCoordinates(l.Lat, l.Lon))
+	}
+	tw.Flush()
+}
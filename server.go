@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// runServe serves the provider data over HTTP. Responses have the same JSON
+// shape as the -o json output of the corresponding commands.
+func runServe(s *session) {
+	if s.opt.interval < time.Second {
+		exitWithUsageError("interval must be at least 1s")
+	}
+	s.provider()
+	if s.opt.history {
+		s.historyDB()
+	}
+
+	srv := &http.Server{
+		Addr:              s.opt.listen,
+		Handler:           s.serverHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var grpcSrv *grpc.Server
+	if s.opt.grpcListen != "" {
+		l, err := net.Listen("tcp", s.opt.grpcListen)
+		if err != nil {
+			exitWithError("serve: " + err.Error())
+		}
+		grpcSrv = s.serveGRPC(l)
+		fmt.Fprintf(os.Stderr, "weather gRPC server listening on %s\n", s.opt.grpcListen)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if grpcSrv != nil {
+			go grpcSrv.GracefulStop()
+		}
+		srv.Shutdown(shutdown)
+		if grpcSrv != nil {
+			// WatchUpdates calls do not end on their own.
+			grpcSrv.Stop()
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "weather server listening on %s\n", s.opt.listen)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		exitWithError("serve: " + err.Error())
+	}
+}
+
+// apiError is an error with the HTTP status it is reported with.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func (s *session) serverHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/current", s.apiHandler(func(w http.ResponseWriter, r *http.Request, opt *options) error {
+		q, err := s.apiQuery(r, opt, r.URL.Query().Get("city"))
+		if err != nil {
+			return err
+		}
+		wt, err := s.provider().current(q)
+		if err != nil {
+			return err
+		}
+		s.recordHistory(wt, q.units)
+		display(w, wt, nil, nil, opt)
+		return nil
+	}))
+	mux.HandleFunc("/v1/forecast", s.apiHandler(func(w http.ResponseWriter, r *http.Request, opt *options) error {
+		q, err := s.apiQuery(r, opt, r.URL.Query().Get("city"))
+		if err != nil {
+			return err
+		}
+		days := 5
+		if value := r.URL.Query().Get("days"); value != "" {
+			if days, err = strconv.Atoi(value); err != nil || days < 1 || days > 5 {
+				return &apiError{http.StatusBadRequest, "days must be between 1 and 5"}
+			}
+		}
+		f, err := s.provider().forecast(q)
+		if err != nil {
+			return err
+		}
+		filterForecastDays(f, days, time.Now())
+		displayForecast(w, f, opt)
+		return nil
+	}))
+	mux.HandleFunc("/v1/air", s.apiHandler(func(w http.ResponseWriter, r *http.Request, opt *options) error {
+		q, err := s.apiQuery(r, opt, r.URL.Query().Get("city"))
+		if err != nil {
+			return err
+		}
+		a, err := s.provider().air(q)
+		if err != nil {
+			return err
+		}
+		displayAir(w, a, opt)
+		return nil
+	}))
+	mux.HandleFunc("/v1/feed", s.apiHandler(func(w http.ResponseWriter, r *http.Request, opt *options) error {
+		cities := r.URL.Query()["city"]
+		if len(cities) == 0 {
+			cities = []string{""}
+		}
+		var items []feedItem
+		for _, city := range cities {
+			q, err := s.apiQuery(r, opt, city)
+			if err != nil {
+				return err
+			}
+			item, err := s.feedItem(q)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		writeFeed(w, items, opt, time.Now())
+		return nil
+	}))
+	mux.HandleFunc("/v1/stream", s.apiHandler(s.stream))
+	mux.Handle("/v1/ws", s.websocketHandler())
+	mux.HandleFunc("/metrics", metricsHandler(s.configuredWeather, "metric"))
+	return mux
+}
+
+// stream pushes the current weather as Server-Sent Events: once when the
+// client connects and then whenever the data changes. Streams check the
+// provider every -interval, which is served from the cache until it
+// expires, so any number of clients cost no more API calls than one.
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+func (s *session) stream(w http.ResponseWriter, r *http.Request, opt *options) error {
+	q, err := s.apiQuery(r, opt, r.URL.Query().Get("city"))
+	if err != nil {
+		return err
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return &apiError{http.StatusInternalServerError, "streaming is not supported"}
+	}
+	wt, err := s.provider().current(q)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Tell proxies such as nginx not to buffer the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	opt.format = "jsonl"
+
+	ticker := time.NewTicker(s.opt.interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		if err != nil {
+			var buf bytes.Buffer
+			writeJSON(&buf, struct {
+				Error string `json:"error"`
+			}{redact(err.Error())}, opt.format)
+			fmt.Fprintf(w, "event: error\ndata: %s\n", buf.String())
+		} else {
+			var buf bytes.Buffer
+			display(&buf, wt, nil, nil, opt)
+			if data := buf.String(); data != last {
+				last = data
+				s.recordHistory(wt, q.units)
+				fmt.Fprintf(w, "event: weather\nid: %d\ndata: %s\n", wt.Time.Unix(), data)
+			} else {
+				// A comment keeps idle connections from timing out.
+				fmt.Fprint(w, ": no change\n\n")
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+		wt, err = s.provider().current(q)
+	}
+}
+
+// configuredWeather returns the current weather of the configured city and
+// favorites in metric units. Locations that cannot be fetched are left out;
+// the failures show up in the fetch error counter.
+func (s *session) configuredWeather() []*Weather {
+	cities := append(s.cfg.list("city"), s.cfg.list("favorites")...)
+	fetched := make([]*Weather, len(cities))
+	s.fetchAll(context.Background(), len(cities), s.opt.maxRequests, func(i int) {
+		fetched[i], _ = s.provider().current(query{city: cities[i], units: "metric", lang: s.opt.lang})
+	})
+
+	var observations []*Weather
+	seen := map[string]bool{}
+	for _, w := range fetched {
+		if w == nil || seen[w.CityName] {
+			continue
+		}
+		seen[w.CityName] = true
+		observations = append(observations, w)
+	}
+	return observations
+}
+
+// apiHandler adapts an endpoint to http.HandlerFunc. Each request gets its
+// own copy of the options, and errors are returned as JSON with a status
+// matching the error.
+func (s *session) apiHandler(endpoint func(http.ResponseWriter, *http.Request, *options) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeAPIError(w, &apiError{http.StatusMethodNotAllowed, "method not allowed"})
+			return
+		}
+
+		opt := *s.opt
+		opt.format = "json"
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := endpoint(w, r, &opt); err != nil {
+			writeAPIError(w, err)
+		}
+	}
+}
+
+// apiQuery builds the provider query for city from a request. The units and
+// lang parameters, and an empty city, default to the server settings.
+func (s *session) apiQuery(r *http.Request, opt *options, city string) (query, error) {
+	if err := apiOptions(r, opt); err != nil {
+		return query{}, err
+	}
+	return s.serverQuery(opt, city)
+}
+
+// serverQuery builds the provider query for city in the units and lang of
+// opt. An empty city defaults to the configured one.
+func (s *session) serverQuery(opt *options, city string) (query, error) {
+	if alias, ok := s.cfg.string("aliases." + city); ok {
+		city = alias
+	}
+	if cities := s.cfg.list("city"); city == "" && len(cities) > 0 {
+		city = cities[0]
+	}
+	if city == "" {
+		return query{}, &apiError{http.StatusBadRequest, "city is required"}
+	}
+	return query{city: city, units: opt.units, lang: opt.lang}, nil
+}
+
+// apiOptions applies the units and lang parameters of a request to opt.
+func apiOptions(r *http.Request, opt *options) error {
+	params := r.URL.Query()
+	if units := params.Get("units"); units != "" {
+		if err := checkEnum("units", units, unitsValues); err != nil {
+			return &apiError{http.StatusBadRequest, err.Error()}
+		}
+		opt.units = units
+	}
+	if lang := params.Get("lang"); lang != "" {
+		if err := checkEnum("lang", lang, langValues); err != nil {
+			return &apiError{http.StatusBadRequest, err.Error()}
+		}
+		opt.lang = lang
+	}
+	return nil
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var ae *apiError
+	var nf *notFoundError
+	switch {
+	case errors.As(err, &ae):
+		status = ae.status
+	case errors.As(err, &nf):
+		status = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	writeJSON(w, struct {
+		Error string `json:"error"`
+	}{redact(err.Error())}, "json")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Messages are posted to a Slack incoming webhook using Block Kit:
+// https://api.slack.com/messaging/webhooks
+// https://api.slack.com/reference/block-kit/blocks
+
+func slackFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.slackWebhook, "slack", "", "Slack incoming webhook URL to post summaries, triggered rules and weather warnings to")
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // shown in notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type      string      `json:"type"`
+	Text      *slackText  `json:"text,omitempty"`
+	Fields    []slackText `json:"fields,omitempty"`
+	Elements  []slackText `json:"elements,omitempty"`
+	Accessory *slackImage `json:"accessory,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackImage struct {
+	Type     string `json:"type"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+// slackTextLength is the maximum length of a section text.
+const slackTextLength = 3000
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackHeader(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{"plain_text", text}}
+}
+
+func slackMarkdown(text string) *slackText {
+	return &slackText{"mrkdwn", text}
+}
+
+// slackWeatherBlocks lays out the weather as a card: the conditions next to
+// the condition icon, the key metrics in two columns and the time.
+func slackWeatherBlocks(w *Weather, today *forecastDay, opt *options) []slackBlock {
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+
+	text := fmt.Sprintf("*%.0f°%s* %s", w.Temperature, temperatureSymbol, slackEscaper.Replace(w.Conditions))
+	if today != nil {
+		text += fmt.Sprintf("\n%s: %s", localDate(today.date, "Mon 2 Jan"), today.summary())
+	}
+	conditions := slackBlock{Type: "section", Text: slackMarkdown(text)}
+	if w.Icon != "" {
+		conditions.Accessory = &slackImage{"image", fmt.Sprintf(ICON_URL, w.Icon), w.Conditions}
+	}
+
+	return []slackBlock{
+		conditions,
+		{Type: "section", Fields: []slackText{
+			*slackMarkdown(fmt.Sprintf("*Wind*\n%.1f %s from %.0f°", w.WindSpeed, windSpeedSymbol, w.WindDegrees)),
+			*slackMarkdown(fmt.Sprintf("*Humidity*\n%.0f%%", w.Humidity)),
+			*slackMarkdown(fmt.Sprintf("*Pressure*\n%.0f hPa", w.Pressure)),
+			*slackMarkdown(fmt.Sprintf("*Visibility*\n%.1f km", w.Visibility/1000)),
+		}},
+		{Type: "context", Elements: []slackText{*slackMarkdown(summaryTime(w))}},
+	}
+}
+
+func slackSummary(sum *dailySummary, opt *options) *slackMessage {
+	w := sum.weather
+	temperatureSymbol, _ := unitSymbols(opt.units)
+	return &slackMessage{
+		Text:   slackEscaper.Replace(fmt.Sprintf("%s %.0f°%s %s %s", w.CityName, w.Temperature, temperatureSymbol, weatherIconIdToEmoji(w.Icon), w.Conditions)),
+		Blocks: append([]slackBlock{slackHeader(weatherIconIdToEmoji(w.Icon) + " " + w.CityName)}, slackWeatherBlocks(w, sum.today, opt)...),
+	}
+}
+
+func slackRule(w *Weather, r ruleResult, opt *options) *slackMessage {
+	title := fmt.Sprintf("⚠️ %s: %s", w.CityName, r.Rule)
+	return &slackMessage{
+		Text:   slackEscaper.Replace(title),
+		Blocks: append([]slackBlock{slackHeader(title)}, slackWeatherBlocks(w, nil, opt)...),
+	}
+}
+
+func slackAlert(location string, a *Alert) *slackMessage {
+	title := fmt.Sprintf("⚠️ %s: %s", location, a.Event)
+	return &slackMessage{
+		Text: slackEscaper.Replace(title),
+		Blocks: []slackBlock{
+			slackHeader(title),
+			{Type: "section", Text: slackMarkdown(slackEscaper.Replace(truncate(a.Description, slackTextLength)))},
+			{Type: "context", Elements: []slackText{*slackMarkdown(fmt.Sprintf("%s · %s – %s", slackEscaper.Replace(a.Sender), a.Start.Local().Format("Mon 2 Jan 15:04"), a.End.Local().Format("Mon 2 Jan 15:04")))}},
+		},
+	}
+}
+
+// postSlack posts m to the -slack webhook when one is given.
+func (s *session) postSlack(m *slackMessage) {
+	if s.opt.slackWebhook == "" {
+		return
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: slack: %s\n", err)
+		return
+	}
+	deliver("slack", s.opt.slackWebhook, nil, body)
+}
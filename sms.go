@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Text messages are sent through the Twilio Messages API. SMS reaches phones
+// without mobile data, which matters most when a storm has taken it down.
+// https://www.twilio.com/docs/messaging/api/message-resource
+
+const TWILIO_URL = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+
+// smsLength keeps a warning within a few SMS segments.
+const smsLength = 300
+
+func smsFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.twilioAccount, "twilio-account", "", "Twilio account SID")
+	fs.StringVar(&opt.twilioToken, "twilio-token", "", "Twilio auth token")
+	fs.StringVar(&opt.twilioFrom, "twilio-from", "", "Twilio phone number the warnings are sent from")
+	fs.StringVar(&opt.smsTo, "sms-to", "", "comma separated phone numbers to text severe weather warnings to")
+}
+
+// smsAlert formats a warning as plain text; emoji would force the message
+// into the UCS-2 encoding, which fits less than half the text per segment.
+func smsAlert(location string, a *Alert) string {
+	return truncate(fmt.Sprintf("Weather warning for %s: %s until %s. %s", location, a.Event, a.End.Local().Format("Mon 2 Jan 15:04"), a.Description), smsLength)
+}
+
+// sendSMS texts body to the -sms-to numbers when Twilio is configured.
+func (s *session) sendSMS(body string) {
+	if s.opt.twilioAccount == "" || s.opt.smsTo == "" {
+		return
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(s.opt.twilioAccount+":"+s.opt.twilioToken)))
+
+	endpoint := fmt.Sprintf(TWILIO_URL, url.PathEscape(s.opt.twilioAccount))
+	for _, to := range parseKeys(s.opt.smsTo) {
+		form := url.Values{"To": {to}, "From": {s.opt.twilioFrom}, "Body": {body}}
+		deliver("sms", endpoint, header, []byte(form.Encode()))
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+)
+
+// -speak reads the summary aloud with the text-to-speech engine of the
+// platform, e.g. for a morning routine or for those who cannot read the
+// terminal.
+
+var errSpeechUnsupported = errors.New("text-to-speech is not supported on this platform")
+
+func speakFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.speak, "speak", false, "read the summary aloud with the text-to-speech of the platform")
+}
+
+// spokenSummary returns the summary of w written out to be spoken, e.g.
+// "Helsinki: minus 9 degrees Celsius, light snow, wind 5 meters per
+// second.", as speech engines read symbols such as °C and m/s poorly.
+func spokenSummary(w *Weather, units string) string {
+	temperature, windSpeed := spokenUnits(units)
+	s := fmt.Sprintf("%s: %s %s, %s", w.CityName, spokenNumber("%.0f", w.Temperature), temperature, w.Conditions)
+	if wind := math.Round(w.WindSpeed); wind > 0 {
+		s += fmt.Sprintf(", wind %.0f %s", wind, windSpeed)
+	}
+	return s + "."
+}
+
+// speakWeather speaks the summary of w with -speak. It waits for the speech
+// to end so that several locations are not read over each other. Failures
+// are reported but do not stop the command.
+func (s *session) speakWeather(w *Weather) {
+	if !s.opt.speak {
+		return
+	}
+	if err := speak(spokenSummary(w, s.opt.units)); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: speak: %s\n", err)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+func speak(text string) error {
+	out, err := exec.Command("say", text).CombinedOutput()
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
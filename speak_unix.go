@@ -0,0 +1,25 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// The summary is spoken with espeak-ng, or the older espeak.
+
+func speak(text string) error {
+	path, err := exec.LookPath("espeak-ng")
+	if err != nil {
+		if path, err = exec.LookPath("espeak"); err != nil {
+			return errors.New("espeak-ng not found, install espeak-ng")
+		}
+	}
+	out, err := exec.Command(path, text).CombinedOutput()
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The summary is spoken with SAPI through System.Speech from PowerShell.
+// The text is passed in the environment so that it needs no quoting.
+
+const speechScript = `
+Add-Type -AssemblyName System.Speech
+$synthesizer = New-Object System.Speech.Synthesis.SpeechSynthesizer
+$synthesizer.Speak($env:WEATHER_SPEAK_TEXT)
+`
+
+func speak(text string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", speechScript)
+	cmd.Env = append(os.Environ(), "WEATHER_SPEAK_TEXT="+text)
+	out, err := cmd.CombinedOutput()
+	if msg := strings.TrimSpace(string(out)); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// weather stars rates tonight for stargazing from 0 to 100: the weighted
+// mean of ratings of the cloud cover and the humidity forecast for the dark
+// hours, of the moonlight and of how dark the night gets. The sun and the
+// moon are computed, so no extra API is needed.
+
+// Sun altitudes below which twilight ends, in degrees.
+const (
+	astronomicalTwilight = -18
+	nauticalTwilight     = -12
+)
+
+// synodicMonth is the mean length of the lunar phase cycle in days, and
+// referenceNewMoon the Julian date of the new moon of 6 January 2000.
+const (
+	synodicMonth     = 29.530588853
+	referenceNewMoon = 2451550.1
+)
+
+// julianDate returns the Julian date of t.
+func julianDate(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+// sunAltitude returns the altitude of the sun in degrees at a location at
+// t, by the low precision formulas of the Astronomical Almanac, accurate to
+// about a degree.
+func sunAltitude(t time.Time, lat, lon float64) float64 {
+	rad := math.Pi / 180
+	d := julianDate(t) - 2451545.0
+	g := (357.529 + 0.98560028*d) * rad
+	q := 280.459 + 0.98564736*d
+	l := (q + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * rad
+	e := (23.439 - 0.00000036*d) * rad
+
+	ra := math.Atan2(math.Cos(e)*math.Sin(l), math.Cos(l)) / rad
+	dec := math.Asin(math.Sin(e) * math.Sin(l))
+	gmst := 18.697374558 + 24.06570982441908*d
+	h := (gmst*15 + lon - ra) * rad
+	return math.Asin(math.Sin(lat*rad)*math.Sin(dec)+math.Cos(lat*rad)*math.Cos(dec)*math.Cos(h)) / rad
+}
+
+// moonAge returns the days since the last new moon at t.
+func moonAge(t time.Time) float64 {
+	return math.Mod(math.Mod(julianDate(t)-referenceNewMoon, synodicMonth)+synodicMonth, synodicMonth)
+}
+
+// moonIllumination returns the illuminated fraction of the moon at t.
+func moonIllumination(t time.Time) float64 {
+	return (1 - math.Cos(2*math.Pi*moonAge(t)/synodicMonth)) / 2
+}
+
+// moonPhase returns the name of the phase of the moon at t.
+func moonPhase(t time.Time) string {
+	names := []string{"new moon", "waxing crescent", "first quarter", "waxing gibbous", "full moon", "waning gibbous", "last quarter", "waning crescent"}
+	// Each phase is centered on its eighth of the cycle.
+	i := int(moonAge(t)/synodicMonth*8+0.5) % 8
+	return names[i]
+}
+
+// darkness returns the first span within a day from now when the sun is
+// below altitude, scanning every five minutes, and the moment the sun is
+// lowest. ok is false when the sun does not get that low.
+func darkness(now time.Time, lat, lon, altitude float64) (start, end, lowest time.Time, ok bool) {
+	const step = 5 * time.Minute
+	lowestAltitude := math.Inf(1)
+	for t := now; t.Before(now.Add(24 * time.Hour)); t = t.Add(step) {
+		alt := sunAltitude(t, lat, lon)
+		if alt < lowestAltitude {
+			lowestAltitude, lowest = alt, t
+		}
+		switch {
+		case alt < altitude && !ok:
+			start, end, ok = t, t.Add(step), true
+		case alt < altitude && end.Equal(t):
+			end = t.Add(step)
+		}
+	}
+	return start, end, lowest, ok
+}
+
+// starsOutlook is the stargazing rating of tonight at a location. Start and
+// End delimit its dark hours: the astronomical night, or the nautical one,
+// or the three hours around the sun's lowest point when neither happens.
+type starsOutlook struct {
+	City      string    `json:"city"`
+	Score     int       `json:"score"`
+	Rating    string    `json:"rating"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Darkness  string    `json:"darkness"`
+	Clouds    float64   `json:"clouds"`
+	Humidity  float64   `json:"humidity"`
+	Moon      float64   `json:"moon_illumination"`
+	MoonPhase string    `json:"moon_phase"`
+
+	forecastGaps bool // no forecast covers the dark hours
+	timeZone     int
+}
+
+// outlookStars rates the coming night at lat, lon with the forecast f.
+func outlookStars(f *Forecast, lat, lon float64, now time.Time) *starsOutlook {
+	o := &starsOutlook{City: f.CityName, timeZone: f.TimeZone}
+	darkRating := 100.0
+	start, end, lowest, ok := darkness(now, lat, lon, astronomicalTwilight)
+	o.Darkness = "astronomical"
+	if !ok {
+		start, end, lowest, ok = darkness(now, lat, lon, nauticalTwilight)
+		o.Darkness, darkRating = "nautical", 50
+	}
+	if !ok {
+		start, end = lowest.Add(-90*time.Minute), lowest.Add(90*time.Minute)
+		o.Darkness, darkRating = "none", 0
+	}
+	o.Start, o.End = start, end
+
+	mid := start.Add(end.Sub(start) / 2)
+	o.Moon, o.MoonPhase = moonIllumination(mid), moonPhase(mid)
+
+	n := 0.0
+	for _, e := range f.Entries {
+		if e.Time.Add(forecastPeriod).After(start) && e.Time.Before(end) {
+			o.Clouds += e.Clouds
+			o.Humidity += e.Humidity
+			n++
+		}
+	}
+	if n > 0 {
+		o.Clouds, o.Humidity = o.Clouds/n, o.Humidity/n
+	} else {
+		// Beyond the forecast, e.g. late at night near its end.
+		o.forecastGaps = true
+	}
+
+	clamp := func(v float64) float64 { return max(0, min(100, v)) }
+	cloudRating := 100 - o.Clouds
+	if o.forecastGaps {
+		cloudRating = 50
+	}
+	moonRating := 100 - o.Moon*100
+	// Humid air blurs the sky, from about 50%.
+	humidityRating := clamp(100 - (o.Humidity-50)*2)
+	o.Score = int((5*cloudRating+2*moonRating+1*humidityRating+2*darkRating)/10 + 0.5)
+	switch {
+	case o.Score >= 80:
+		o.Rating = "excellent"
+	case o.Score >= 60:
+		o.Rating = "good"
+	case o.Score >= 40:
+		o.Rating = "fair"
+	default:
+		o.Rating = "poor"
+	}
+	return o
+}
+
+// runStars rates tonight for stargazing at each location.
+func runStars(s *session) {
+	for _, city := range s.cities() {
+		l, err := s.provider().locate(city)
+		if exitOnError(err) {
+			continue
+		}
+		f, err := s.provider().forecast(s.query(formatCoordinates(l.Lat, l.Lon)))
+		if exitOnError(err) {
+			continue
+		}
+		if f.CityName == "" {
+			f.CityName = l.Name
+		}
+		displayStars(os.Stdout, outlookStars(f, l.Lat, l.Lon, time.Now()), s.opt)
+	}
+}
+
+func displayStars(w io.Writer, o *starsOutlook, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, o, opt.format)
+		return
+	}
+
+	localFprintf(w, "%s tonight: %d/100, %s\n", o.City, o.Score, o.Rating)
+	span := localTime(o.Start, o.timeZone).Format("15:04") + "–" + localTime(o.End, o.timeZone).Format("15:04")
+	switch o.Darkness {
+	case "astronomical":
+		localFprintf(w, "  dark %s\n", span)
+	case "nautical":
+		localFprintf(w, "  no astronomical night, darkest %s\n", span)
+	default:
+		localFprintf(w, "  the sky stays bright, darkest %s\n", span)
+	}
+	clouds := localSprintf("clouds %.0f%%, humidity %.0f%%", o.Clouds, o.Humidity)
+	if o.forecastGaps {
+		clouds = "no forecast for the dark hours"
+	}
+	localFprintf(w, "  %s, moon %.0f%% lit (%s)\n", clouds, o.Moon*100, o.MoonPhase)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// -stats writes what a run cost when it ends: the calls made to the
+// provider API with their latency, the retries with another key and how
+// often the response cache was hit, which helps to tune -cache-ttl and the
+// use of the quota. The numbers come from the counters that the daemon and
+// the server export as metrics.
+
+// statsOutput receives the statistics of the run with -stats, or is nil.
+var statsOutput io.Writer
+
+// exit shows the output collected for the pager, writes the statistics of
+// the run and exits with status. Commands exit with a status telling their
+// result, e.g. that some locations failed, after writing their output.
+func exit(status int) {
+	if watching {
+		panic(watchExit{status: status})
+	}
+	showPage(true)
+	writeStats()
+	os.Exit(status)
+}
+
+// writeStats writes the statistics of the run to statsOutput, once.
+func writeStats() {
+	w := statsOutput
+	if w == nil {
+		return
+	}
+	statsOutput = nil
+
+	var calls, failed, retries float64
+	apiRequests.each(func(values []string, n float64) {
+		calls += n
+		if values[1] != "200" {
+			failed += n
+		}
+	})
+	apiRetries.each(func(_ []string, n float64) { retries += n })
+	fmt.Fprintf(w, "stats: %s, %s, %s\n", plural(int(calls), "API call"), plural(int(failed), "failure"), plural(int(retries), "retry"))
+
+	lookups := map[string]float64{}
+	var total float64
+	cacheLookups.each(func(values []string, n float64) {
+		lookups[values[0]] += n
+		total += n
+	})
+	if total > 0 {
+		fmt.Fprintf(w, "stats: cache %s, %s, %s (%.0f%% hit rate)\n",
+			plural(int(lookups["hit"]), "hit"), plural(int(lookups["miss"]), "miss"), plural(int(lookups["stale"]), "stale response"),
+			(lookups["hit"]+lookups["stale"])/total*100)
+	}
+
+	type endpointStats struct {
+		endpoint string
+		histogram
+	}
+	var endpoints []endpointStats
+	requestDuration.each(func(values []string, h histogram) {
+		endpoints = append(endpoints, endpointStats{values[0], h})
+	})
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].endpoint < endpoints[j].endpoint })
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "stats: %s %s, latency avg %s, max %s\n", e.endpoint, plural(int(e.count), "call"),
+			seconds(e.sum/float64(e.count)), seconds(e.max))
+	}
+}
+
+// plural returns n with noun, pluralized unless n is 1.
+func plural(n int, noun string) string {
+	switch {
+	case n == 1:
+		return fmt.Sprintf("%d %s", n, noun)
+	case noun[len(noun)-1] == 'y':
+		return fmt.Sprintf("%d %sies", n, noun[:len(noun)-1])
+	case noun[len(noun)-1] == 's':
+		return fmt.Sprintf("%d %ses", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// seconds returns a duration given in seconds rounded for reading.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func summaryTimeFlag(fs *flag.FlagSet, opt *options, usage string) {
+	fs.StringVar(&opt.summaryTime, "summary-time", "07:00", usage)
+}
+
+// checkSummaryTime validates -summary-time and normalizes it to HH:MM, so
+// that it can be compared with formatted times.
+func checkSummaryTime(opt *options) {
+	t, err := time.Parse("15:04", opt.summaryTime)
+	if err != nil {
+		exitWithError(fmt.Sprintf("invalid summary-time %q, expected e.g. 07:00", opt.summaryTime))
+	}
+	opt.summaryTime = t.Format("15:04")
+}
+
+// nextSummaryTime returns the next time after now when the clock shows
+// the HH:MM time of day.
+func nextSummaryTime(now time.Time, hhmm string) time.Time {
+	t, _ := time.Parse("15:04", hhmm)
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// dailySummary is the current weather of a location together with the
+// forecast of the day.
+type dailySummary struct {
+	weather  *Weather
+	forecast *Forecast
+	today    *forecastDay // nil when the forecast has no entries
+}
+
+func (s *session) dailySummary(city string) (*dailySummary, error) {
+	w, err := s.provider().current(s.query(city))
+	if err != nil {
+		return nil, err
+	}
+	s.recordHistory(w, s.opt.units)
+
+	f, err := s.provider().forecast(s.query(city))
+	if err != nil {
+		return nil, err
+	}
+	sum := &dailySummary{weather: w, forecast: f}
+	if days := forecastDays(f); len(days) > 0 {
+		sum.today = days[0]
+	}
+	return sum, nil
+}
+
+// runSummary prints the daily summary of each location and posts it to the
+// configured chat webhooks. Run it from cron for a morning report.
+func runSummary(s *session) {
+	for _, city := range s.cities() {
+		sum, err := s.dailySummary(city)
+		if exitOnError(err) {
+			continue
+		}
+		displaySummary(os.Stdout, sum, s.opt)
+		s.postSlack(slackSummary(sum, s.opt))
+		s.postDiscord(sum.weather.CityName, discordSummary(sum, s.opt))
+	}
+	webhookDeliveries.Wait()
+}
+
+func displaySummary(w io.Writer, sum *dailySummary, opt *options) {
+	display(w, sum.weather, nil, nil, opt)
+	if sum.today != nil {
+		localFprintf(w, "  %s: %s\n", localDate(sum.today.date, "Mon 2 Jan"), sum.today.summary())
+	}
+}
+
+// summaryTime returns the time of the weather in the location's time zone
+// for display, e.g. "Mon 15 Jan 14:00".
+func summaryTime(w *Weather) string {
+	return localTime(w.Time, w.TimeZone).Format("Mon 2 Jan 15:04")
+}
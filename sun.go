@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// weather sun prints the time of the next sunrise or sunset, shifted by
+// -offset, for home automation: `weather sun -event sunset -offset -30m -o
+// unix helsinki` prints the Unix time half an hour before the sunset, to
+// schedule the blinds or the lights with. Only the coordinates of the
+// location are needed, and those are cached, so this makes no requests
+// once the location has been looked up.
+
+// sunHorizon is the altitude of the center of the sun at sunrise and
+// sunset, below the horizon by the refraction and the radius of the sun.
+const sunHorizon = -0.833
+
+// sunSearch is how far ahead the next sunrise or sunset is looked for.
+// There is none in the polar day or night.
+const sunSearch = 48 * time.Hour
+
+var sunEventValues = []string{"sunrise", "sunset"}
+
+func sunFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("event", "the event to time ("+strings.Join(sunEventValues, "|")+")", enumFlag(&opt.sunEvent, "event", sunEventValues))
+	fs.DurationVar(&opt.sunOffset, "offset", 0, "time relative to the event, e.g. -30m for half an hour before")
+}
+
+// sunTime is the next sunrise or sunset at a location and the time offset
+// from it.
+type sunTime struct {
+	City   string    `json:"city"`
+	Event  string    `json:"event"`
+	Sun    time.Time `json:"sun"`
+	Offset string    `json:"offset"`
+	Time   time.Time `json:"time"`
+	Unix   int64     `json:"unix"`
+
+	offset time.Duration
+}
+
+// nextSunEvent returns the first sunrise, or sunset with falling, at lat,
+// lon after now once shifted by offset.
+func nextSunEvent(now time.Time, lat, lon float64, falling bool, offset time.Duration) (time.Time, bool) {
+	start := now.Add(-offset).Truncate(time.Minute)
+	for end := start.Add(sunSearch); start.Before(end); start = start.Add(photoScanWindow - photoScanStep) {
+		// Consecutive scans share a minute so that no crossing falls
+		// between them.
+		if t, ok := crossing(start, lat, lon, sunHorizon, falling); ok && t.Add(offset).After(now) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// runSun prints the next sunrise or sunset of each location.
+func runSun(s *session) {
+	for _, city := range s.cities() {
+		l, err := s.provider().locate(city)
+		if exitOnError(err) {
+			continue
+		}
+		sun, ok := nextSunEvent(time.Now(), l.Lat, l.Lon, s.opt.sunEvent == "sunset", s.opt.sunOffset)
+		if !ok {
+			exitWithError(fmt.Sprintf("no %s in %s in the next %s", s.opt.sunEvent, l.Name, formatAge(sunSearch)))
+		}
+		at := sun.Add(s.opt.sunOffset)
+		displaySun(os.Stdout, &sunTime{
+			City:   l.Name,
+			Event:  s.opt.sunEvent,
+			Sun:    sun.UTC(),
+			Offset: s.opt.sunOffset.String(),
+			Time:   at.UTC(),
+			Unix:   at.Unix(),
+			offset: s.opt.sunOffset,
+		}, s.opt)
+	}
+}
+
+func displaySun(w io.Writer, t *sunTime, opt *options) {
+	switch {
+	case isJSON(opt.format):
+		writeJSON(w, t, opt.format)
+		return
+	case opt.format == "unix":
+		fmt.Fprintln(w, t.Unix)
+		return
+	}
+
+	at := localDate(t.Time.Local(), "15:04 on Mon 2 Jan")
+	switch {
+	case t.offset < 0:
+		localFprintf(w, "%s: %s before %s (%s) at %s\n", t.City, formatAge(-t.offset), t.Event, t.Sun.Local().Format("15:04"), at)
+	case t.offset > 0:
+		localFprintf(w, "%s: %s after %s (%s) at %s\n", t.City, formatAge(t.offset), t.Event, t.Sun.Local().Format("15:04"), at)
+	default:
+		localFprintf(w, "%s: %s at %s\n", t.City, t.Event, at)
+	}
+}
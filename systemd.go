@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemd integration of the daemon: readiness and watchdog notifications
+// (sd_notify) and socket activation (sd_listen_fds). Everything is a no-op
+// when the daemon is not started by systemd.
+// https://www.freedesktop.org/software/systemd/man/sd_notify.html
+
+// sdListenFdsStart is the first file descriptor passed by systemd.
+const sdListenFdsStart = 3
+
+// sdNotify sends state, e.g. "READY=1", to the service manager.
+func sdNotify(state string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	// A leading @ is a socket in the abstract namespace.
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns the watchdog timeout of the service, or zero
+// when the watchdog is not enabled for this process.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// sdListener is a socket passed by systemd socket activation with its
+// FileDescriptorName, which defaults to the name of the socket unit, so
+// that the sockets of a unit with several ListenStream= share it.
+type sdListener struct {
+	name string
+	net.Listener
+}
+
+// sdListeners returns the sockets passed by systemd socket activation, in
+// the order they were passed. The environment variables are unset so that
+// child processes do not take the sockets for their own.
+func sdListeners() ([]sdListener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	var listeners []sdListener
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(sdListenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket activation: %s: %w", name, err)
+		}
+		listeners = append(listeners, sdListener{name, l})
+	}
+	return listeners, nil
+}
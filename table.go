@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// table aligns tab-separated columns like text/tabwriter, but by the width
+// the text takes on a terminal rather than by its number of runes, so that
+// names such as 東京 and emoji, two columns wide, and the variation
+// selectors and joiners of emoji, none, keep the columns straight.
+//
+// As with tabwriter, a cell is the text before a tab, the text after the
+// last tab of a line is not aligned, and a column spans the consecutive
+// lines having a cell in it.
+type table struct {
+	w       io.Writer
+	padding int
+	buf     bytes.Buffer
+
+	lines  [][]string
+	widths []int
+	out    strings.Builder
+}
+
+// newTable returns a table writing to w with padding spaces between the
+// columns.
+func newTable(w io.Writer, padding int) *table {
+	return &table{w: w, padding: padding}
+}
+
+func (t *table) Write(b []byte) (int, error) {
+	return t.buf.Write(b)
+}
+
+// Flush writes the aligned text.
+func (t *table) Flush() error {
+	text := t.buf.String()
+	t.buf.Reset()
+	newline := strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+
+	t.lines, t.widths = t.lines[:0], t.widths[:0]
+	t.out.Reset()
+	for _, line := range strings.Split(text, "\n") {
+		t.lines = append(t.lines, strings.Split(line, "\t"))
+	}
+	t.format(0, len(t.lines))
+	if !newline {
+		// The last line is written with a newline like the others.
+		s := t.out.String()
+		_, err := io.WriteString(t.w, s[:len(s)-1])
+		return err
+	}
+	_, err := io.WriteString(t.w, t.out.String())
+	return err
+}
+
+// format aligns the lines from line0 to line1 in the columns after those in
+// t.widths, as the format method of tabwriter does.
+func (t *table) format(line0, line1 int) {
+	column := len(t.widths)
+	for this := line0; this < line1; this++ {
+		if column >= len(t.lines[this])-1 {
+			continue
+		}
+		// The lines before are not in this column.
+		t.writeLines(line0, this)
+		line0 = this
+
+		cellWidth := 0
+		for ; this < line1 && column < len(t.lines[this])-1; this++ {
+			cellWidth = max(cellWidth, displayWidth(t.lines[this][column])+t.padding)
+		}
+		t.widths = append(t.widths, cellWidth)
+		t.format(line0, this)
+		t.widths = t.widths[:len(t.widths)-1]
+		line0 = this
+	}
+	t.writeLines(line0, line1)
+}
+
+func (t *table) writeLines(line0, line1 int) {
+	for _, line := range t.lines[line0:line1] {
+		for j, cell := range line {
+			t.out.WriteString(cell)
+			if j < len(t.widths) {
+				t.out.WriteString(strings.Repeat(" ", t.widths[j]-displayWidth(cell)))
+			}
+		}
+		t.out.WriteByte('\n')
+	}
+}
+
+// displayWidth returns how many columns s takes on a terminal: two for
+// wide characters such as CJK and emoji, none for combining marks, joiners
+// and ANSI escape sequences. A variation selector giving a character the
+// emoji presentation, as in ❄️, makes it two columns wide.
+func displayWidth(s string) int {
+	n, last := 0, 0
+	joined := false
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "\x1b[") {
+			// Skip the sequence up to its final byte.
+			for i += 2; i < len(s) && (s[i] < 0x40 || s[i] > 0x7e); i++ {
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch {
+		case r == 0x200d:
+			joined = true
+			continue
+		case r == 0xfe0f:
+			if last == 1 {
+				n++
+				last = 2
+			}
+			continue
+		case joined:
+			// The character joins the previous one into one emoji.
+			joined = false
+			continue
+		case r < 0x20 || r == 0x7f || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+			continue
+		}
+		last = 1
+		if k := width.LookupRune(r).Kind(); k == width.EastAsianWide || k == width.EastAsianFullwidth {
+			last = 2
+		}
+		n += last
+	}
+	return n
+}
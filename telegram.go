@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The bot talks to the Telegram Bot API with long polling, so it needs no
+// public address: https://core.telegram.org/bots/api
+
+const TELEGRAM_API_URL = "https://api.telegram.org/bot%s/%s"
+
+// telegramPollTimeout is how long a getUpdates request waits for messages.
+const telegramPollTimeout = 50 * time.Second
+
+// telegramMessageLength is the maximum length of a message.
+const telegramMessageLength = 4096
+
+func telegramFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.telegramToken, "telegram-token", "", "Telegram bot token from @BotFather")
+	fs.StringVar(&opt.telegramChats, "telegram-chats", "", "comma separated chat IDs allowed to use the bot (default all)")
+	summaryTimeFlag(fs, opt, "local time of day when subscribers get the daily forecast")
+	fs.StringVar(&opt.subscriptionsPath, "subscriptions", defaultDataPath("telegram.json"), "path of the subscriptions file")
+}
+
+const telegramHelp = `/weather <city> - current weather and the forecast of the day
+/forecast <city> - forecast of the next days
+/subscribe <city> - daily forecast and severe weather warnings
+/unsubscribe [<city>] - stop the daily forecasts of a city, or of all
+/subscriptions - list the subscriptions
+
+Without a city the default location of the bot is used.`
+
+// telegramSubscription subscribes a chat to the daily forecast and the
+// weather warnings of a location.
+type telegramSubscription struct {
+	ChatID      int64  `json:"chat_id"`
+	City        string `json:"city"` // as given, used in queries
+	Name        string `json:"name"` // as named by the provider
+	LastSummary string `json:"last_summary,omitempty"`
+}
+
+type telegramBot struct {
+	s      *session
+	client *http.Client
+	chats  []int64 // allowed chats, empty for all
+
+	mu            sync.Mutex
+	subscriptions []*telegramSubscription
+	alertsSent    map[string]bool // keyed by location and alert
+	noAlerts      bool
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+func runBot(s *session) {
+	if args := s.args(); len(args) != 1 || args[0] != "telegram" {
+		exitWithUsageError("usage: weather bot telegram")
+	}
+	if s.opt.telegramToken == "" {
+		exitWithError("Telegram bot token is required, use -telegram-token or WEATHER_TELEGRAM_TOKEN")
+	}
+	checkSummaryTime(s.opt)
+	if s.opt.interval < minWatchInterval {
+		exitWithUsageError(fmt.Sprintf("interval must be at least %s", minWatchInterval))
+	}
+	s.provider()
+
+	b := &telegramBot{
+		s: s,
+		// Long polls outlast the timeout of ordinary requests.
+		client:     &http.Client{Transport: httpClient.Transport, Timeout: telegramPollTimeout + s.opt.timeout},
+		alertsSent: map[string]bool{},
+	}
+	for _, id := range parseKeys(s.opt.telegramChats) {
+		chat, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			exitWithError(fmt.Sprintf("invalid chat ID %q", id))
+		}
+		b.chats = append(b.chats, chat)
+	}
+	if err := b.load(); err != nil {
+		exitWithError("bot: " + err.Error())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go b.schedule(ctx)
+	fmt.Fprintf(os.Stderr, "weather bot running with %d subscriptions\n", len(b.subscriptions))
+	b.poll(ctx)
+}
+
+// call calls a Bot API method and decodes its result into result.
+func (b *telegramBot) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(TELEGRAM_API_URL, b.s.opt.telegramToken, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	if !reply.OK {
+		return fmt.Errorf("%s: %s", method, reply.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+func (b *telegramBot) send(chat int64, text string) {
+	params := map[string]any{"chat_id": chat, "text": truncate(text, telegramMessageLength)}
+	if err := b.call(context.Background(), "sendMessage", params, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: telegram: %s\n", redact(err.Error()))
+	}
+}
+
+// poll answers messages until ctx is done.
+func (b *telegramBot) poll(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []telegramUpdate
+		params := map[string]any{"offset": offset, "timeout": int(telegramPollTimeout.Seconds()), "allowed_updates": []string{"message"}}
+		if err := b.call(ctx, "getUpdates", params, &updates); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "WARNING: telegram: %s\n", redact(err.Error()))
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+				continue
+			}
+			chat := u.Message.Chat.ID
+			if len(b.chats) > 0 && !slices.Contains(b.chats, chat) {
+				continue
+			}
+			b.send(chat, b.answer(chat, u.Message.Text))
+		}
+	}
+}
+
+// answer handles a command, e.g. "/weather helsinki", and returns the reply.
+func (b *telegramBot) answer(chat int64, text string) string {
+	fields := strings.Fields(text)
+	// In groups commands may be addressed to the bot: /weather@name_bot.
+	command, _, _ := strings.Cut(fields[0], "@")
+	city := strings.Join(fields[1:], " ")
+
+	switch command {
+	case "/start", "/help":
+		return telegramHelp
+
+	case "/weather":
+		city, err := b.city(city)
+		if err != nil {
+			return err.Error()
+		}
+		sum, err := b.s.dailySummary(city)
+		if err != nil {
+			return telegramError(city, err)
+		}
+		var buf bytes.Buffer
+		displaySummary(&buf, sum, b.s.opt)
+		return buf.String()
+
+	case "/forecast":
+		city, err := b.city(city)
+		if err != nil {
+			return err.Error()
+		}
+		f, err := b.s.provider().forecast(b.s.query(city))
+		if err != nil {
+			return telegramError(city, err)
+		}
+		var buf bytes.Buffer
+		for _, d := range forecastDays(f) {
+			fmt.Fprintf(&buf, "%s: %s\n", localDate(d.date, "Mon 2 Jan"), d.summary())
+		}
+		return f.CityName + "\n" + buf.String()
+
+	case "/subscribe":
+		city, err := b.city(city)
+		if err != nil {
+			return err.Error()
+		}
+		w, err := b.s.provider().current(b.s.query(city))
+		if err != nil {
+			return telegramError(city, err)
+		}
+		return b.subscribe(chat, city, w.CityName)
+
+	case "/unsubscribe":
+		return b.unsubscribe(chat, city)
+
+	case "/subscriptions":
+		var names []string
+		b.mu.Lock()
+		for _, sub := range b.subscriptions {
+			if sub.ChatID == chat {
+				names = append(names, sub.Name)
+			}
+		}
+		b.mu.Unlock()
+		if len(names) == 0 {
+			return "No subscriptions, add one with /subscribe <city>."
+		}
+		return fmt.Sprintf("Daily forecasts at %s for %s.", b.s.opt.summaryTime, strings.Join(names, ", "))
+	}
+	return "Unknown command, see /help."
+}
+
+// city expands aliases and falls back to the default city of the bot.
+func (b *telegramBot) city(city string) (string, error) {
+	if city == "" {
+		if cities := b.s.cfg.list("city"); len(cities) > 0 {
+			return cities[0], nil
+		}
+		return "", errors.New("Which city? E.g. /weather Helsinki")
+	}
+	if alias, ok := b.s.cfg.string("aliases." + city); ok {
+		return alias, nil
+	}
+	return city, nil
+}
+
+func telegramError(city string, err error) string {
+	var nf *notFoundError
+	if errors.As(err, &nf) {
+		return fmt.Sprintf("No location found for %q.", city)
+	}
+	fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", city, redact(err.Error()))
+	return "The weather service could not be reached, try again later."
+}
+
+func (b *telegramBot) subscribe(chat int64, city, name string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subscriptions {
+		if sub.ChatID == chat && sub.Name == name {
+			return fmt.Sprintf("Already subscribed to %s.", name)
+		}
+	}
+	b.subscriptions = append(b.subscriptions, &telegramSubscription{ChatID: chat, City: city, Name: name})
+	if err := b.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: bot: %s\n", err)
+	}
+	return fmt.Sprintf("Subscribed to the daily forecast of %s at %s and its severe weather warnings.", name, b.s.opt.summaryTime)
+}
+
+func (b *telegramBot) unsubscribe(chat int64, city string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var removed []string
+	b.subscriptions = slices.DeleteFunc(b.subscriptions, func(sub *telegramSubscription) bool {
+		if sub.ChatID == chat && (city == "" || strings.EqualFold(sub.City, city) || strings.EqualFold(sub.Name, city)) {
+			removed = append(removed, sub.Name)
+			return true
+		}
+		return false
+	})
+	if len(removed) == 0 {
+		return "No matching subscriptions."
+	}
+	if err := b.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: bot: %s\n", err)
+	}
+	return fmt.Sprintf("Unsubscribed from %s.", strings.Join(removed, ", "))
+}
+
+// schedule sends the daily forecasts at -summary-time and checks for new
+// warnings every -interval.
+func (b *telegramBot) schedule(ctx context.Context) {
+	minute := time.NewTicker(time.Minute)
+	defer minute.Stop()
+	alerts := time.NewTicker(b.s.opt.interval)
+	defer alerts.Stop()
+
+	b.sendAlerts()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-minute.C:
+			b.sendSummaries(now)
+		case <-alerts.C:
+			b.sendAlerts()
+		}
+	}
+}
+
+func (b *telegramBot) sendSummaries(now time.Time) {
+	if now.Format("15:04") < b.s.opt.summaryTime {
+		return
+	}
+	today := now.Format("2006-01-02")
+
+	b.mu.Lock()
+	var due []*telegramSubscription
+	for _, sub := range b.subscriptions {
+		if sub.LastSummary != today {
+			sub.LastSummary = today
+			due = append(due, sub)
+		}
+	}
+	if len(due) > 0 {
+		if err := b.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: bot: %s\n", err)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, sub := range due {
+		sum, err := b.s.dailySummary(sub.City)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", sub.City, redact(err.Error()))
+			continue
+		}
+		var buf bytes.Buffer
+		displaySummary(&buf, sum, b.s.opt)
+		b.send(sub.ChatID, buf.String())
+	}
+}
+
+// sendAlerts sends each subscriber the warnings of their locations that
+// they have not been sent yet.
+func (b *telegramBot) sendAlerts() {
+	b.mu.Lock()
+	if b.noAlerts {
+		b.mu.Unlock()
+		return
+	}
+	chats := map[string][]int64{} // by city
+	for _, sub := range b.subscriptions {
+		chats[sub.City] = append(chats[sub.City], sub.ChatID)
+	}
+	b.mu.Unlock()
+
+	for city, ids := range chats {
+		alerts, err := b.s.provider().alerts(b.s.query(city))
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+			b.mu.Lock()
+			b.noAlerts = true
+			b.mu.Unlock()
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s: alerts: %s\n", city, redact(err.Error()))
+			continue
+		}
+
+		for _, a := range alerts {
+			key := fmt.Sprintf("%s|%s|%d", strings.ToLower(city), a.Event, a.Start.Unix())
+			b.mu.Lock()
+			sent := b.alertsSent[key]
+			b.alertsSent[key] = true
+			b.mu.Unlock()
+			if sent || time.Now().After(a.End) {
+				continue
+			}
+			text := fmt.Sprintf("⚠️ %s: %s\n%s – %s\n\n%s", city, a.Event, a.Start.Local().Format("Mon 2 Jan 15:04"), a.End.Local().Format("Mon 2 Jan 15:04"), a.Description)
+			for _, id := range ids {
+				b.send(id, text)
+			}
+		}
+	}
+}
+
+func (b *telegramBot) load() error {
+	data, err := os.ReadFile(b.s.opt.subscriptionsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &b.subscriptions)
+}
+
+// save writes the subscriptions through a temporary file so that a crash
+// never leaves a truncated file behind. Callers hold mu.
+func (b *telegramBot) save() error {
+	path := b.s.opt.subscriptionsPath
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b.subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// On a terminal weather map shows the map in it with the graphics protocol
+// of the terminal: those of kitty and iTerm2, which take a PNG image, or
+// Sixel. Other terminals get the map drawn with characters.
+
+var graphicsValues = []string{"auto", "kitty", "iterm", "sixel", "cells"}
+
+// terminalGraphics guesses the graphics protocol of the terminal from its
+// environment. Inside tmux or screen images rarely get through, so cells
+// are drawn.
+func terminalGraphics() string {
+	t, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("TMUX") != "" || strings.HasPrefix(t, "screen") || strings.HasPrefix(t, "tmux"):
+		return "cells"
+	case os.Getenv("KITTY_WINDOW_ID") != "" || t == "xterm-kitty" || t == "xterm-ghostty":
+		return "kitty"
+	case program == "iTerm.app" || program == "WezTerm":
+		return "iterm"
+	case strings.Contains(t, "sixel") || t == "foot" || strings.HasPrefix(t, "foot-") || t == "mlterm" || t == "yaft-256color":
+		return "sixel"
+	}
+	return "cells"
+}
+
+// displayMap writes the map to w, a terminal, with the graphics protocol.
+func displayMap(w io.Writer, base, weather *image.RGBA, graphics string, opt *options) error {
+	img := composeMap(base, weather)
+	if graphics == "cells" {
+		writeMapCells(w, img, weather, colorOutput(w))
+		fmt.Fprintln(w, mapAttribution(opt))
+		return nil
+	}
+
+	drawAttribution(img, opt)
+	if graphics == "sixel" {
+		writeSixel(w, img)
+		return nil
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+	if graphics == "kitty" {
+		writeKittyImage(w, b.Bytes())
+	} else {
+		writeITermImage(w, b.Bytes())
+	}
+	return nil
+}
+
+// writeKittyImage writes a PNG image in the kitty graphics protocol, in
+// chunks of at most 4096 bytes of base64:
+// https://sw.kovidgoyal.net/kitty/graphics-protocol/
+func writeKittyImage(w io.Writer, pngData []byte) {
+	data := base64.StdEncoding.EncodeToString(pngData)
+	for first := true; len(data) > 0; first = false {
+		chunk := data[:min(len(data), 4096)]
+		data = data[len(chunk):]
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(w, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeITermImage writes a PNG image as an inline file of iTerm2:
+// https://iterm2.com/documentation-images.html
+func writeITermImage(w io.Writer, pngData []byte) {
+	fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;preserveAspectRatio=1:%s\a\n", len(pngData), base64.StdEncoding.EncodeToString(pngData))
+}
+
+// writeSixel writes img as Sixel graphics in the 216 colors of a 6×6×6
+// color cube. Each band of six pixel rows is written one color at a time,
+// with runs of the same sixel compressed.
+func writeSixel(w io.Writer, img *image.RGBA) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", width, height)
+	for i := 0; i < 216; i++ {
+		r, g, b := i/36, i/6%6, i%6
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i, r*20, g*20, b*20)
+	}
+
+	sixels := make([][]byte, 216)
+	for top := 0; top < height; top += 6 {
+		used := make([]bool, 216)
+		for y := top; y < min(top+6, height); y++ {
+			for x := 0; x < width; x++ {
+				c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+				i := int(c.R)*6/256*36 + int(c.G)*6/256*6 + int(c.B)*6/256
+				if !used[i] {
+					used[i] = true
+					if sixels[i] == nil {
+						sixels[i] = make([]byte, width)
+					}
+					clear(sixels[i])
+				}
+				sixels[i][x] |= 1 << (y - top)
+			}
+		}
+		for i, ok := range used {
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&out, "#%d", i)
+			row := sixels[i]
+			for x := 0; x < width; {
+				run := 1
+				for x+run < width && row[x+run] == row[x] {
+					run++
+				}
+				char := string(rune(63 + row[x]))
+				if run > 3 {
+					fmt.Fprintf(&out, "!%d%s", run, char)
+				} else {
+					out.WriteString(strings.Repeat(char, run))
+				}
+				x += run
+			}
+			out.WriteString("$")
+		}
+		out.WriteString("-")
+	}
+	out.WriteString("\x1b\\\n")
+	io.WriteString(w, out.String())
+}
+
+// mapShades are the characters of the heatmap of the weather layer, from
+// clear to the most intense.
+var mapShades = []rune(" ░▒▓█")
+
+// writeMapCells draws the map with characters as wide as the terminal
+// allows. In color each character shows two pixels, one in the foreground
+// of an upper half block and one in the background; without color the
+// intensity of the weather layer is shaded.
+func writeMapCells(w io.Writer, img, weather *image.RGBA, colored bool) {
+	columns, rows := 80, 40
+	if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		columns, rows = width, height-2
+	}
+	// Characters are about twice as tall as wide, so a square map has
+	// half as many rows as columns.
+	size := min(columns, max(2*rows, 20))
+	scale := float64(mapSize) / float64(size)
+
+	// average returns the mean color of the pixels of src in the cell
+	// at column x and half row y.
+	average := func(src *image.RGBA, x, y, halves int) (r, g, b, a int) {
+		x0, x1 := int(float64(x)*scale), int(float64(x+1)*scale)
+		y0, y1 := int(float64(y)*scale*2/float64(halves)), int(float64(y+1)*scale*2/float64(halves))
+		n := 0
+		for py := y0; py < max(y1, y0+1); py++ {
+			for px := x0; px < max(x1, x0+1); px++ {
+				c := src.RGBAAt(px, py)
+				r, g, b, a = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A)
+				n++
+			}
+		}
+		return r / n, g / n, b / n, a / n
+	}
+
+	var out strings.Builder
+	for y := 0; y < size/2; y++ {
+		for x := 0; x < size; x++ {
+			if colored {
+				r1, g1, b1, _ := average(img, x, 2*y, 2)
+				r2, g2, b2, _ := average(img, x, 2*y+1, 2)
+				fmt.Fprintf(&out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", r1, g1, b1, r2, g2, b2)
+				continue
+			}
+			if x == size/2 && y == size/4 {
+				out.WriteRune('+')
+				continue
+			}
+			_, _, _, a := average(weather, x, y, 1)
+			out.WriteRune(mapShades[a*len(mapShades)/256])
+		}
+		if colored {
+			out.WriteString("\x1b[0m")
+		}
+		out.WriteString("\n")
+	}
+	io.WriteString(w, out.String())
+}
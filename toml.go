@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The config file uses a small subset of TOML: tables, bare or quoted keys,
+// and string, integer, float, boolean and string array values. Parsed files
+// are flattened into dotted keys, e.g. [profile.work] units = "metric"
+// becomes "profile.work.units". The parts of each key are returned too, as
+// quoted parts may contain dots themselves, e.g. [aliases] "St. Louis".
+
+func parseTOML(r io.Reader) (values map[string]any, keys map[string][]string, err error) {
+	values, keys = map[string]any{}, map[string][]string{}
+	var table []string
+
+	sc := bufio.NewScanner(r)
+	lineNumber := 0
+	for sc.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(stripTOMLComment(sc.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") || !strings.HasSuffix(line, "]") {
+				return nil, nil, fmt.Errorf("line %d: invalid table header", lineNumber)
+			}
+			if table, err = parseTOMLKey(line[1 : len(line)-1]); err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+
+		// A quoted key may contain the = itself.
+		parts := splitTOML(line, '=')
+		if len(parts) < 2 {
+			return nil, nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		k, v := parts[0], strings.Join(parts[1:], "=")
+		key, err := parseTOMLKey(k)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		v = strings.TrimSpace(v)
+		// Arrays may span several lines.
+		for strings.HasPrefix(v, "[") && !tomlArrayClosed(v) && sc.Scan() {
+			lineNumber++
+			v += " " + strings.TrimSpace(stripTOMLComment(sc.Text()))
+		}
+
+		value, err := parseTOMLValue(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		key = append(slices.Clip(table), key...)
+		values[strings.Join(key, ".")] = value
+		keys[strings.Join(key, ".")] = key
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	return values, keys, nil
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func tomlArrayClosed(v string) bool {
+	return strings.HasSuffix(strings.TrimSpace(v), "]")
+}
+
+// parseTOMLKey parses a possibly dotted and quoted key into its parts.
+func parseTOMLKey(s string) ([]string, error) {
+	var parts []string
+	for _, part := range splitTOML(s, '.') {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, `"`) || strings.HasPrefix(part, "'") {
+			unquoted, err := parseTOMLString(part)
+			if err != nil {
+				return nil, err
+			}
+			part = unquoted
+		}
+		if part == "" {
+			return nil, fmt.Errorf("invalid key %q", strings.TrimSpace(s))
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func parseTOMLValue(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("missing value")
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s[0] == '"' || s[0] == '\'':
+		return parseTOMLString(s)
+	case s[0] == '[':
+		if !tomlArrayClosed(s) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		items := []string{}
+		for _, item := range splitTOML(s[1:len(s)-1], ',') {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			str, err := parseTOMLString(item)
+			if err != nil {
+				return nil, fmt.Errorf("only arrays of strings are supported")
+			}
+			items = append(items, str)
+		}
+		return items, nil
+	}
+
+	clean := strings.ReplaceAll(s, "_", "")
+	if i, err := strconv.ParseInt(clean, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
+
+func parseTOMLString(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strconv.Unquote(s)
+	}
+	return "", fmt.Errorf("invalid string %s", s)
+}
+
+// splitTOML splits s on sep outside of quoted strings.
+func splitTOML(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{
+			name:  "bare keys",
+			input: "city = \"Helsinki\"\nparallel = 4\nwind = 1_000\nbase = 17.5\nhistory = true\nfirst = false\n",
+			want: map[string]any{
+				"city": "Helsinki", "parallel": int64(4), "wind": int64(1000), "base": 17.5,
+				"history": true, "first": false,
+			},
+		},
+		{
+			name:  "quoted keys",
+			input: "\"new york\" = 1\n'los angeles' = 2\n\"a.b\" = 3\n\"x=y\" = 4\nsite.\"my host\" = 5\n",
+			want:  map[string]any{"new york": int64(1), "los angeles": int64(2), "a.b": int64(3), "x=y": int64(4), "site.my host": int64(5)},
+		},
+		{
+			name:  "escapes",
+			input: `tab = "a\tb"` + "\n" + `quote = "say \"hi\""` + "\n" + `unicode = "\u00e4"` + "\n" + `literal = 'C:\temp\n'` + "\n",
+			want:  map[string]any{"tab": "a\tb", "quote": `say "hi"`, "unicode": "ä", "literal": `C:\temp\n`},
+		},
+		{
+			name:  "arrays",
+			input: "empty = []\none = [\"a\"]\nmixed = [ 'a', \"b,c\" , ]\nlines = [\n  \"x\", # first\n  \"y\",\n]\n",
+			want: map[string]any{
+				"empty": []string{}, "one": []string{"a"}, "mixed": []string{"a", "b,c"}, "lines": []string{"x", "y"},
+			},
+		},
+		{
+			name:  "inline comments",
+			input: "# a comment\ncity = \"Helsinki\" # the default\nhash = \"#1\" # not the first\nsingle = 'a#b'\n   # indented\n",
+			want:  map[string]any{"city": "Helsinki", "hash": "#1", "single": "a#b"},
+		},
+		{
+			name:  "tables",
+			input: "units = \"metric\"\n[profiles.work]\ncity = \"Espoo\"\n[ profiles.\"summer cabin\" ] # comment\nunits = \"imperial\"\n[aliases]\nhome = \"Helsinki\"\n",
+			want: map[string]any{
+				"units": "metric", "profiles.work.city": "Espoo", "profiles.summer cabin.units": "imperial",
+				"aliases.home": "Helsinki",
+			},
+		},
+		{
+			name:  "empty",
+			input: "\n\n# only comments\n",
+			want:  map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		got, _, err := parseTOML(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTOMLKeyParts(t *testing.T) {
+	input := "city = \"Oulu\"\n[aliases]\n\"St. Louis\" = \"St. Louis,US\"\n[profile.\"v1.2\"]\nunits = \"imperial\"\n"
+	_, keys, err := parseTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"city":               {"city"},
+		"aliases.St. Louis":  {"aliases", "St. Louis"},
+		"profile.v1.2.units": {"profile", "v1.2", "units"},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got the key parts %q, want %q", keys, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"city\n", "line 1: expected key = value"},
+		{"a = 1\n\n[[profiles]]\n", "line 3: invalid table header"},
+		{"[profiles.work\n", "line 1: invalid table header"},
+		{"[profiles..work]\n", `line 1: invalid key "profiles..work"`},
+		{"a = 1\n\"\" = 2\n", `line 2: invalid key "\"\""`},
+		{"a =\n", "line 1: missing value"},
+		{"a = # nothing\n", "line 1: missing value"},
+		{"a = \"unterminated\n", `line 1: invalid string "unterminated`},
+		{"a = \"bad \\q\"\n", "line 1: invalid syntax"},
+		{"a = [1, 2]\n", "line 1: only arrays of strings are supported"},
+		{"a = [\n  \"x\",\n  \"y\"\n", "line 3: unterminated array"},
+		{"a = [\n  \"x\",\n]\nb = maybe\n", `line 4: invalid value "maybe"`},
+	}
+	for _, tt := range tests {
+		_, _, err := parseTOML(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseTOML(%q): got error %v, want %s", tt.input, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// The daemon and the server may answer many clients through one process,
+// so the connections they keep to the provider can be tuned: whether
+// HTTP/2 is used, how many idle connections are kept to each host for the
+// next requests and for how long.
+
+func transportFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.http2, "http2", true, "use HTTP/2 with the hosts supporting it")
+	fs.IntVar(&opt.maxIdleConns, "max-idle-conns", 2, "idle connections kept open to each host, 0 closes each after its request")
+	fs.DurationVar(&opt.idleTimeout, "idle-timeout", 90*time.Second, "how long idle connections are kept open")
+}
+
+// tuneTransport applies the transport options of opt.
+func tuneTransport(opt *options) {
+	if opt.maxIdleConns < 0 {
+		exitWithUsageError("max-idle-conns must not be negative")
+	}
+	updateTransport(func(t *http.Transport) {
+		if !opt.http2 {
+			// A non-nil map turns the HTTP/2 upgrade off, and the TLS
+			// handshake must not offer it either.
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+			if t.TLSClientConfig != nil {
+				t.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(t.TLSClientConfig.NextProtos), func(p string) bool { return p == "h2" })
+			}
+		}
+		t.MaxIdleConnsPerHost = opt.maxIdleConns
+		t.MaxIdleConns = max(t.MaxIdleConns, opt.maxIdleConns)
+		t.DisableKeepAlives = opt.maxIdleConns == 0
+		t.IdleConnTimeout = opt.idleTimeout
+	})
+}
+
+// updateTransport replaces the default transport with a copy changed by
+// fn. A default transport replaced by something else than an
+// http.Transport is left alone.
+func updateTransport(fn func(t *http.Transport)) {
+	t, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return
+	}
+	t = t.Clone()
+	fn(t)
+	http.DefaultTransport = t
+}
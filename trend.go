@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// trendPeriods are the periods a temperature is compared over. An
+// observation counts as "this time yesterday" when it is within tolerance of
+// exactly one period before the reading.
+var trendPeriods = []struct {
+	name      string
+	period    time.Duration
+	tolerance time.Duration
+	phrase    string
+}{
+	{"24h", 24 * time.Hour, 2 * time.Hour, "this time yesterday"},
+	{"7d", 7 * 24 * time.Hour, 12 * time.Hour, "a week ago"},
+}
+
+type trendDelta struct {
+	Period string    `json:"period"`
+	Since  time.Time `json:"since"`
+	Delta  float64   `json:"delta"`
+}
+
+type trend struct {
+	City        string        `json:"city"`
+	Time        time.Time     `json:"time"`
+	Temperature float64       `json:"temperature"`
+	Deltas      []*trendDelta `json:"deltas"`
+}
+
+// trend compares the temperature of w against the history recorded under
+// its city name. Periods without an observation close enough to compare
+// against are left out.
+func (s *session) trend(w *Weather) *trend {
+	longest := trendPeriods[len(trendPeriods)-1]
+	since := w.Time.Add(-longest.period - longest.tolerance)
+	observations, err := s.historyDB().observations(w.CityName, since, time.Time{})
+	if err != nil {
+		exitWithError("history: " + err.Error())
+	}
+
+	t := &trend{City: w.CityName, Time: w.Time, Temperature: w.Temperature}
+	for _, p := range trendPeriods {
+		target := w.Time.Add(-p.period)
+		var nearest *observation
+		for _, o := range observations {
+			if d := absDuration(o.Time.Sub(target)); d <= p.tolerance && (nearest == nil || d < absDuration(nearest.Time.Sub(target))) {
+				nearest = o
+			}
+		}
+		if nearest != nil {
+			ref := fromMetric(&nearest.Weather, s.opt.units)
+			t.Deltas = append(t.Deltas, &trendDelta{Period: p.name, Since: ref.Time, Delta: math.Round((w.Temperature-ref.Temperature)*10) / 10})
+		}
+	}
+	return t
+}
+
+// recentTemperatures returns the temperatures recorded under the city name
+// of w during the day before it, oldest first, ending with the temperature
+// of w itself.
+func (s *session) recentTemperatures(w *Weather) []float64 {
+	observations, err := s.historyDB().observations(w.CityName, w.Time.Add(-24*time.Hour), w.Time)
+	if err != nil {
+		exitWithError("history: " + err.Error())
+	}
+
+	var temperatures []float64
+	for _, o := range observations {
+		temperatures = append(temperatures, fromMetric(&o.Weather, s.opt.units).Temperature)
+	}
+	if len(temperatures) == 0 {
+		return nil
+	}
+	return append(temperatures, w.Temperature)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// describe returns a phrase such as "↑ 3.1° warmer than this time yesterday".
+func (d *trendDelta) describe() string {
+	phrase := d.Period
+	for _, p := range trendPeriods {
+		if p.name == d.Period {
+			phrase = p.phrase
+		}
+	}
+
+	switch {
+	case d.Delta > 0:
+		return localSprintf("↑ %.1f° warmer than %s", d.Delta, phrase)
+	case d.Delta < 0:
+		return localSprintf("↓ %.1f° colder than %s", -d.Delta, phrase)
+	default:
+		return localSprintf("→ same as %s", phrase)
+	}
+}
+
+func runTrend(s *session) {
+	for _, city := range s.cities() {
+		observations, err := s.historyDB().observations(city, time.Now().Add(-24*time.Hour), time.Time{})
+		if err != nil {
+			exitWithError("history: " + err.Error())
+		}
+		if len(observations) == 0 {
+			exitWithError(fmt.Sprintf("no observations of %s recorded in the last 24 hours", historyLocation(city)))
+		}
+
+		latest := fromMetric(&observations[len(observations)-1].Weather, s.opt.units)
+		displayTrend(os.Stdout, s.trend(latest), s.opt)
+	}
+}
+
+func displayTrend(w io.Writer, t *trend, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, struct {
+			*trend
+			Units string `json:"units"`
+		}{t, opt.units}, opt.format)
+		return
+	}
+
+	temperatureSymbol, _ := unitSymbols(opt.units)
+	localFprintf(w, "%s %.0f°%s at %s\n", t.City, t.Temperature, temperatureSymbol, t.Time.Local().Format("2006-01-02 15:04"))
+	if len(t.Deltas) == 0 {
+		localFprintf(w, "  not enough history to compare against\n")
+	}
+	for _, d := range t.Deltas {
+		localFprintf(w, "  %-4s %s\n", d.Period+":", d.describe())
+	}
+}
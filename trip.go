@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// weather trip summarizes the weather of each day of a trip with what to
+// pack for it: the daily forecast for the days within the forecast range and
+// the climate normals of the days beyond it.
+
+// maxTripDays is the longest trip summarized.
+const maxTripDays = 31
+
+func tripFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("from", "first day of the trip, e.g. 2024-07-10 (default today)", dateFlag(&opt.tripFrom))
+	fs.Func("to", "last day of the trip, e.g. 2024-07-14 (default the first day)", dateFlag(&opt.tripTo))
+}
+
+// dateFlag parses a date. The date is kept at midnight UTC like the dates of
+// forecastDay, which are calendar days at the location.
+func dateFlag(dst *time.Time) func(string) error {
+	return func(value string) error {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return errors.New("expected a date such as 2024-07-10")
+		}
+		*dst = t
+		return nil
+	}
+}
+
+// tripDay is the weather of a day of the trip. Source is "forecast",
+// "normal" for the climate normals or empty when there is no data.
+type tripDay struct {
+	Date          string   `json:"date"`
+	Source        string   `json:"source,omitempty"`
+	Low           float64  `json:"low"`
+	High          float64  `json:"high"`
+	Precipitation float64  `json:"precipitation"`
+	Probability   float64  `json:"precipitation_probability,omitempty"`
+	WindSpeed     float64  `json:"wind_speed"`
+	Conditions    string   `json:"conditions,omitempty"`
+	Icon          string   `json:"icon,omitempty"`
+	Pack          []string `json:"pack"`
+}
+
+// trip is the summary of a trip at a location.
+type trip struct {
+	City string    `json:"city"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Days []tripDay `json:"days"`
+	Note string    `json:"note,omitempty"`
+}
+
+// pack returns what to pack for a day. The thresholds are metric, so the
+// weather is converted from units first.
+func (d *tripDay) pack(units string) []string {
+	m := toMetric(&Weather{Temperature: d.High, WindSpeed: d.WindSpeed}, units)
+	low := toMetric(&Weather{Temperature: d.Low}, units).Temperature
+	snow := strings.HasPrefix(d.Icon, "13") || (d.Icon == "" && m.Temperature <= 0 && d.Precipitation >= 1)
+
+	items := []string{}
+	switch {
+	case m.Temperature < 0:
+		items = append(items, "winter coat", "gloves")
+	case low < 10:
+		items = append(items, "warm layer")
+	}
+	if m.Temperature >= 25 {
+		items = append(items, "sunscreen", "sun hat")
+	}
+	if snow {
+		items = append(items, "boots")
+	} else if d.Precipitation >= 1 || d.Probability >= rainProbability {
+		items = append(items, "umbrella")
+	}
+	if m.WindSpeed >= 10 {
+		items = append(items, "windbreaker")
+	}
+	return items
+}
+
+// runTrip summarizes the days of the trip at each location.
+func runTrip(s *session) {
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := s.opt.tripFrom, s.opt.tripTo
+	if from.IsZero() {
+		from = today
+	}
+	if to.IsZero() {
+		to = from
+	}
+	switch {
+	case from.Before(today):
+		exitWithUsageError("the trip must not start in the past")
+	case to.Before(from):
+		exitWithUsageError("the trip must not end before it starts")
+	case to.Sub(from) >= maxTripDays*24*time.Hour:
+		exitWithUsageError(fmt.Sprintf("a trip can be at most %d days", maxTripDays))
+	}
+
+	for _, city := range s.cities() {
+		if t := s.planTrip(s.query(city), from, to); t != nil {
+			displayTrip(os.Stdout, t, s.opt)
+		}
+	}
+}
+
+// planTrip returns the days from from to to at the location of q, or nil
+// when the forecast cannot be fetched.
+func (s *session) planTrip(q query, from, to time.Time) *trip {
+	f, err := s.provider().forecast(q)
+	if exitOnError(err) {
+		return nil
+	}
+
+	forecast := map[time.Time]*forecastDay{}
+	for _, d := range forecastDays(f) {
+		forecast[d.date] = d
+	}
+
+	t := &trip{City: f.CityName, From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	normals := true
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := tripDay{Date: date.Format("2006-01-02")}
+		if d, ok := forecast[date]; ok {
+			day.Source = "forecast"
+			day.Low, day.High = d.low, d.high
+			day.Precipitation, day.Probability = d.precipitation, d.probability
+			day.WindSpeed = d.wind
+			day.Conditions, day.Icon = d.midday.Conditions, d.midday.Icon
+		} else if normals {
+			c, err := s.provider().climate(q, date.Month(), date.Day())
+			var se *statusError
+			switch {
+			case errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden):
+				// The statistics API needs a subscription, so the days
+				// beyond the forecast are left without data.
+				t.Note = "no climate normals beyond the forecast, they need an OpenWeather subscription with the statistics API"
+				normals = false
+			case exitOnError(err):
+				return nil
+			default:
+				day.Source = "normal"
+				day.Low, day.High = c.Low, c.High
+				day.Precipitation = c.Precipitation
+				day.WindSpeed = c.WindSpeed
+			}
+		}
+		if day.Source != "" {
+			day.Pack = day.pack(s.opt.units)
+		}
+		t.Days = append(t.Days, day)
+	}
+	return t
+}
+
+// displayTrip writes a table of the days of the trip followed by everything
+// to pack for it.
+func displayTrip(w io.Writer, t *trip, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, t, opt.format)
+		return
+	}
+
+	temperatureSymbol, windSpeedSymbol := unitSymbols(opt.units)
+	localFprintf(w, "%s, %s – %s\n\n", t.City, t.From, t.To)
+	tw := newTable(w, 2)
+	fmt.Fprintln(tw, "DATE\tTEMP\tCONDITIONS\tPRECIPITATION\tWIND\tPACK")
+	var all []string
+	seen := map[string]bool{}
+	for _, d := range t.Days {
+		date, _ := time.Parse("2006-01-02", d.Date)
+		day := localDate(date, "Mon Jan 2")
+		switch d.Source {
+		case "":
+			localFprintf(tw, "%s\tno data\n", day)
+			continue
+		case "normal":
+			localFprintf(tw, "%s\t%.0f–%.0f°%s\tclimate normal\t%.1f mm\t%.1f %s\t%s\n",
+				day, d.Low, d.High, temperatureSymbol, d.Precipitation, d.WindSpeed, windSpeedSymbol, strings.Join(d.Pack, ", "))
+		default:
+			localFprintf(tw, "%s\t%.0f–%.0f°%s\t%s %s\t%.1f mm (%.0f%%)\t%.1f %s\t%s\n",
+				day, d.Low, d.High, temperatureSymbol, weatherIconIdToEmoji(d.Icon), d.Conditions,
+				d.Precipitation, d.Probability*100, d.WindSpeed, windSpeedSymbol, strings.Join(d.Pack, ", "))
+		}
+		for _, item := range d.Pack {
+			if !seen[item] {
+				seen[item] = true
+				all = append(all, item)
+			}
+		}
+	}
+	tw.Flush()
+
+	if len(all) > 0 {
+		localFprintf(w, "\nPack: %s\n", strings.Join(all, ", "))
+	}
+	if t.Note != "" {
+		localFprintf(w, "\nNote: %s\n", t.Note)
+	}
+}
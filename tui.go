@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// weather tui is a full-screen dashboard of the current weather, forecast
+// and alerts of the configured locations. It follows the model, update,
+// view loop of Bubble Tea: input, fetched data and clock ticks are
+// messages that update the model, which is then redrawn.
+
+var tuiTabs = []string{"Current", "Forecast", "Alerts"}
+
+type tuiModel struct {
+	s      *session
+	cities []string
+	city   int // index of the selected location
+	tab    int
+	scroll int
+	width  int
+	height int
+	data   map[string]*tuiData // by location
+}
+
+// tuiData is what has been fetched for a location.
+type tuiData struct {
+	weather  *Weather
+	forecast *Forecast
+	alerts   []Alert
+	noAlerts bool // the API key has no access to alerts
+	updated  time.Time
+	loading  bool
+
+	// Errors of the current weather, the forecast and the alerts.
+	err, forecastErr, alertsErr error
+}
+
+// Messages of the update loop.
+type (
+	tuiKey     string
+	tuiFetched struct {
+		city string
+		data *tuiData
+	}
+	tuiTick struct{}
+	tuiQuit struct{}
+)
+
+func runTUI(s *session) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		exitWithError("tui needs a terminal")
+	}
+	if s.opt.interval < minWatchInterval {
+		exitWithUsageError(fmt.Sprintf("interval must be at least %s", minWatchInterval))
+	}
+	s.opt.cacheTTL = min(s.opt.cacheTTL, s.opt.interval/2)
+	// The provider and the history database are set up before the
+	// terminal is taken over: failing from a fetch would leave it raw.
+	s.provider()
+	if s.opt.history {
+		s.historyDB()
+	}
+
+	m := &tuiModel{s: s, data: map[string]*tuiData{}}
+	seen := map[string]bool{}
+	for _, city := range append(s.cities(), s.cfg.list("favorites")...) {
+		if !seen[strings.ToLower(city)] {
+			seen[strings.ToLower(city)] = true
+			m.cities = append(m.cities, city)
+		}
+	}
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		exitWithError("tui: " + err.Error())
+	}
+	// Switch to the alternate screen and hide the cursor, and back on exit.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(int(os.Stdin.Fd()), state)
+	}()
+
+	msgs := make(chan any, 16)
+	go readTUIKeys(msgs)
+	go func() {
+		for range time.Tick(time.Second) {
+			msgs <- tuiTick{}
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		msgs <- tuiQuit{}
+	}()
+
+	m.fetchIfStale(msgs)
+	m.view()
+	for msg := range msgs {
+		if !m.update(msg, msgs) {
+			return
+		}
+		m.view()
+	}
+}
+
+// readTUIKeys sends the keys read from the terminal, with escape sequences
+// translated to names such as "up" or "shift+tab".
+func readTUIKeys(msgs chan<- any) {
+	sequences := map[string]string{
+		"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+		"\x1b[H": "home", "\x1b[F": "end", "\x1b[5~": "pgup", "\x1b[6~": "pgdown",
+		"\x1b[Z": "shift+tab", "\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+	}
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			msgs <- tuiQuit{}
+			return
+		}
+		for in := string(buf[:n]); in != ""; {
+			key, size := "", 1
+			for seq, name := range sequences {
+				if strings.HasPrefix(in, seq) {
+					key, size = name, len(seq)
+				}
+			}
+			switch {
+			case key != "":
+			case in[0] == '\x1b':
+				key = "esc"
+			case in[0] == '\t':
+				key = "tab"
+			case in[0] == 3:
+				key = "ctrl+c"
+			default:
+				key = in[:1]
+			}
+			msgs <- tuiKey(key)
+			in = in[size:]
+		}
+	}
+}
+
+// update applies msg to the model and reports whether to keep running.
+func (m *tuiModel) update(msg any, msgs chan<- any) bool {
+	switch msg := msg.(type) {
+	case tuiQuit:
+		return false
+
+	case tuiTick:
+		m.fetchIfStale(msgs)
+
+	case tuiFetched:
+		m.data[msg.city] = msg.data
+
+	case tuiKey:
+		switch msg {
+		case "q", "esc", "ctrl+c":
+			return false
+		case "tab", "shift+tab", "1", "2", "3":
+			switch msg {
+			case "tab":
+				m.tab = (m.tab + 1) % len(tuiTabs)
+			case "shift+tab":
+				m.tab = (m.tab + len(tuiTabs) - 1) % len(tuiTabs)
+			default:
+				m.tab = int(msg[0] - '1')
+			}
+			m.scroll = 0
+		case "right", "l", "left", "h":
+			if msg == "right" || msg == "l" {
+				m.city = (m.city + 1) % len(m.cities)
+			} else {
+				m.city = (m.city + len(m.cities) - 1) % len(m.cities)
+			}
+			m.scroll = 0
+			m.fetchIfStale(msgs)
+		case "down", "j":
+			m.scroll++
+		case "up", "k":
+			m.scroll--
+		case "pgdown", " ":
+			m.scroll += m.bodyHeight()
+		case "pgup":
+			m.scroll -= m.bodyHeight()
+		case "home", "g":
+			m.scroll = 0
+		case "end", "G":
+			m.scroll = 1 << 30
+		case "r":
+			m.fetch(msgs)
+		}
+	}
+	return true
+}
+
+// fetchIfStale fetches the selected location when it has not been fetched
+// within -interval. Other locations are fetched once they are selected.
+func (m *tuiModel) fetchIfStale(msgs chan<- any) {
+	d := m.data[m.cities[m.city]]
+	if d == nil || (!d.loading && time.Since(d.updated) >= m.s.opt.interval) {
+		m.fetch(msgs)
+	}
+}
+
+// fetch fetches the selected location in the background, keeping the data
+// shown until the new data arrives.
+func (m *tuiModel) fetch(msgs chan<- any) {
+	city := m.cities[m.city]
+	if d := m.data[city]; d != nil {
+		if d.loading {
+			return
+		}
+		d.loading = true
+	} else {
+		m.data[city] = &tuiData{loading: true}
+	}
+
+	s := m.s
+	go func() {
+		d := &tuiData{updated: time.Now()}
+		defer func() { msgs <- tuiFetched{city, d} }()
+
+		if d.weather, d.err = s.provider().current(s.query(city)); d.err != nil {
+			return
+		}
+		s.recordHistory(d.weather, s.opt.units)
+		d.forecast, d.forecastErr = s.provider().forecast(s.query(city))
+		d.alerts, d.alertsErr = s.provider().alerts(s.query(city))
+		var se *statusError
+		if errors.As(d.alertsErr, &se) && se.code == http.StatusUnauthorized {
+			d.noAlerts, d.alertsErr = true, nil
+		}
+	}()
+}
+
+// bodyHeight is the number of lines below the tab and location bars and
+// above the status line.
+func (m *tuiModel) bodyHeight() int {
+	return max(m.height-4, 1)
+}
+
+// body renders the selected tab of the selected location.
+func (m *tuiModel) body() []string {
+	d := m.data[m.cities[m.city]]
+	if d == nil || (d.loading && d.weather == nil && d.err == nil) {
+		return []string{"loading..."}
+	}
+
+	var b bytes.Buffer
+	opt := *m.s.opt
+	opt.format, opt.verbose = "text", 1
+	switch {
+	case d.weather == nil:
+		fmt.Fprintf(&b, "%s\n", redact(d.err.Error()))
+	case m.tab == 1 && d.forecast == nil:
+		fmt.Fprintf(&b, "forecast: %s\n", redact(d.forecastErr.Error()))
+	case m.tab == 0:
+		var recent []float64
+		if opt.history {
+			recent = m.s.recentTemperatures(d.weather)
+		}
+		display(&b, d.weather, nil, recent, &opt)
+		if d.forecast != nil {
+			if days := forecastDays(d.forecast); len(days) > 0 {
+				fmt.Fprintf(&b, "\n%s: %s\n", localDate(days[0].date, "Mon 2 Jan"), days[0].summary())
+			}
+		}
+	case m.tab == 1:
+		if m.width < 100 {
+			opt.verbose = 0
+		}
+		displayForecast(&b, d.forecast, &opt)
+	case d.noAlerts:
+		b.WriteString("Alerts need a One Call API 3.0 subscription, see https://openweathermap.org/api/one-call-3\n")
+	case d.alertsErr != nil:
+		fmt.Fprintf(&b, "alerts: %s\n", redact(d.alertsErr.Error()))
+	case len(d.alerts) == 0:
+		b.WriteString("No weather warnings in effect.\n")
+	default:
+		for _, a := range d.alerts {
+			start, end := localTime(a.Start, d.weather.TimeZone), localTime(a.End, d.weather.TimeZone)
+			fmt.Fprintf(&b, "%s\n%s, %s – %s\n\n", a.Event, a.Sender, start.Format("Mon Jan _2 15:04"), end.Format("Mon Jan _2 15:04"))
+			for _, line := range strings.Split(strings.TrimSpace(a.Description), "\n") {
+				b.WriteString(wrapText(line, m.width) + "\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+}
+
+// view redraws the whole screen.
+func (m *tuiModel) view() {
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		m.width, m.height = w, h
+	}
+
+	var b bytes.Buffer
+	b.WriteString("\x1b[H")
+	line := func(s string) {
+		b.WriteString(s)
+		b.WriteString("\x1b[K\r\n")
+	}
+
+	var tabs []string
+	for i, name := range tuiTabs {
+		if i == m.tab {
+			name = "\x1b[7m " + name + " \x1b[0m"
+		} else {
+			name = " " + name + " "
+		}
+		tabs = append(tabs, fmt.Sprintf("%d%s", i+1, name))
+	}
+	line(strings.Join(tabs, "  "))
+
+	var cities []string
+	for i, city := range m.cities {
+		if i == m.city {
+			city = "\x1b[1m" + city + "\x1b[0m"
+		}
+		cities = append(cities, city)
+	}
+	line("◀ " + strings.Join(cities, " · ") + " ▶")
+	line(strings.Repeat("─", max(m.width, 1)))
+
+	body := m.body()
+	height := m.bodyHeight()
+	m.scroll = max(min(m.scroll, len(body)-height), 0)
+	for i := 0; i < height; i++ {
+		if i+m.scroll < len(body) {
+			line(truncate(body[i+m.scroll], max(m.width, 2)))
+		} else {
+			line("")
+		}
+	}
+
+	status := "←→ location · tab view · ↑↓ scroll · r refresh · q quit"
+	if d := m.data[m.cities[m.city]]; d != nil {
+		switch {
+		case d.loading:
+			status = "refreshing... · " + status
+		case !d.updated.IsZero():
+			status = "updated " + d.updated.Format("15:04:05") + " · " + status
+		}
+	}
+	b.WriteString("\x1b[2m" + truncate(status, max(m.width, 2)) + "\x1b[0m\x1b[K\x1b[J")
+	os.Stdout.Write(b.Bytes())
+}
+
+// wrapText wraps s at spaces into lines of at most width runes.
+func wrapText(s string, width int) string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
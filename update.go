@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const RELEASES_URL = "https://api.github.com/repos/jtlehtinen/weather/releases/latest"
+
+type release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r *release) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// releaseAssetName returns the name of the release binary for this
+// platform, e.g. weather-linux-amd64 or weather-windows-amd64.exe.
+func releaseAssetName() string {
+	name := "weather-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// runUpdate replaces the running binary with the latest GitHub release after
+// checking it against the SHA-256 checksums published with the release. The
+// checksums come from the same release, so they catch a corrupted or
+// truncated download but do not authenticate the binary: whoever can
+// publish a release can publish its checksums too. Signing releases is out
+// of scope for now. Without -force it only ever moves to a newer release.
+func runUpdate(s *session) {
+	body, err := get(RELEASES_URL)
+	if err != nil {
+		exitWithError("failed to check for updates: " + err.Error())
+	}
+
+	var latest release
+	if err := json.Unmarshal(body, &latest); err != nil {
+		exitWithError("failed to check for updates: " + err.Error())
+	}
+
+	if !semver.IsValid(latest.Tag) {
+		exitWithError(fmt.Sprintf("latest release %q is not a version", latest.Tag))
+	}
+
+	current, _, _ := buildInfo()
+	dev := isDevelopmentBuild(current)
+	if !dev && !s.opt.force {
+		switch c := semver.Compare(current, latest.Tag); {
+		case c == 0:
+			fmt.Printf("weather %s is up to date\n", current)
+			return
+		case c > 0:
+			fmt.Printf("weather %s is newer than the latest release %s, use -force to downgrade\n", current, latest.Tag)
+			return
+		}
+	}
+
+	fmt.Printf("current version: %s\nlatest version: %s\n", orUnknown(current), latest.Tag)
+	if s.opt.checkOnly {
+		return
+	}
+	if dev && !s.opt.force {
+		// A development build may well be newer than the release.
+		exitWithError(fmt.Sprintf("weather %s is a development build, use -force to replace it with %s", orUnknown(current), latest.Tag))
+	}
+
+	name := releaseAssetName()
+	binaryURL, checksumsURL := latest.assetURL(name), latest.assetURL("checksums.txt")
+	if binaryURL == "" {
+		exitWithError(fmt.Sprintf("release %s has no binary for %s/%s", latest.Tag, runtime.GOOS, runtime.GOARCH))
+	}
+	if checksumsURL == "" {
+		exitWithError(fmt.Sprintf("release %s has no checksums.txt, refusing to update", latest.Tag))
+	}
+
+	checksums, err := get(checksumsURL)
+	if err != nil {
+		exitWithError("failed to download checksums: " + err.Error())
+	}
+	want, ok := findChecksum(checksums, name)
+	if !ok {
+		exitWithError(fmt.Sprintf("checksums.txt has no entry for %s", name))
+	}
+
+	binary, err := get(binaryURL)
+	if err != nil {
+		exitWithError("failed to download update: " + err.Error())
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		exitWithError(fmt.Sprintf("checksum mismatch for %s: got %s, want %s", name, got, want))
+	}
+
+	if err := replaceExecutable(binary); err != nil {
+		exitWithError("failed to install update: " + err.Error())
+	}
+	fmt.Printf("updated to %s\n", latest.Tag)
+}
+
+// isDevelopmentBuild reports whether version is not that of a release:
+// unknown, "(devel)" or a pseudo-version of an untagged commit.
+func isDevelopmentBuild(version string) bool {
+	return !semver.IsValid(version) || module.IsPseudoVersion(version)
+}
+
+// findChecksum looks up name in a sha256sum formatted file.
+func findChecksum(checksums []byte, name string) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// replaceExecutable writes binary next to the running executable and
+// renames it into place. The running binary is moved aside first as
+// Windows does not allow overwriting it.
+func replaceExecutable(binary []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".weather-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	// Removing fails on Windows while the old binary is still running; it is
+	// cleaned up by the next update instead.
+	os.Remove(old)
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// -uv follows the current weather with the sun protection the UV index
+// calls for and the hours of the day it is needed, following the WHO
+// guidance: https://www.who.int/news-room/questions-and-answers/item/radiation-the-ultraviolet-(uv)-index
+
+// uvProtectionIndex is the UV index from which sun protection is needed.
+const uvProtectionIndex = 3
+
+func uvFlags(fs *flag.FlagSet, opt *options) {
+	fs.BoolVar(&opt.uv, "uv", false, "follow the weather with sun protection advice, needs a One Call API 3.0 subscription")
+}
+
+// uvLevel returns the WHO exposure category of a UV index and the
+// protection it calls for.
+func uvLevel(index float64) (category, protection string) {
+	switch i := int(index + 0.5); {
+	case i < 3:
+		return "low", "no protection needed"
+	case i < 6:
+		return "moderate", "seek shade at midday, wear a hat, sunglasses and SPF 30+ sunscreen"
+	case i < 8:
+		return "high", "seek shade at midday, cover up, wear a hat, sunglasses and SPF 30+ sunscreen"
+	case i < 11:
+		return "very high", "avoid the midday sun, shade, a shirt, sunscreen and a hat are a must"
+	default:
+		return "extreme", "avoid being outside at midday, shade, a shirt, sunscreen and a hat are a must"
+	}
+}
+
+// uvAdvisory is the sun protection advice of a location for the rest of
+// the day. From and To delimit the hours with a UV index of at least
+// uvProtectionIndex; outside them exposure is safe without protection.
+type uvAdvisory struct {
+	Index, Peak          float64
+	Category, Protection string
+	From, To             *time.Time
+	timeZone             int
+}
+
+// adviseUV finds the hours of the rest of the local day from now that need
+// protection and advises on the highest index among them.
+func adviseUV(uv *UVIndex, now time.Time) *uvAdvisory {
+	a := &uvAdvisory{Index: uv.Current, Peak: uv.Current, timeZone: uv.TimeZone}
+	today := localTime(now, uv.TimeZone).YearDay()
+	for _, h := range uv.Hourly {
+		end := h.Time.Add(time.Hour)
+		if !end.After(now) || localTime(h.Time, uv.TimeZone).YearDay() != today {
+			continue
+		}
+		a.Peak = max(a.Peak, h.Index)
+		if h.Index >= uvProtectionIndex {
+			if a.From == nil {
+				from := h.Time
+				a.From = &from
+			}
+			a.To = &end
+		}
+	}
+	a.Category, a.Protection = uvLevel(a.Peak)
+	return a
+}
+
+// displayUV prints the sun protection advice at city with -uv. Without a
+// One Call subscription there is no UV data and nothing is printed.
+func (s *session) displayUV(city string) {
+	if !s.opt.uv || s.opt.format != "text" {
+		return
+	}
+	uv, err := s.provider().uv(s.query(city))
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: uv: %s\n", redact(err.Error()))
+		return
+	}
+	displayUVAdvisory(os.Stdout, adviseUV(uv, time.Now()))
+}
+
+// displayUVAdvisory writes e.g. "uv: 4 now, up to 6 (high), protection
+// needed 10:00–16:00: seek shade at midday, ...".
+func displayUVAdvisory(w io.Writer, a *uvAdvisory) {
+	localFprintf(w, "uv: %.0f now", a.Index)
+	if a.From == nil {
+		localFprintf(w, ", below %d for the rest of the day, %s\n", uvProtectionIndex, a.Protection)
+		return
+	}
+	from, to := localTime(*a.From, a.timeZone).Format("15:04"), localTime(*a.To, a.timeZone).Format("15:04")
+	localFprintf(w, ", up to %.0f (%s), protection needed %s–%s: %s\n", a.Peak, a.Category, from, to, a.Protection)
+}
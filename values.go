@@ -0,0 +1,115 @@
+package main
+
+import "fmt"
+
+// The values of the JSON and CSV output are in the units of -units. The
+// metric and display values carry the same measurements in metric units and
+// as the text output displays them, so that consumers need not know which
+// units were in effect.
+
+// metricValues are the measurements of a weather in metric units.
+type metricValues struct {
+	Temperature float64 `json:"temperature"`
+	Pressure    float64 `json:"pressure"`
+	Humidity    float64 `json:"humidity"`
+	WindSpeed   float64 `json:"wind_speed"`
+	WindGust    float64 `json:"wind_gust,omitempty"`
+	WindDegrees float64 `json:"wind_degrees"`
+}
+
+// displayValues are the measurements of a weather as displayed.
+type displayValues struct {
+	Temperature string `json:"temperature"`
+	Pressure    string `json:"pressure"`
+	Humidity    string `json:"humidity"`
+	WindSpeed   string `json:"wind_speed"`
+	WindGust    string `json:"wind_gust,omitempty"`
+	WindDegrees string `json:"wind_degrees"`
+}
+
+// unitValues are added to the JSON of a weather, or omitted without one.
+type unitValues struct {
+	Metric  *metricValues  `json:"metric,omitempty"`
+	Display *displayValues `json:"display,omitempty"`
+}
+
+// newUnitValues returns the metric and displayed values of w, which is in
+// units.
+func newUnitValues(w *Weather, units string) unitValues {
+	if w == nil {
+		return unitValues{}
+	}
+	m := toMetric(w, units)
+	temperatureSymbol, windSpeedSymbol := unitSymbols(units)
+	v := unitValues{
+		Metric: &metricValues{
+			Temperature: m.Temperature,
+			Pressure:    m.Pressure,
+			Humidity:    m.Humidity,
+			WindSpeed:   m.WindSpeed,
+			WindGust:    m.WindGust,
+			WindDegrees: m.WindDegrees,
+		},
+		Display: &displayValues{
+			Temperature: fmt.Sprintf("%.0f°%s", w.Temperature, temperatureSymbol),
+			Pressure:    fmt.Sprintf("%.0f hPa", w.Pressure),
+			Humidity:    fmt.Sprintf("%.1f%%", w.Humidity),
+			WindSpeed:   fmt.Sprintf("%.1f %s", w.WindSpeed, windSpeedSymbol),
+			WindDegrees: fmt.Sprintf("%.0f°", w.WindDegrees),
+		},
+	}
+	if w.WindGust > 0 {
+		v.Display.WindGust = fmt.Sprintf("%.1f %s", w.WindGust, windSpeedSymbol)
+	}
+	return v
+}
+
+// forecastEntryValues is a forecast entry with its metric and displayed
+// values.
+type forecastEntryValues struct {
+	ForecastEntry
+	unitValues
+}
+
+// forecastValues returns the entries of f, in units, with their metric and
+// displayed values.
+func forecastValues(f *Forecast, units string) []forecastEntryValues {
+	entries := make([]forecastEntryValues, len(f.Entries))
+	for i, e := range f.Entries {
+		w := &Weather{
+			Temperature: e.Temperature,
+			Pressure:    e.Pressure,
+			Humidity:    e.Humidity,
+			WindSpeed:   e.WindSpeed,
+			WindGust:    e.WindGust,
+			WindDegrees: e.WindDegrees,
+		}
+		entries[i] = forecastEntryValues{e, newUnitValues(w, units)}
+	}
+	return entries
+}
+
+// unitColumns are the columns of the metric and displayed values appended
+// to the CSV of weathers, the same as in the JSON output.
+var unitColumns = []string{
+	"metric_temperature", "metric_pressure", "metric_humidity", "metric_wind_speed", "metric_wind_gust", "metric_wind_degrees",
+	"display_temperature", "display_pressure", "display_humidity", "display_wind_speed", "display_wind_gust", "display_wind_degrees",
+}
+
+// csv returns the unitColumns of the values, empty without a weather. The
+// gusts are empty when calm, as they are left out of the JSON.
+func (v unitValues) csv() []string {
+	if v.Metric == nil {
+		return make([]string, len(unitColumns))
+	}
+	m, d := v.Metric, v.Display
+	gust := ""
+	if m.WindGust > 0 {
+		gust = formatValue("wind_gust", m.WindGust)
+	}
+	return []string{
+		formatValue("temperature", m.Temperature), formatValue("pressure", m.Pressure), formatValue("humidity", m.Humidity),
+		formatValue("wind_speed", m.WindSpeed), gust, formatValue("wind_degrees", m.WindDegrees),
+		d.Temperature, d.Pressure, d.Humidity, d.WindSpeed, d.WindGust, d.WindDegrees,
+	}
+}
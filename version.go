@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=abc123 -X main.date=2024-01-02"
+//
+// Values not set are taken from the build info embedded by the Go toolchain.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// buildInfo returns the version, commit and build date of the binary.
+func buildInfo() (v, c, d string) {
+	v, c, d = version, commit, date
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if c == "" {
+					c = s.Value
+				}
+			case "vcs.time":
+				if d == "" {
+					d = s.Value
+				}
+			case "vcs.modified":
+				if s.Value == "true" && c != "" && commit == "" {
+					c += " (modified)"
+				}
+			}
+		}
+	}
+
+	return v, c, d
+}
+
+func printVersion(w io.Writer) {
+	v, c, d := buildInfo()
+	fmt.Fprintf(w, "weather %s\n", orUnknown(v))
+	fmt.Fprintf(w, "commit: %s\n", orUnknown(c))
+	fmt.Fprintf(w, "built: %s\n", orUnknown(d))
+	fmt.Fprintf(w, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const minWatchInterval = 10 * time.Second
+
+func watchFlags(fs *flag.FlagSet, opt *options) {
+	opt.interval = 10 * time.Minute
+	fs.BoolVar(&opt.watch, "watch", false, "keep running and refresh the output every -interval")
+	fs.Func("interval", "refresh interval of -watch, implies -watch (default 10m)", func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		opt.interval, opt.watch = d, true
+		return nil
+	})
+}
+
+// watchExit is panicked by exit during a refresh of -watch, so that a
+// failed refresh does not end the watch. failed is set for errors, as
+// opposed to exit statuses telling the result, e.g. of weather rain.
+type watchExit struct {
+	status int
+	failed bool
+}
+
+// workerExits carries the exits of the goroutines a refresh starts, e.g.
+// the workers of fetchAll, to the goroutine running the refresh, as only
+// refresh recovers the watchExit panic. Each goroutine defers catch, and
+// rethrow is called once they are done.
+type workerExits struct {
+	mu   sync.Mutex
+	exit *watchExit
+}
+
+func (w *workerExits) catch() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e, ok := r.(watchExit)
+	if !ok {
+		panic(r)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// An error outweighs an exit status telling the result.
+	if w.exit == nil || e.failed && !w.exit.failed {
+		w.exit = &e
+	}
+}
+
+func (w *workerExits) rethrow() {
+	if w.exit != nil {
+		panic(*w.exit)
+	}
+}
+
+// watching is set while a refresh of -watch runs.
+var watching bool
+
+// watchStdout is the terminal while the output of a refresh is collected
+// to replace the previous one, or nil.
+var watchStdout *os.File
+
+// refresh runs run once for watch and reports whether it succeeded. The
+// error of a failed refresh is printed and the previous output is left in
+// place, to be refreshed on the next round; only usage and API key errors,
+// which the next round would repeat, end the watch. In place, the output
+// is collected and replaces the previous one once complete.
+func refresh(s *session, run func(*session), inPlace bool) (ok bool) {
+	if inPlace {
+		tmp, err := os.CreateTemp("", "weather-watch-*")
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		watchStdout, os.Stdout = os.Stdout, tmp
+	}
+
+	defer func() {
+		watching = false
+		var collected *os.File
+		if watchStdout != nil {
+			os.Stdout, collected, watchStdout = watchStdout, os.Stdout, nil
+		}
+		r := recover()
+		e, isExit := r.(watchExit)
+		if r != nil && !isExit {
+			panic(r)
+		}
+		if e.failed && (e.status == exitUsage || e.status == exitAuth) {
+			exit(e.status)
+		}
+		ok = !e.failed
+		if ok && collected != nil {
+			fmt.Print("\x1b[H\x1b[2J")
+			collected.Seek(0, io.SeekStart)
+			io.Copy(os.Stdout, collected)
+		}
+	}()
+	watching = true
+	run(s)
+	return true
+}
+
+// watch calls run every -interval until interrupted. On a terminal the
+// output is redrawn in place; otherwise, and with -o jsonl, each refresh is
+// appended to the previous ones. With -output each refresh replaces the file.
+// A failed refresh is reported and retried on the next round.
+func watch(s *session, run func(*session)) {
+	if s.opt.interval < minWatchInterval {
+		exitWithUsageError(fmt.Sprintf("interval must be at least %s", minWatchInterval))
+	}
+	// Refresh the data on every round instead of showing cached responses.
+	// The ticker runs from before the first fetch, so a TTL of a full
+	// interval would still hit the cache.
+	s.opt.cacheTTL = min(s.opt.cacheTTL, s.opt.interval/2)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second Ctrl-C kills a refresh that is still running.
+		<-ctx.Done()
+		stop()
+	}()
+
+	inPlace := s.opt.format != "jsonl" && s.opt.output == "" && term.IsTerminal(int(os.Stdout.Fd()))
+	ticker := time.NewTicker(s.opt.interval)
+	defer ticker.Stop()
+
+	for {
+		if refresh(s, run, inPlace) && inPlace {
+			fmt.Printf("\nupdated %s, refreshing every %s (Ctrl-C to quit)\n", time.Now().Format("15:04:05"), s.opt.interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package weatherpb holds the gRPC service definition of the weather server
+// and the code generated from it.
+package weatherpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative weather.proto
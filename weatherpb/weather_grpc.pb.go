@@ -0,0 +1,223 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: weather.proto
+
+// The weather service exposes the same data as the HTTP server. Values are
+// in the requested units; temperatures are in °C or °F and wind speeds in
+// m/s or mi/h.
+
+package weatherpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	WeatherService_Current_FullMethodName      = "/weather.v1.WeatherService/Current"
+	WeatherService_Forecast_FullMethodName     = "/weather.v1.WeatherService/Forecast"
+	WeatherService_WatchUpdates_FullMethodName = "/weather.v1.WeatherService/WatchUpdates"
+)
+
+// WeatherServiceClient is the client API for WeatherService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WeatherServiceClient interface {
+	// Current returns the current weather of a location.
+	Current(ctx context.Context, in *CurrentRequest, opts ...grpc.CallOption) (*Weather, error)
+	// Forecast returns the forecast of a location in three hour steps.
+	Forecast(ctx context.Context, in *ForecastRequest, opts ...grpc.CallOption) (*Forecast, error)
+	// WatchUpdates streams the current weather of the locations: each one
+	// once when the call starts and then whenever its data changes.
+	WatchUpdates(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (WeatherService_WatchUpdatesClient, error)
+}
+
+type weatherServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWeatherServiceClient(cc grpc.ClientConnInterface) WeatherServiceClient {
+	return &weatherServiceClient{cc}
+}
+
+func (c *weatherServiceClient) Current(ctx context.Context, in *CurrentRequest, opts ...grpc.CallOption) (*Weather, error) {
+	out := new(Weather)
+	err := c.cc.Invoke(ctx, WeatherService_Current_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *weatherServiceClient) Forecast(ctx context.Context, in *ForecastRequest, opts ...grpc.CallOption) (*Forecast, error) {
+	out := new(Forecast)
+	err := c.cc.Invoke(ctx, WeatherService_Forecast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *weatherServiceClient) WatchUpdates(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (WeatherService_WatchUpdatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WeatherService_ServiceDesc.Streams[0], WeatherService_WatchUpdates_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &weatherServiceWatchUpdatesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type WeatherService_WatchUpdatesClient interface {
+	Recv() (*Weather, error)
+	grpc.ClientStream
+}
+
+type weatherServiceWatchUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *weatherServiceWatchUpdatesClient) Recv() (*Weather, error) {
+	m := new(Weather)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WeatherServiceServer is the server API for WeatherService service.
+// All implementations must embed UnimplementedWeatherServiceServer
+// for forward compatibility
+type WeatherServiceServer interface {
+	// Current returns the current weather of a location.
+	Current(context.Context, *CurrentRequest) (*Weather, error)
+	// Forecast returns the forecast of a location in three hour steps.
+	Forecast(context.Context, *ForecastRequest) (*Forecast, error)
+	// WatchUpdates streams the current weather of the locations: each one
+	// once when the call starts and then whenever its data changes.
+	WatchUpdates(*WatchRequest, WeatherService_WatchUpdatesServer) error
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+// UnimplementedWeatherServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWeatherServiceServer struct {
+}
+
+func (UnimplementedWeatherServiceServer) Current(context.Context, *CurrentRequest) (*Weather, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Current not implemented")
+}
+func (UnimplementedWeatherServiceServer) Forecast(context.Context, *ForecastRequest) (*Forecast, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Forecast not implemented")
+}
+func (UnimplementedWeatherServiceServer) WatchUpdates(*WatchRequest, WeatherService_WatchUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUpdates not implemented")
+}
+func (UnimplementedWeatherServiceServer) mustEmbedUnimplementedWeatherServiceServer() {}
+
+// UnsafeWeatherServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WeatherServiceServer will
+// result in compilation errors.
+type UnsafeWeatherServiceServer interface {
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+func RegisterWeatherServiceServer(s grpc.ServiceRegistrar, srv WeatherServiceServer) {
+	s.RegisterService(&WeatherService_ServiceDesc, srv)
+}
+
+func _WeatherService_Current_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CurrentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).Current(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_Current_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).Current(ctx, req.(*CurrentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WeatherService_Forecast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForecastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).Forecast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_Forecast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).Forecast(ctx, req.(*ForecastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WeatherService_WatchUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WeatherServiceServer).WatchUpdates(m, &weatherServiceWatchUpdatesServer{stream})
+}
+
+type WeatherService_WatchUpdatesServer interface {
+	Send(*Weather) error
+	grpc.ServerStream
+}
+
+type weatherServiceWatchUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *weatherServiceWatchUpdatesServer) Send(m *Weather) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// WeatherService_ServiceDesc is the grpc.ServiceDesc for WeatherService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WeatherService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "weather.v1.WeatherService",
+	HandlerType: (*WeatherServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Current",
+			Handler:    _WeatherService_Current_Handler,
+		},
+		{
+			MethodName: "Forecast",
+			Handler:    _WeatherService_Forecast_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchUpdates",
+			Handler:       _WeatherService_WatchUpdates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "weather.proto",
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+func webhookFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.webhooks, "webhook", "", "comma separated URLs to POST condition changes and alerts to")
+	fs.StringVar(&opt.webhookSecret, "webhook-secret", "", "secret for signing webhook payloads with HMAC-SHA256")
+}
+
+// webhookEvent is the JSON payload posted to webhooks.
+type webhookEvent struct {
+	Type     string    `json:"type"`
+	Location string    `json:"location"`
+	Time     time.Time `json:"time"`
+	Units    string    `json:"units"`
+	Weather  *Weather  `json:"weather"`
+	Previous *Weather  `json:"previous,omitempty"`
+	Alert    *Alert    `json:"alert,omitempty"`
+	Rule     string    `json:"rule,omitempty"`
+}
+
+// webhookDeliveries tracks the deliveries in progress so that one-shot
+// commands can wait for them before exiting.
+var webhookDeliveries sync.WaitGroup
+
+// webhookRetries are the delays between delivery attempts.
+var webhookRetries = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+// conditionCategory groups OpenWeather icons into the condition categories
+// whose changes are worth a notification, e.g. clouds turning into rain.
+// https://openweathermap.org/weather-conditions
+func conditionCategory(icon string) string {
+	if len(icon) < 2 {
+		return ""
+	}
+	switch icon[:2] {
+	case "01":
+		return "clear"
+	case "02", "03", "04":
+		return "clouds"
+	case "09", "10":
+		return "rain"
+	case "11":
+		return "thunderstorm"
+	case "13":
+		return "snow"
+	case "50":
+		return "mist"
+	}
+	return ""
+}
+
+// sendWebhooks posts event to every URL in the background. With a secret
+// the body is signed and the signature sent as
+// "X-Weather-Signature: sha256=<hex>", the same scheme GitHub uses, so
+// receivers can verify where the payload came from.
+func sendWebhooks(urls []string, secret string, event *webhookEvent) {
+	if len(urls) == 0 {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: webhook: %s\n", err)
+		return
+	}
+
+	header := http.Header{}
+	header.Set("X-Weather-Event", event.Type)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		header.Set("X-Weather-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	for _, u := range urls {
+		deliver("webhook", u, header, body)
+	}
+}
+
+// deliver posts body to u in the background. The body is JSON unless header
+// sets another Content-Type. Deliveries that fail with a network error or a
+// 429 or 5xx status are retried.
+func deliver(what, u string, header http.Header, body []byte) {
+	webhookDeliveries.Add(1)
+	go func() {
+		defer webhookDeliveries.Done()
+		err := postPayload(u, header, body)
+		for _, delay := range webhookRetries {
+			if err == nil || !retryableWebhookError(err) {
+				break
+			}
+			time.Sleep(delay)
+			err = postPayload(u, header, body)
+		}
+		if err != nil {
+			// Webhook URLs often embed a token, so only the host is shown.
+			var ue *url.Error
+			if errors.As(err, &ue) {
+				err = ue.Err
+			}
+			host := u
+			if parsed, perr := url.Parse(u); perr == nil {
+				host = parsed.Host
+			}
+			fmt.Fprintf(os.Stderr, "WARNING: %s %s: %s\n", what, host, redact(err.Error()))
+		}
+	}()
+}
+
+func postPayload(u string, header http.Header, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	v, _, _ := buildInfo()
+	req.Header.Set("User-Agent", "weather/"+orUnknown(v))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+func retryableWebhookError(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// The WebSocket API at /v1/ws pushes the weather of any number of
+// locations over one connection, e.g. for a kiosk display cycling through
+// cities. Clients send
+//
+//	{"type": "subscribe", "city": "helsinki"}
+//	{"type": "unsubscribe", "city": "helsinki"}
+//
+// and receive a "weather" message for a location when it is subscribed and
+// whenever its data changes, or an "error" message.
+
+// maxSubscriptions limits the locations of one connection.
+const maxSubscriptions = 50
+
+type wsMessage struct {
+	Type    string `json:"type"`
+	City    string `json:"city,omitempty"`
+	Weather any    `json:"weather,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// wsConn is a client connection with its subscriptions, keyed by city as
+// the client gave it.
+type wsConn struct {
+	s   *session
+	ws  *websocket.Conn
+	opt options
+
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+}
+
+type wsSubscription struct {
+	q    query
+	last *Weather
+}
+
+func (s *session) websocketHandler() http.Handler {
+	// Kiosks and dashboards are served from anywhere, so the origin is not
+	// checked; the API is read-only.
+	return websocket.Server{Handler: s.serveWebSocket}
+}
+
+func (s *session) serveWebSocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	c := &wsConn{s: s, ws: ws, opt: *s.opt, subs: map[string]*wsSubscription{}}
+	// The units and lang query parameters apply to the whole connection.
+	if err := apiOptions(ws.Request(), &c.opt); err != nil {
+		c.send(&wsMessage{Type: "error", Error: err.Error()})
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.receive()
+	}()
+
+	ticker := time.NewTicker(s.opt.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			cities := make([]string, 0, len(c.subs))
+			for city := range c.subs {
+				cities = append(cities, city)
+			}
+			c.mu.Unlock()
+			for _, city := range cities {
+				c.update(city)
+			}
+		}
+	}
+}
+
+// receive handles messages until the connection is closed.
+func (c *wsConn) receive() {
+	for {
+		var m wsMessage
+		if err := websocket.JSON.Receive(c.ws, &m); err != nil {
+			return
+		}
+		city := strings.TrimSpace(m.City)
+
+		switch m.Type {
+		case "subscribe":
+			q, err := c.s.apiQuery(c.ws.Request(), &c.opt, city)
+			if err != nil {
+				c.send(&wsMessage{Type: "error", City: city, Error: err.Error()})
+				continue
+			}
+			c.mu.Lock()
+			_, exists := c.subs[city]
+			full := !exists && len(c.subs) >= maxSubscriptions
+			if !exists && !full {
+				c.subs[city] = &wsSubscription{q: q}
+			}
+			c.mu.Unlock()
+			if full {
+				c.send(&wsMessage{Type: "error", City: city, Error: "too many subscriptions"})
+				continue
+			}
+			c.update(city)
+
+		case "unsubscribe":
+			c.mu.Lock()
+			delete(c.subs, city)
+			c.mu.Unlock()
+
+		default:
+			c.send(&wsMessage{Type: "error", City: city, Error: "unknown message type " + m.Type})
+		}
+	}
+}
+
+// update fetches the weather of a subscribed city and sends it when it has
+// changed since the last message.
+func (c *wsConn) update(city string) {
+	c.mu.Lock()
+	sub := c.subs[city]
+	c.mu.Unlock()
+	if sub == nil {
+		return
+	}
+
+	w, err := c.s.provider().current(sub.q)
+	if err != nil {
+		c.send(&wsMessage{Type: "error", City: city, Error: redact(err.Error())})
+		return
+	}
+
+	c.mu.Lock()
+	changed := sub.last == nil || *stripCachedAt(sub.last) != *stripCachedAt(w)
+	sub.last = w
+	c.mu.Unlock()
+	if changed {
+		c.s.recordHistory(w, sub.q.units)
+		c.send(&wsMessage{Type: "weather", City: city, Weather: struct {
+			*Weather
+			Units string `json:"units"`
+		}{w, sub.q.units}})
+	}
+}
+
+// stripCachedAt returns a copy of w without CachedAt, for comparing the
+// data itself.
+func stripCachedAt(w *Weather) *Weather {
+	c := *w
+	c.CachedAt = nil
+	return &c
+}
+
+func (c *wsConn) send(m *wsMessage) {
+	c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	websocket.JSON.Send(c.ws, m)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// weather check -when tests a condition such as "rain || wind > 10" and
+// exits 0 when it holds and 1 when it does not, for shell scripts:
+//
+//	weather check -when rain helsinki && notify-send "take an umbrella"
+//
+// Conditions are alert rules and the weather words of whenWords, combined
+// with &&, || and !, and grouped with parentheses.
+
+// whenWords maps the words usable in conditions to the OpenWeather icons of
+// the weather they stand for, without the day or night suffix.
+// https://openweathermap.org/weather-conditions
+var whenWords = map[string][]string{
+	"clear":        {"01"},
+	"clouds":       {"02", "03", "04"},
+	"cloudy":       {"02", "03", "04"},
+	"rain":         {"09", "10", "11"},
+	"thunderstorm": {"11"},
+	"storm":        {"11"},
+	"snow":         {"13"},
+	"fog":          {"50"},
+	"mist":         {"50"},
+}
+
+// condition is a parsed -when expression. m is the weather in metric units
+// and a the air quality, which is nil unless the condition tests aqi.
+type condition interface {
+	holds(m *Weather, a *AirQuality) bool
+}
+
+type (
+	andCondition  struct{ left, right condition }
+	orCondition   struct{ left, right condition }
+	notCondition  struct{ c condition }
+	ruleCondition struct{ r *rule }
+	wordCondition []string // icons
+)
+
+func (c andCondition) holds(m *Weather, a *AirQuality) bool {
+	return c.left.holds(m, a) && c.right.holds(m, a)
+}
+
+func (c orCondition) holds(m *Weather, a *AirQuality) bool {
+	return c.left.holds(m, a) || c.right.holds(m, a)
+}
+
+func (c notCondition) holds(m *Weather, a *AirQuality) bool { return !c.c.holds(m, a) }
+
+func (c ruleCondition) holds(m *Weather, a *AirQuality) bool { return c.r.matches(c.r.observed(m, a)) }
+
+func (icons wordCondition) holds(m *Weather, a *AirQuality) bool {
+	for _, icon := range icons {
+		if strings.HasPrefix(m.Icon, icon) {
+			return true
+		}
+	}
+	return false
+}
+
+// whenParser parses a -when expression by recursive descent:
+//
+//	or   = and { "||" and }
+//	and  = not { "&&" not }
+//	not  = "!" not | "(" or ")" | rule | word
+type whenParser struct {
+	text   string
+	tokens []string
+	units  string
+	rules  []*rule // the rules of the expression
+}
+
+func parseWhen(text, units string) (condition, []*rule, error) {
+	p := &whenParser{text: text, tokens: tokenizeWhen(text), units: units}
+	c, err := p.or()
+	if err == nil && len(p.tokens) > 0 {
+		err = fmt.Errorf("invalid condition %q, unexpected %q", text, p.tokens[0])
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, p.rules, nil
+}
+
+// tokenizeWhen splits text into the operators and parentheses and the
+// rules and words between them.
+func tokenizeWhen(text string) []string {
+	var tokens []string
+	var atom strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(atom.String()); s != "" {
+			tokens = append(tokens, s)
+		}
+		atom.Reset()
+	}
+	for i := 0; i < len(text); {
+		switch rest := text[i:]; {
+		case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+			flush()
+			tokens = append(tokens, rest[:2])
+			i += 2
+		case rest[0] == '(', rest[0] == ')', rest[0] == '!' && !strings.HasPrefix(rest, "!="):
+			flush()
+			tokens = append(tokens, rest[:1])
+			i++
+		default:
+			atom.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (p *whenParser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+func (p *whenParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *whenParser) or() (condition, error) {
+	c, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var right condition
+		right, err = p.and()
+		c = orCondition{c, right}
+	}
+	return c, err
+}
+
+func (p *whenParser) and() (condition, error) {
+	c, err := p.not()
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var right condition
+		right, err = p.not()
+		c = andCondition{c, right}
+	}
+	return c, err
+}
+
+func (p *whenParser) not() (condition, error) {
+	switch t := p.next(); t {
+	case "!":
+		c, err := p.not()
+		return notCondition{c}, err
+	case "(":
+		c, err := p.or()
+		if err == nil && p.next() != ")" {
+			err = fmt.Errorf("invalid condition %q, missing )", p.text)
+		}
+		return c, err
+	case "", ")", "&&", "||":
+		return nil, fmt.Errorf("invalid condition %q, expected e.g. \"rain || wind > 10\"", p.text)
+	default:
+		if icons, ok := whenWords[strings.ToLower(t)]; ok {
+			return wordCondition(icons), nil
+		}
+		if !strings.ContainsAny(t, "<>=") {
+			return nil, fmt.Errorf("invalid condition %q, unknown weather %q", p.text, t)
+		}
+		r, err := parseRule(t, p.units)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, r)
+		return ruleCondition{r}, nil
+	}
+}
+
+// whenResult is the outcome of -when for a location.
+type whenResult struct {
+	City  string `json:"city"`
+	When  string `json:"when"`
+	Holds bool   `json:"holds"`
+}
+
+// runWhen evaluates -when for the locations and exits 0 when it holds for
+// any of them and 1 otherwise. With -v the outcome is printed as well.
+func runWhen(s *session) {
+	c, rules, err := parseWhen(s.opt.when, s.opt.units)
+	if err != nil {
+		exitWithUsageError(err.Error())
+	}
+
+	holds := false
+	for _, city := range s.cities() {
+		w, err := s.provider().current(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		var a *AirQuality
+		if needsAir(rules) {
+			if a, err = s.provider().air(s.query(city)); exitOnError(err) {
+				continue
+			}
+		}
+		s.recordHistory(w, s.opt.units)
+
+		r := whenResult{City: w.CityName, When: s.opt.when, Holds: c.holds(toMetric(w, s.opt.units), a)}
+		holds = holds || r.Holds
+		switch {
+		case isJSON(s.opt.format):
+			writeJSON(os.Stdout, r, s.opt.format)
+		case s.opt.verbose > 0:
+			fmt.Printf("%s: %s: %t\n", r.City, r.When, r.Holds)
+		}
+	}
+	if !holds && !s.opt.dryRun && !s.opt.raw {
+		exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenizeWhen(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"rain || wind > 10", []string{"rain", "||", "wind > 10"}},
+		{"!rain&&temp != 0", []string{"!", "rain", "&&", "temp != 0"}},
+		{"!(snow||fog)", []string{"!", "(", "snow", "||", "fog", ")"}},
+		{"! !clear", []string{"!", "!", "clear"}},
+		{"temp!=-5||temp < -10", []string{"temp!=-5", "||", "temp < -10"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenizeWhen(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenizeWhen(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	// Light rain at 2 °C with a wind of 12 m/s.
+	m := &Weather{Temperature: 2, WindSpeed: 12, Humidity: 80, Icon: "10d"}
+	tests := []struct {
+		text  string
+		units string
+		holds bool
+		rules int
+	}{
+		{"rain", "metric", true, 0},
+		{"RAIN", "metric", true, 0},
+		{"snow", "metric", false, 0},
+		{"!snow", "metric", true, 0},
+		{"!!rain", "metric", true, 0},
+		{"rain && wind > 10", "metric", true, 1},
+		{"rain && wind > 15", "metric", false, 1},
+		{"snow || temp < 5", "metric", true, 1},
+		{"temp != 2", "metric", false, 1},
+		{"!temp != 2", "metric", true, 1},
+		{"temp > -1 && temp < 3", "metric", true, 2},
+		{"temp < 32", "imperial", false, 1},
+		{"temp < 40", "imperial", true, 1},
+		// && binds tighter than ||.
+		{"rain || snow && temp > 10", "metric", true, 1},
+		{"(rain || snow) && temp > 10", "metric", false, 1},
+		{"!(snow || fog) && (clouds || rain)", "metric", true, 0},
+	}
+	for _, tt := range tests {
+		c, rules, err := parseWhen(tt.text, tt.units)
+		if err != nil {
+			t.Errorf("parseWhen(%q): %s", tt.text, err)
+			continue
+		}
+		if got := c.holds(m, nil); got != tt.holds {
+			t.Errorf("%q holds %t, want %t", tt.text, got, tt.holds)
+		}
+		if len(rules) != tt.rules {
+			t.Errorf("%q has %d rules, want %d", tt.text, len(rules), tt.rules)
+		}
+	}
+}
+
+func TestParseWhenErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"", `invalid condition "", expected e.g. "rain || wind > 10"`},
+		{"rain &&", `invalid condition "rain &&", expected e.g. "rain || wind > 10"`},
+		{"|| rain", `invalid condition "|| rain", expected e.g. "rain || wind > 10"`},
+		{"!", `invalid condition "!", expected e.g. "rain || wind > 10"`},
+		{"(rain", `invalid condition "(rain", missing )`},
+		{"rain)", `invalid condition "rain)", unexpected ")"`},
+		{"rain (snow)", `invalid condition "rain (snow)", unexpected "("`},
+		{"sunny", `invalid condition "sunny", unknown weather "sunny"`},
+		{"rain snow", `invalid condition "rain snow", unknown weather "rain snow"`},
+		{"rain || hail > 1", `invalid rule "hail > 1", unknown quantity "hail"`},
+		{"temp < x", `invalid rule "temp < x", expected e.g. "temp < 0"`},
+	}
+	for _, tt := range tests {
+		_, _, err := parseWhen(tt.text, "metric")
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("parseWhen(%q): got error %v, want %s", tt.text, err, tt.err)
+		}
+	}
+}
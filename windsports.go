@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// weather wind-sports tells whether the current wind suits a wind sport:
+// it is a go when the sustained wind is within the sport's range, the
+// gusts are steady enough and the wind blows from an allowed direction.
+
+var sportValues = []string{"kitesurf", "windsurf", "sailing"}
+
+func windSportsFlags(fs *flag.FlagSet, opt *options) {
+	fs.Func("sport", "the sport ("+strings.Join(sportValues, "|")+") (default kitesurf)", enumFlag(&opt.sport, "sport", sportValues))
+	fs.StringVar(&opt.sportWind, "wind", "", "sustained wind range in -units, e.g. \"6-16\" (default the sport's)")
+	fs.Float64Var(&opt.sportGustFactor, "gust-factor", 0, "highest ratio of gusts to sustained wind (default the sport's)")
+	fs.StringVar(&opt.sportDirections, "directions", "", "directions the wind may blow from, e.g. \"180-270\" or \"SW-N\" (default any)")
+}
+
+// windSport describes the wind a sport needs, in m/s. Gusts more than
+// gustFactor times the sustained wind make it unsafe.
+type windSport struct {
+	minWind, maxWind float64
+	gustFactor       float64
+}
+
+var windSports = map[string]windSport{
+	"kitesurf": {minWind: 6, maxWind: 16, gustFactor: 1.5},
+	"windsurf": {minWind: 7, maxWind: 18, gustFactor: 1.5},
+	"sailing":  {minWind: 3, maxWind: 12, gustFactor: 1.6},
+}
+
+// compassPoints are the 16 points of the compass, clockwise from north.
+var compassPoints = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// compassPoint returns the compass point nearest to a direction in degrees.
+func compassPoint(degrees float64) string {
+	i := int(math.Mod(degrees/22.5+0.5, 16))
+	return compassPoints[(i+16)%16]
+}
+
+// parseDirection parses a direction in degrees or as a compass point.
+func parseDirection(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	for i, p := range compassPoints {
+		if strings.EqualFold(value, p) {
+			return float64(i) * 22.5, true
+		}
+	}
+	d, err := strconv.ParseFloat(value, 64)
+	return d, err == nil && d >= 0 && d <= 360
+}
+
+// parseRange parses a range such as "6-16" with parse for each end.
+func parseRange(value string, parse func(string) (float64, bool)) (from, to float64, ok bool) {
+	a, b, found := strings.Cut(value, "-")
+	if !found {
+		return 0, 0, false
+	}
+	from, ok1 := parse(a)
+	to, ok2 := parse(b)
+	return from, to, ok1 && ok2
+}
+
+// windDirectionWithin reports whether the direction d lies clockwise from
+// from to to, so that "300-60" spans north.
+func windDirectionWithin(d, from, to float64) bool {
+	if from <= to {
+		return d >= from && d <= to
+	}
+	return d >= from || d <= to
+}
+
+// windCheck is one condition of a go: the wind, the gusts, the direction
+// or the weather.
+type windCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// windSportsReport is the go or no-go of a sport at a location. The speeds
+// are in the units of the query.
+type windSportsReport struct {
+	City        string      `json:"city"`
+	Sport       string      `json:"sport"`
+	Go          bool        `json:"go"`
+	WindSpeed   float64     `json:"wind_speed"`
+	WindGust    float64     `json:"wind_gust,omitempty"`
+	WindDegrees float64     `json:"wind_degrees"`
+	Checks      []windCheck `json:"checks"`
+}
+
+// windLimits is a sport with the ranges of the flags applied, its speeds in
+// the units of the query, and the allowed directions.
+type windLimits struct {
+	windSport
+	directions         bool
+	fromDeg, toDeg     float64
+	windSpeedSymbol    string
+	directionsArgument string
+}
+
+// windSportsLimits returns the limits of -sport in units overridden by the
+// -wind, -gust-factor and -directions flags.
+func windSportsLimits(opt *options) (*windLimits, error) {
+	l := &windLimits{windSport: windSports[opt.sport]}
+	if opt.units == "imperial" {
+		l.minWind, l.maxWind = l.minWind/0.44704, l.maxWind/0.44704
+	}
+	_, l.windSpeedSymbol = unitSymbols(opt.units)
+
+	if opt.sportWind != "" {
+		number := func(s string) (float64, bool) {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			return v, err == nil && v >= 0
+		}
+		from, to, ok := parseRange(opt.sportWind, number)
+		if !ok || from >= to {
+			return nil, fmt.Errorf("invalid -wind %q, expected a range such as \"6-16\"", opt.sportWind)
+		}
+		l.minWind, l.maxWind = from, to
+	}
+	if opt.sportGustFactor != 0 {
+		if opt.sportGustFactor < 1 {
+			return nil, fmt.Errorf("invalid -gust-factor %s, must be at least 1", formatFloat(opt.sportGustFactor))
+		}
+		l.gustFactor = opt.sportGustFactor
+	}
+	if opt.sportDirections != "" {
+		from, to, ok := parseRange(opt.sportDirections, parseDirection)
+		if !ok {
+			return nil, fmt.Errorf("invalid -directions %q, expected a range such as \"180-270\" or \"SW-N\"", opt.sportDirections)
+		}
+		l.directions, l.fromDeg, l.toDeg, l.directionsArgument = true, from, to, opt.sportDirections
+	}
+	return l, nil
+}
+
+// checkWindSports checks w, in the units of the limits, against them.
+func checkWindSports(w *Weather, sport string, l *windLimits) *windSportsReport {
+	r := &windSportsReport{City: w.CityName, Sport: sport, WindSpeed: w.WindSpeed, WindGust: w.WindGust, WindDegrees: w.WindDegrees}
+	check := func(name string, ok bool, format string, args ...any) {
+		r.Checks = append(r.Checks, windCheck{Name: name, OK: ok, Detail: localSprintf(format, args...)})
+	}
+
+	speed := localSprintf("%.1f %s", w.WindSpeed, l.windSpeedSymbol)
+	limits := localSprintf("%s–%s %s", formatFloat(math.Round(l.minWind*10)/10), formatFloat(math.Round(l.maxWind*10)/10), l.windSpeedSymbol)
+	switch {
+	case w.WindSpeed < l.minWind:
+		check("wind", false, "%s, too light, needs %s", speed, limits)
+	case w.WindSpeed > l.maxWind:
+		check("wind", false, "%s, too strong, needs %s", speed, limits)
+	default:
+		check("wind", true, "%s, within %s", speed, limits)
+	}
+
+	switch {
+	case w.WindGust == 0:
+		check("gusts", true, "not reported")
+	case w.WindSpeed == 0:
+		check("gusts", false, "%.1f %s in calm", w.WindGust, l.windSpeedSymbol)
+	default:
+		factor := w.WindGust / w.WindSpeed
+		gusty := factor > l.gustFactor
+		verdict := "steady"
+		if gusty {
+			verdict = "too gusty"
+		}
+		check("gusts", !gusty, "%.1f %s, %.1f× the wind, %s (up to %s×)", w.WindGust, l.windSpeedSymbol, factor, verdict, formatFloat(l.gustFactor))
+	}
+
+	from := localSprintf("from %s (%.0f°)", compassPoint(w.WindDegrees), w.WindDegrees)
+	switch {
+	case !l.directions:
+		check("direction", true, "%s", from)
+	case windDirectionWithin(w.WindDegrees, l.fromDeg, l.toDeg):
+		check("direction", true, "%s, within %s", from, l.directionsArgument)
+	default:
+		check("direction", false, "%s, outside %s", from, l.directionsArgument)
+	}
+
+	if strings.HasPrefix(w.Icon, "11") {
+		check("weather", false, "thunderstorm, stay off the water")
+	}
+
+	r.Go = true
+	for _, c := range r.Checks {
+		r.Go = r.Go && c.OK
+	}
+	return r
+}
+
+// runWindSports checks the current wind of each location for -sport.
+func runWindSports(s *session) {
+	limits, err := windSportsLimits(s.opt)
+	if err != nil {
+		exitWithUsageError(err.Error())
+	}
+
+	for _, city := range s.cities() {
+		w, err := s.provider().current(s.query(city))
+		if exitOnError(err) {
+			continue
+		}
+		s.recordHistory(w, s.opt.units)
+		displayWindSports(os.Stdout, checkWindSports(w, s.opt.sport, limits), s.opt)
+	}
+}
+
+// displayWindSports writes e.g. "Helsinki: go for kitesurfing" followed by
+// the checks.
+func displayWindSports(w io.Writer, r *windSportsReport, opt *options) {
+	if isJSON(opt.format) {
+		writeJSON(w, r, opt.format)
+		return
+	}
+
+	sport := map[string]string{"kitesurf": "kitesurfing", "windsurf": "windsurfing", "sailing": "sailing"}[r.Sport]
+	verdict := "go"
+	if !r.Go {
+		verdict = "no-go"
+	}
+	localFprintf(w, "%s: %s for %s\n", r.City, verdict, sport)
+	for _, c := range r.Checks {
+		mark := "✓"
+		if !c.OK {
+			mark = "✗"
+		}
+		localFprintf(w, "  %s %s %s\n", mark, c.Name, c.Detail)
+	}
+}